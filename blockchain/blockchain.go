@@ -1,16 +1,16 @@
 package blockchain
 
 import (
-	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/eoscanada/eos-go"
 	"github.com/eoscanada/eos-go/ecc"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
-	"net/http"
 	"platform-backend/config"
+	"platform-backend/utils"
 	"strings"
 	"sync"
 	"time"
@@ -62,12 +62,17 @@ type PubKeys struct {
 
 type Blockchain struct {
 	Api                 *eos.API
+	Signer              Signer
+	Nodes               *NodePool
+	Sponsor             *SponsorClient
 	PubKeys             *PubKeys
 	ChainId             eos.Checksum256
 	PlatformAccountName string
-	sponsorUrl          string
 	disableSponsor      bool
+	healthCheckInterval time.Duration
 	trxPushAttempts     int
+	trxPushMinBackoff   time.Duration
+	trxPushMaxBackoff   time.Duration
 
 	optsMutex      sync.Mutex
 	lastInfoTime   time.Time
@@ -79,9 +84,10 @@ func (b *Blockchain) PushTransaction(actions []*eos.Action, requiredKeys []ecc.P
 		trxOpts := b.GetTrxOpts()
 		err := trxOpts.FillFromChain(b.Api)
 		if err != nil {
-			return nil, err
+			return nil, classifyPushError(err)
 		}
 		trx := eos.NewTransaction(actions, trxOpts)
+		trx.SetExpiration(TrxExpiration)
 
 		var notSignedTrx *eos.SignedTransaction
 		if sponsored {
@@ -93,7 +99,7 @@ func (b *Blockchain) PushTransaction(actions []*eos.Action, requiredKeys []ecc.P
 			notSignedTrx = eos.NewSignedTransaction(trx)
 		}
 
-		signedTrx, err := b.Api.Signer.Sign(notSignedTrx, b.ChainId, requiredKeys...)
+		signedTrx, err := b.SignTransaction(notSignedTrx, requiredKeys...)
 		if err != nil {
 			return nil, err
 		}
@@ -116,30 +122,45 @@ func (b *Blockchain) PushTransaction(actions []*eos.Action, requiredKeys []ecc.P
 					return trxID, nil
 				}
 			}
-			return nil, err
+			return nil, classifyPushError(err)
 		}
 		return trxID, nil
 	}
 
-	attempts := 0
-	for {
+	for attempts := 1; ; attempts++ {
 		trxID, err := sendTrx()
 		if err == nil {
 			return trxID, nil
 		}
-		attempts++
 		log.Error().Msgf("Send transaction error (attempt %d of %d): %s", attempts, b.trxPushAttempts, err.Error())
-		if attempts >= b.trxPushAttempts {
+		if !IsRetryableError(err) || attempts >= b.trxPushAttempts {
 			return nil, err
 		}
+		time.Sleep(b.pushBackoff(attempts))
 	}
 }
 
-func Init(config *config.BlockchainConfig) (*Blockchain, error) {
+// pushBackoff returns exponential delay with jitter before the next push attempt
+func (b *Blockchain) pushBackoff(attempts int) time.Duration {
+	return utils.JitterBackoff(b.trxPushMinBackoff, b.trxPushMaxBackoff, attempts)
+}
+
+func Init(config *config.BlockchainConfig, reg prometheus.Registerer) (*Blockchain, error) {
+	nodes, err := NewNodePool(config.Nodes(), config.MaxHeadBlockLag, reg)
+	if err != nil {
+		return nil, err
+	}
+
 	blockchain := new(Blockchain)
-	blockchain.Api = eos.New(config.NodeUrl)
-	blockchain.sponsorUrl = config.SponsorUrl
+	blockchain.Nodes = nodes
+	blockchain.healthCheckInterval = time.Duration(config.HealthCheckInterval) * time.Second
+	// requests are routed to nodes by pool
+	blockchain.Api = eos.New(nodePoolBaseURL)
+	blockchain.Api.HttpClient = nodes.Client()
+	blockchain.Sponsor = NewSponsorClient(config.SponsorUrl, &config.Sponsor, blockchain.Api, reg)
 	blockchain.trxPushAttempts = config.TrxPushAttempts
+	blockchain.trxPushMinBackoff = time.Duration(config.TrxPushMinBackoff) * time.Millisecond
+	blockchain.trxPushMaxBackoff = time.Duration(config.TrxPushMaxBackoff) * time.Millisecond
 	blockchain.PlatformAccountName = config.Contracts.Platform
 
 	info, err := blockchain.Api.GetInfo()
@@ -148,29 +169,14 @@ func Init(config *config.BlockchainConfig) (*Blockchain, error) {
 		return nil, err
 	}
 
-	blockchain.Api.EnableKeepAlives()
 	blockchain.ChainId = info.ChainID
 	blockchain.lastInfoTime = time.Now()
 	blockchain.lastLibBlockID = info.HeadBlockID
 
-	keyBag := &eos.KeyBag{}
-	if err := keyBag.ImportPrivateKey(config.Permissions.Deposit); err != nil {
-		return nil, err
-	}
-	if err := keyBag.ImportPrivateKey(config.Permissions.GameAction); err != nil {
-		return nil, err
-	}
-	if err := keyBag.ImportPrivateKey(config.Permissions.SigniDice); err != nil {
+	blockchain.Signer, blockchain.PubKeys, err = newSigner(config)
+	if err != nil {
 		return nil, err
 	}
-	blockchain.Api.SetSigner(keyBag)
-
-	pubKeys := &PubKeys{
-		Deposit:    keyBag.Keys[0].PublicKey(),
-		GameAction: keyBag.Keys[1].PublicKey(),
-		SigniDice:  keyBag.Keys[2].PublicKey(),
-	}
-	blockchain.PubKeys = pubKeys
 
 	blockchain.disableSponsor = config.DisableSponsor
 
@@ -178,60 +184,64 @@ func Init(config *config.BlockchainConfig) (*Blockchain, error) {
 	return blockchain, nil
 }
 
-func (b *Blockchain) GetSponsoredTrx(trx *eos.Transaction) (*eos.SignedTransaction, error) {
-	if b.disableSponsor {
-		return eos.NewSignedTransaction(trx), nil
-	}
-
-	packedTrx, err := eos.MarshalBinary(trx)
-	if err != nil {
-		return nil, err
-	}
-
-	reqBody, err := json.Marshal(&sponsorRequest{
-		SerializedTransaction: packedTrx,
-	})
-	if err != nil {
-		return nil, errors.New("request body marshal error")
-	}
-
-	httpResp, err := http.Post(b.sponsorUrl+"/sponsor", "application/json", bytes.NewReader(reqBody))
-	if err != nil {
-		return nil, errors.New("sponsorship provider request error: " + err.Error())
-	}
-	// don't forget to close response body
-	defer httpResp.Body.Close()
-	if httpResp.StatusCode != http.StatusOK {
-		return nil, errors.New("sponsorship provider respond with error: " + httpResp.Status)
-	}
-
-	var response sponsorResponse
-	err = json.NewDecoder(httpResp.Body).Decode(&response)
-	if err != nil {
-		return nil, errors.New("sponsorship response parsing error: " + err.Error())
-	}
-
-	var sponsoredTrx eos.Transaction
-	err = eos.UnmarshalBinary(response.SerializedTransaction, &sponsoredTrx)
-	if err != nil {
-		return nil, errors.New("sponsored transaction parsing error: " + err.Error())
-	}
-
-	sponsoredSignedTrx := eos.NewSignedTransaction(&sponsoredTrx)
-
-	for _, strSignature := range response.Signatures {
-		sign, err := ecc.NewSignature(strSignature)
+func newSigner(config *config.BlockchainConfig) (Signer, *PubKeys, error) {
+	switch config.Signer.Type {
+	case LocalSignerType, "":
+		signer, err := NewLocalSigner(
+			config.Permissions.Deposit,
+			config.Permissions.GameAction,
+			config.Permissions.SigniDice,
+		)
 		if err != nil {
-			return nil, errors.New("sponsored signature parsing error: " + err.Error())
+			return nil, nil, err
+		}
+		keys := signer.PublicKeys()
+		return signer, &PubKeys{
+			Deposit:    keys[0],
+			GameAction: keys[1],
+			SigniDice:  keys[2],
+		}, nil
+
+	case KeosdSignerType:
+		pubKeys := new(PubKeys)
+		for _, key := range []struct {
+			dest *ecc.PublicKey
+			str  string
+		}{
+			{&pubKeys.Deposit, config.Signer.PubKeys.Deposit},
+			{&pubKeys.GameAction, config.Signer.PubKeys.GameAction},
+			{&pubKeys.SigniDice, config.Signer.PubKeys.SigniDice},
+		} {
+			pubKey, err := ecc.NewPublicKey(key.str)
+			if err != nil {
+				return nil, nil, err
+			}
+			*key.dest = pubKey
 		}
-		sponsoredSignedTrx.Signatures = append(sponsoredSignedTrx.Signatures, sign)
+		log.Info().Msgf("Transactions are signed by keosd on %s", config.Signer.Url)
+		signer := NewKeosdSigner(config.Signer.Url, time.Duration(config.Signer.Timeout)*time.Second)
+		return signer, pubKeys, nil
+
+	default:
+		return nil, nil, ErrUnknownSignerType
 	}
+}
 
-	for _, action := range sponsoredSignedTrx.Actions {
-		action.SetToServer(true)
+// RunHealthChecks checks blockchain nodes until ctx is done
+func (b *Blockchain) RunHealthChecks(ctx context.Context) error {
+	if b.healthCheckInterval <= 0 {
+		log.Info().Msg("Blockchain nodes health checks are disabled")
+		<-ctx.Done()
+		return nil
 	}
+	return b.Nodes.RunHealthChecks(ctx, b.healthCheckInterval)
+}
 
-	return sponsoredSignedTrx, nil
+func (b *Blockchain) GetSponsoredTrx(trx *eos.Transaction) (*eos.SignedTransaction, error) {
+	if b.disableSponsor {
+		return eos.NewSignedTransaction(trx), nil
+	}
+	return b.Sponsor.Sponsor(trx)
 }
 
 func (b *Blockchain) GetTrxOpts() *eos.TxOptions {
@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"errors"
+	"github.com/eoscanada/eos-go"
+)
+
+// see: https://github.com/DaoCasino/DAObet/blob/master/libraries/chain/include/eosio/chain/exceptions.hpp
+const (
+	eosExpiredTrxErrorCode        = 3040005
+	eosInvalidRefBlockErrorCode   = 3040007
+	eosAssertMessageErrorCode     = 3050003
+	eosAssertCodeErrorCode        = 3050004
+	eosNetUsageExceededErrorCode  = 3080002
+	eosCpuUsageExceededErrorCode  = 3080004
+	eosDeadlineErrorCode          = 3080006
+	eosLeewayDeadlineErrorCode    = 3081001
+	eosUnsatisfiedAuthErrorCode   = 3090003
+	eosMissingAuthErrorCode       = 3090004
+	eosIrrelevantAuthErrorCode    = 3090005
+	eosInsufficientDelayErrorCode = 3090006
+)
+
+var (
+	// retryable
+	ErrTrxExpired           = errors.New("transaction expired")
+	ErrTrxResourceExhausted = errors.New("not enough cpu or net resources")
+	ErrNodeUnavailable      = errors.New("blockchain node unavailable")
+	ErrSponsorUnavailable   = errors.New("sponsorship provider unavailable")
+	ErrSignerUnavailable    = errors.New("transaction signer unavailable")
+
+	// permanent
+	ErrTrxAssertion    = errors.New("transaction rejected by contract")
+	ErrTrxMissingAuth  = errors.New("transaction authorization error")
+	ErrTrxRejected     = errors.New("transaction rejected by blockchain")
+	ErrSponsorRejected = errors.New("transaction rejected by sponsorship provider")
+
+	ErrTrxNotFound = errors.New("transaction not found")
+)
+
+// TrxError is classified transaction push error, errors.Is matches its kind
+type TrxError struct {
+	Kind      error
+	Retryable bool
+	// eos error code, zero if unknown
+	Code int
+	Err  error
+}
+
+func (e *TrxError) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *TrxError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *TrxError) Unwrap() error {
+	return e.Err
+}
+
+func newTrxError(kind error, code int, err error) *TrxError {
+	retryable := kind == ErrTrxExpired ||
+		kind == ErrTrxResourceExhausted ||
+		kind == ErrNodeUnavailable ||
+		kind == ErrSponsorUnavailable ||
+		kind == ErrSignerUnavailable
+
+	return &TrxError{Kind: kind, Retryable: retryable, Code: code, Err: err}
+}
+
+// classifyPushError classifies blockchain api error, errors without api response are considered node unavailability
+func classifyPushError(err error) *TrxError {
+	var apiErr eos.APIError
+	if !errors.As(err, &apiErr) {
+		return newTrxError(ErrNodeUnavailable, 0, err)
+	}
+
+	code := apiErr.ErrorStruct.Code
+	switch code {
+	case eosExpiredTrxErrorCode, eosInvalidRefBlockErrorCode:
+		return newTrxError(ErrTrxExpired, code, err)
+	case eosNetUsageExceededErrorCode, eosCpuUsageExceededErrorCode,
+		eosDeadlineErrorCode, eosLeewayDeadlineErrorCode:
+		return newTrxError(ErrTrxResourceExhausted, code, err)
+	case eosAssertMessageErrorCode, eosAssertCodeErrorCode:
+		return newTrxError(ErrTrxAssertion, code, err)
+	case eosUnsatisfiedAuthErrorCode, eosMissingAuthErrorCode,
+		eosIrrelevantAuthErrorCode, eosInsufficientDelayErrorCode:
+		return newTrxError(ErrTrxMissingAuth, code, err)
+	}
+
+	// error response without eos error, e.g. from proxy in front of node
+	if code == 0 && apiErr.Code >= 500 {
+		return newTrxError(ErrNodeUnavailable, code, err)
+	}
+	return newTrxError(ErrTrxRejected, code, err)
+}
+
+// IsRetryableError tells whether transaction can succeed if pushed again
+func IsRetryableError(err error) bool {
+	var trxErr *TrxError
+	return errors.As(err, &trxErr) && trxErr.Retryable
+}
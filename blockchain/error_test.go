@@ -0,0 +1,53 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func apiError(httpCode int, code int) eos.APIError {
+	err := eos.APIError{Code: httpCode, Message: "error"}
+	err.ErrorStruct.Code = code
+	return err
+}
+
+func TestClassifyPushError(t *testing.T) {
+	tests := []struct {
+		err       error
+		kind      error
+		retryable bool
+	}{
+		{apiError(500, eosExpiredTrxErrorCode), ErrTrxExpired, true},
+		{apiError(500, eosCpuUsageExceededErrorCode), ErrTrxResourceExhausted, true},
+		{apiError(500, eosNetUsageExceededErrorCode), ErrTrxResourceExhausted, true},
+		{apiError(500, eosAssertMessageErrorCode), ErrTrxAssertion, false},
+		{apiError(500, eosMissingAuthErrorCode), ErrTrxMissingAuth, false},
+		{apiError(500, 3010001), ErrTrxRejected, false},
+		{apiError(502, 0), ErrNodeUnavailable, true},
+		{errors.New("connection refused"), ErrNodeUnavailable, true},
+	}
+	for _, test := range tests {
+		err := fmt.Errorf("push: %w", classifyPushError(test.err))
+		assert.True(t, errors.Is(err, test.kind), test.err.Error())
+		assert.Equal(t, test.retryable, IsRetryableError(err), test.err.Error())
+	}
+	assert.False(t, IsRetryableError(errors.New("sign error")))
+}
+
+func TestPushBackoff(t *testing.T) {
+	b := &Blockchain{trxPushMinBackoff: 100 * time.Millisecond, trxPushMaxBackoff: time.Second}
+	for attempts, max := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		10: time.Second,
+	} {
+		delay := b.pushBackoff(attempts)
+		assert.True(t, delay >= max/2 && delay <= max, "attempts %d delay %s", attempts, delay)
+	}
+}
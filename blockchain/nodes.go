@@ -0,0 +1,314 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// base url of eos api, requests to it are routed to nodes by NodePool
+const nodePoolBaseURL = "http://blockchain-nodes"
+
+var ErrNoBlockchainNodes = errors.New("no blockchain nodes configured")
+
+type node struct {
+	url       *url.URL
+	name      string
+	healthy   int32
+	headBlock uint32
+}
+
+func (n *node) isHealthy() bool {
+	return atomic.LoadInt32(&n.healthy) == 1
+}
+
+// NodePool routes blockchain API requests to several nodes: reads are balanced with round-robin,
+// transactions are pushed to the first healthy node, unavailable node is replaced by the next one
+type NodePool struct {
+	nodes           []*node
+	next            uint32
+	transport       http.RoundTripper
+	checkClient     *http.Client
+	maxHeadBlockLag uint32
+
+	requests    *prometheus.CounterVec
+	durations   *prometheus.HistogramVec
+	healthy     *prometheus.GaugeVec
+	headLag     *prometheus.GaugeVec
+	checkErrors *prometheus.CounterVec
+}
+
+func NewNodePool(urls []string, maxHeadBlockLag uint32, reg prometheus.Registerer) (*NodePool, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoBlockchainNodes
+	}
+
+	nodes := make([]*node, len(urls))
+	for i, rawURL := range urls {
+		nodeURL, err := url.Parse(strings.TrimRight(rawURL, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("node url %q parse error: %w", rawURL, err)
+		}
+		// considered healthy until the first check
+		nodes[i] = &node{url: nodeURL, name: nodeURL.Host, healthy: 1}
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	p := &NodePool{
+		nodes:           nodes,
+		transport:       transport,
+		checkClient:     &http.Client{Transport: transport, Timeout: 5 * time.Second},
+		maxHeadBlockLag: maxHeadBlockLag,
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "blockchain_node_requests_total",
+				Help: "Requests sent to blockchain node by kind and result",
+			}, []string{"node", "kind", "result"},
+		),
+		durations: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "blockchain_node_request_duration_seconds",
+				Help:    "Blockchain node request time by kind",
+				Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+			}, []string{"node", "kind"},
+		),
+		healthy: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "blockchain_node_healthy",
+				Help: "1 - node is used for requests, 0 - node is excluded by health check",
+			}, []string{"node"},
+		),
+		headLag: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "blockchain_node_head_block_lag",
+				Help: "Blocks the node is behind the best known head block",
+			}, []string{"node"},
+		),
+		checkErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "blockchain_node_health_check_errors_total",
+				Help: "Failed node health checks",
+			}, []string{"node"},
+		),
+	}
+	reg.MustRegister(p.requests, p.durations, p.healthy, p.headLag, p.checkErrors)
+
+	for _, n := range nodes {
+		p.healthy.WithLabelValues(n.name).Set(1)
+	}
+
+	return p, nil
+}
+
+// Client returns http client to be used by eos api with nodePoolBaseURL
+func (p *NodePool) Client() *http.Client {
+	return &http.Client{Transport: p}
+}
+
+func isWriteRequest(req *http.Request) bool {
+	return strings.HasSuffix(req.URL.Path, "/push_transaction") ||
+		strings.HasSuffix(req.URL.Path, "/push_transactions") ||
+		strings.HasSuffix(req.URL.Path, "/send_transaction")
+}
+
+// candidates returns nodes in order of trying, unhealthy nodes are used only if all others fail
+func (p *NodePool) candidates(write bool) []*node {
+	start := 0
+	if !write {
+		start = int(atomic.AddUint32(&p.next, 1) % uint32(len(p.nodes)))
+	}
+
+	healthy := make([]*node, 0, len(p.nodes))
+	var unhealthy []*node
+	for i := range p.nodes {
+		n := p.nodes[(start+i)%len(p.nodes)]
+		if n.isHealthy() {
+			healthy = append(healthy, n)
+		} else {
+			unhealthy = append(unhealthy, n)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// isNodeUnavailable tells whether request should be sent to another node,
+// eos api responds with 500 on transaction errors, so only gateway errors are considered
+func isNodeUnavailable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (p *NodePool) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	write := isWriteRequest(req)
+	kind := "read"
+	if write {
+		kind = "write"
+	}
+
+	candidates := p.candidates(write)
+	for i, n := range candidates {
+		if i > 0 {
+			log.Warn().Msgf("Blockchain request %s failover to node %s", req.URL.Path, n.name)
+		}
+
+		nodeReq := req.Clone(req.Context())
+		nodeReq.URL.Scheme = n.url.Scheme
+		nodeReq.URL.Host = n.url.Host
+		nodeReq.URL.Path = n.url.Path + req.URL.Path
+		nodeReq.Host = n.url.Host
+		nodeReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		nodeReq.ContentLength = int64(len(body))
+
+		start := time.Now()
+		resp, err := p.transport.RoundTrip(nodeReq)
+		p.durations.WithLabelValues(n.name, kind).Observe(time.Since(start).Seconds())
+
+		if !isNodeUnavailable(resp, err) {
+			p.requests.WithLabelValues(n.name, kind, "ok").Inc()
+			return resp, nil
+		}
+		p.requests.WithLabelValues(n.name, kind, "error").Inc()
+
+		// the last node result is returned as is
+		if i == len(candidates)-1 || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if err == nil {
+			// another node will be tried, so response isn't needed
+			err = fmt.Errorf("responded with %s", resp.Status)
+			_ = resp.Body.Close()
+		}
+		log.Warn().Msgf("Blockchain node %s request error: %s", n.name, err.Error())
+	}
+
+	return nil, ErrNoBlockchainNodes
+}
+
+type nodeInfo struct {
+	HeadBlockNum uint32 `json:"head_block_num"`
+}
+
+func (p *NodePool) getHeadBlock(ctx context.Context, n *node) (uint32, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url.String()+"/v1/chain/get_info", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := p.checkClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.New("get_info responded with " + resp.Status)
+	}
+
+	var info nodeInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return 0, err
+	}
+	return info.HeadBlockNum, nil
+}
+
+// CheckHealth marks nodes unavailable or lagging behind the best head block as unhealthy
+func (p *NodePool) CheckHealth(ctx context.Context) {
+	errs := make([]error, len(p.nodes))
+	wg := sync.WaitGroup{}
+	for i, n := range p.nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			var head uint32
+			head, errs[i] = p.getHeadBlock(ctx, n)
+			if errs[i] == nil {
+				atomic.StoreUint32(&n.headBlock, head)
+			}
+		}(i, n)
+	}
+	wg.Wait()
+
+	var bestHead uint32
+	for i, n := range p.nodes {
+		if head := atomic.LoadUint32(&n.headBlock); errs[i] == nil && head > bestHead {
+			bestHead = head
+		}
+	}
+
+	for i, n := range p.nodes {
+		healthy := int32(1)
+		if errs[i] != nil {
+			healthy = 0
+			p.checkErrors.WithLabelValues(n.name).Inc()
+			log.Warn().Msgf("Blockchain node %s health check error: %s", n.name, errs[i].Error())
+		} else {
+			lag := bestHead - atomic.LoadUint32(&n.headBlock)
+			p.headLag.WithLabelValues(n.name).Set(float64(lag))
+			if lag > p.maxHeadBlockLag {
+				healthy = 0
+				log.Warn().Msgf("Blockchain node %s is %d blocks behind", n.name, lag)
+			}
+		}
+
+		if atomic.SwapInt32(&n.healthy, healthy) != healthy {
+			log.Info().Msgf("Blockchain node %s healthy: %v", n.name, healthy == 1)
+		}
+		p.healthy.WithLabelValues(n.name).Set(float64(healthy))
+	}
+}
+
+// RunHealthChecks checks nodes with the interval until ctx is done
+func (p *NodePool) RunHealthChecks(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			p.CheckHealth(checkCtx)
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testNode struct {
+	*httptest.Server
+	headBlock uint32
+	requests  int32
+}
+
+func newTestNode(headBlock uint32, status int) *testNode {
+	n := &testNode{headBlock: headBlock}
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n.requests, 1)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"head_block_num": %d, "server_version": "%s"}`, n.headBlock, r.Host)
+	}))
+	return n
+}
+
+func newTestAPI(t *testing.T, urls ...string) (*eos.API, *NodePool) {
+	pool, err := NewNodePool(urls, 10, prometheus.NewRegistry())
+	require.NoError(t, err)
+	api := eos.New(nodePoolBaseURL)
+	api.HttpClient = pool.Client()
+	return api, pool
+}
+
+func TestNodePoolFailover(t *testing.T) {
+	down := newTestNode(0, http.StatusServiceUnavailable)
+	defer down.Close()
+	up := newTestNode(100, http.StatusOK)
+	defer up.Close()
+
+	api, _ := newTestAPI(t, down.URL, up.URL)
+	for i := 0; i < 4; i++ {
+		info, err := api.GetInfo()
+		require.NoError(t, err)
+		assert.Equal(t, strings.TrimPrefix(up.URL, "http://"), info.ServerVersion)
+	}
+}
+
+func TestNodePoolRoundRobin(t *testing.T) {
+	first := newTestNode(100, http.StatusOK)
+	defer first.Close()
+	second := newTestNode(100, http.StatusOK)
+	defer second.Close()
+
+	api, _ := newTestAPI(t, first.URL, second.URL)
+	for i := 0; i < 4; i++ {
+		_, err := api.GetInfo()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&first.requests))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&second.requests))
+}
+
+func TestNodePoolHealthCheck(t *testing.T) {
+	lagging := newTestNode(50, http.StatusOK)
+	defer lagging.Close()
+	up := newTestNode(100, http.StatusOK)
+	defer up.Close()
+
+	api, pool := newTestAPI(t, lagging.URL, up.URL)
+	pool.CheckHealth(context.Background())
+	assert.False(t, pool.nodes[0].isHealthy())
+	assert.True(t, pool.nodes[1].isHealthy())
+
+	atomic.StoreInt32(&lagging.requests, 0)
+	for i := 0; i < 4; i++ {
+		_, err := api.GetInfo()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&lagging.requests))
+}
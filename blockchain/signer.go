@@ -0,0 +1,103 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrUnknownSignerType = errors.New("unknown signer type")
+
+const (
+	LocalSignerType = "local"
+	KeosdSignerType = "keosd"
+)
+
+// Signer adds signatures of the platform keys to transactions
+type Signer interface {
+	Sign(trx *eos.SignedTransaction, chainID eos.Checksum256, requiredKeys ...ecc.PublicKey) (*eos.SignedTransaction, error)
+}
+
+// LocalSigner signs with private keys kept in memory
+type LocalSigner struct {
+	keyBag *eos.KeyBag
+}
+
+func NewLocalSigner(privateKeys ...string) (*LocalSigner, error) {
+	keyBag := eos.NewKeyBag()
+	for _, key := range privateKeys {
+		if err := keyBag.ImportPrivateKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return &LocalSigner{keyBag: keyBag}, nil
+}
+
+func (s *LocalSigner) Sign(
+	trx *eos.SignedTransaction,
+	chainID eos.Checksum256,
+	requiredKeys ...ecc.PublicKey,
+) (*eos.SignedTransaction, error) {
+	return s.keyBag.Sign(trx, chainID, requiredKeys...)
+}
+
+// PublicKeys returns public keys in order of private keys import
+func (s *LocalSigner) PublicKeys() []ecc.PublicKey {
+	keys := make([]ecc.PublicKey, len(s.keyBag.Keys))
+	for i, key := range s.keyBag.Keys {
+		keys[i] = key.PublicKey()
+	}
+	return keys
+}
+
+// KeosdSigner signs with keosd compatible service, private keys never leave it
+type KeosdSigner struct {
+	api *eos.API
+}
+
+func NewKeosdSigner(url string, timeout time.Duration) *KeosdSigner {
+	api := eos.New(url)
+	api.HttpClient = &http.Client{Timeout: timeout}
+	return &KeosdSigner{api: api}
+}
+
+func (s *KeosdSigner) Sign(
+	trx *eos.SignedTransaction,
+	chainID eos.Checksum256,
+	requiredKeys ...ecc.PublicKey,
+) (*eos.SignedTransaction, error) {
+	resp, err := s.api.WalletSignTransaction(trx, chainID, requiredKeys...)
+	if err != nil {
+		var apiErr eos.APIError
+		if !errors.As(err, &apiErr) || apiErr.Code >= http.StatusInternalServerError {
+			return nil, newTrxError(ErrSignerUnavailable, 0, err)
+		}
+		return nil, fmt.Errorf("keosd sign error: %w", err)
+	}
+
+	// response contains previous signatures too, e.g. sponsor ones
+	for _, signature := range resp.Signatures {
+		if !hasSignature(trx.Signatures, signature) {
+			trx.Signatures = append(trx.Signatures, signature)
+		}
+	}
+	return trx, nil
+}
+
+func hasSignature(signatures []ecc.Signature, signature ecc.Signature) bool {
+	for _, s := range signatures {
+		if strings.EqualFold(s.String(), signature.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// SignTransaction signs transaction for the current chain
+func (b *Blockchain) SignTransaction(trx *eos.SignedTransaction, requiredKeys ...ecc.PublicKey) (*eos.SignedTransaction, error) {
+	return b.Signer.Sign(trx, b.ChainId, requiredKeys...)
+}
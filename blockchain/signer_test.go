@@ -0,0 +1,78 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newKeosdStub serves keosd sign_transaction endpoint with local signer
+func newKeosdStub(t *testing.T, signer *LocalSigner) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/wallet/sign_transaction", r.URL.Path)
+
+		var params []json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+		require.Len(t, params, 3)
+
+		var (
+			trx     eos.SignedTransaction
+			keys    []ecc.PublicKey
+			chainID string
+		)
+		require.NoError(t, json.Unmarshal(params[0], &trx))
+		require.NoError(t, json.Unmarshal(params[1], &keys))
+		require.NoError(t, json.Unmarshal(params[2], &chainID))
+		chain, err := hex.DecodeString(chainID)
+		require.NoError(t, err)
+
+		signed, err := signer.Sign(&trx, chain, keys...)
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(w).Encode(signed))
+	}))
+}
+
+func TestKeosdSigner(t *testing.T) {
+	privateKey, err := ecc.NewRandomPrivateKey()
+	require.NoError(t, err)
+	localSigner, err := NewLocalSigner(privateKey.String())
+	require.NoError(t, err)
+	pubKey := localSigner.PublicKeys()[0]
+
+	keosd := newKeosdStub(t, localSigner)
+	defer keosd.Close()
+
+	chainID := eos.Checksum256(make([]byte, 32))
+	trx := eos.NewSignedTransaction(eos.NewTransaction(nil, &eos.TxOptions{
+		ChainID:     chainID,
+		HeadBlockID: eos.Checksum256(make([]byte, 32)),
+	}))
+	trx.Expiration = eos.JSONTime{Time: time.Now().Add(time.Minute).Truncate(time.Second)}
+
+	signed, err := NewKeosdSigner(keosd.URL, time.Second).Sign(trx, chainID, pubKey)
+	require.NoError(t, err)
+	require.Len(t, signed.Signatures, 1)
+
+	keys, err := signed.SignedByKeys(chainID)
+	require.NoError(t, err)
+	assert.Equal(t, pubKey.String(), keys[0].String())
+}
+
+func TestKeosdSignerUnavailable(t *testing.T) {
+	keosd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer keosd.Close()
+
+	trx := eos.NewSignedTransaction(&eos.Transaction{})
+	_, err := NewKeosdSigner(keosd.URL, time.Second).Sign(trx, make([]byte, 32))
+	assert.True(t, IsRetryableError(err))
+}
@@ -0,0 +1,210 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"net/http"
+	"platform-backend/config"
+	"platform-backend/utils"
+	"time"
+)
+
+// sponsorStatusError is returned when sponsorship provider responded with not OK status
+type sponsorStatusError struct {
+	status int
+	body   string
+}
+
+func (e *sponsorStatusError) Error() string {
+	return fmt.Sprintf("sponsorship provider respond with error: %d %s", e.status, e.body)
+}
+
+// SponsorClient requests sponsorship of transactions, unavailable provider is cut off by circuit breaker,
+// optionally not sponsored transaction is used if its payer has enough own resources.
+// Requests aren't retried by client, retryable errors are retried by transaction push.
+type SponsorClient struct {
+	url     string
+	client  *http.Client
+	api     *eos.API
+	breaker *utils.CircuitBreaker
+
+	fallback       bool
+	fallbackMinCpu int64
+	fallbackMinNet int64
+
+	requests     *prometheus.CounterVec
+	durations    prometheus.Histogram
+	breakerState prometheus.Gauge
+	fallbacks    *prometheus.CounterVec
+}
+
+func NewSponsorClient(url string, cfg *config.SponsorConfig, api *eos.API, reg prometheus.Registerer) *SponsorClient {
+	c := &SponsorClient{
+		url:            url,
+		client:         &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		api:            api,
+		breaker:        utils.NewCircuitBreaker(cfg.BreakerThreshold, time.Duration(cfg.BreakerTimeout)*time.Second),
+		fallback:       cfg.Fallback,
+		fallbackMinCpu: cfg.FallbackMinCpu,
+		fallbackMinNet: cfg.FallbackMinNet,
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sponsor_requests_total",
+				Help: "Transaction sponsoring requests by result",
+			}, []string{"result"},
+		),
+		durations: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "sponsor_request_duration_seconds",
+				Help:    "Transaction sponsoring request time",
+				Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+			},
+		),
+		breakerState: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "sponsor_circuit_breaker_state",
+				Help: "0 - closed, 1 - open, 2 - half-open",
+			},
+		),
+		fallbacks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sponsor_fallback_total",
+				Help: "Attempts to push not sponsored transaction while sponsor is unavailable",
+			}, []string{"result"},
+		),
+	}
+	reg.MustRegister(c.requests, c.durations, c.breakerState, c.fallbacks)
+	return c
+}
+
+// Sponsor returns sponsored transaction, sponsorship provider unavailability is ErrSponsorUnavailable
+// unless fallback to not sponsored transaction is allowed
+func (c *SponsorClient) Sponsor(trx *eos.Transaction) (*eos.SignedTransaction, error) {
+	sponsoredTrx, err := c.sponsor(trx)
+	if err == nil || !c.fallback || !errors.Is(err, ErrSponsorUnavailable) {
+		return sponsoredTrx, err
+	}
+
+	payer, ok, e := c.hasOwnResources(trx)
+	if e != nil {
+		c.fallbacks.WithLabelValues("error").Inc()
+		log.Warn().Msgf("Sponsor fallback resources check error: %s", e.Error())
+		return nil, err
+	}
+	if !ok {
+		c.fallbacks.WithLabelValues("insufficient_resources").Inc()
+		return nil, err
+	}
+
+	c.fallbacks.WithLabelValues("used").Inc()
+	log.Warn().Msgf("Sponsorship provider is unavailable, push not sponsored trx paid by %s", payer)
+	return eos.NewSignedTransaction(trx), nil
+}
+
+func (c *SponsorClient) sponsor(trx *eos.Transaction) (*eos.SignedTransaction, error) {
+	// local errors aren't sponsor outcomes, so they're returned before breaker is asked
+	packedTrx, err := eos.MarshalBinary(trx)
+	if err != nil {
+		return nil, err
+	}
+	reqBody, err := json.Marshal(&sponsorRequest{
+		SerializedTransaction: packedTrx,
+	})
+	if err != nil {
+		return nil, errors.New("request body marshal error")
+	}
+
+	if err := c.breaker.Allow(); err != nil {
+		c.requests.WithLabelValues("circuit_open").Inc()
+		return nil, newTrxError(ErrSponsorUnavailable, 0, err)
+	}
+	defer func() {
+		c.breakerState.Set(float64(c.breaker.State()))
+	}()
+
+	sponsoredTrx, err := c.request(reqBody)
+	if err == nil {
+		c.requests.WithLabelValues("ok").Inc()
+		c.breaker.Success()
+		return sponsoredTrx, nil
+	}
+
+	// sponsor is available, but doesn't want to pay for transaction
+	if statusErr, ok := err.(*sponsorStatusError); ok && statusErr.status < http.StatusInternalServerError {
+		c.requests.WithLabelValues("rejected").Inc()
+		c.breaker.Success()
+		return nil, newTrxError(ErrSponsorRejected, 0, err)
+	}
+
+	c.requests.WithLabelValues("error").Inc()
+	log.Warn().Msgf("Sponsorship request error: %s", err.Error())
+	c.breaker.Failure()
+	return nil, newTrxError(ErrSponsorUnavailable, 0, err)
+}
+
+func (c *SponsorClient) request(reqBody []byte) (*eos.SignedTransaction, error) {
+	start := time.Now()
+	httpResp, err := c.client.Post(c.url+"/sponsor", "application/json", bytes.NewReader(reqBody))
+	c.durations.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, errors.New("sponsorship provider request error: " + err.Error())
+	}
+	// don't forget to close response body
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(httpResp.Body)
+		return nil, &sponsorStatusError{status: httpResp.StatusCode, body: string(body)}
+	}
+
+	var response sponsorResponse
+	err = json.NewDecoder(httpResp.Body).Decode(&response)
+	if err != nil {
+		return nil, errors.New("sponsorship response parsing error: " + err.Error())
+	}
+
+	var sponsoredTrx eos.Transaction
+	err = eos.UnmarshalBinary(response.SerializedTransaction, &sponsoredTrx)
+	if err != nil {
+		return nil, errors.New("sponsored transaction parsing error: " + err.Error())
+	}
+
+	sponsoredSignedTrx := eos.NewSignedTransaction(&sponsoredTrx)
+
+	for _, strSignature := range response.Signatures {
+		sign, err := ecc.NewSignature(strSignature)
+		if err != nil {
+			return nil, errors.New("sponsored signature parsing error: " + err.Error())
+		}
+		sponsoredSignedTrx.Signatures = append(sponsoredSignedTrx.Signatures, sign)
+	}
+
+	for _, action := range sponsoredSignedTrx.Actions {
+		action.SetToServer(true)
+	}
+
+	return sponsoredSignedTrx, nil
+}
+
+// hasOwnResources checks resources of not sponsored transaction payer, it is the first authorizer
+func (c *SponsorClient) hasOwnResources(trx *eos.Transaction) (eos.AccountName, bool, error) {
+	if len(trx.Actions) == 0 || len(trx.Actions[0].Authorization) == 0 {
+		return "", false, nil
+	}
+	payer := trx.Actions[0].Authorization[0].Actor
+
+	account, err := c.api.GetAccount(payer)
+	if err != nil {
+		return payer, false, err
+	}
+
+	ok := int64(account.CPULimit.Available) >= c.fallbackMinCpu &&
+		int64(account.NetLimit.Available) >= c.fallbackMinNet
+	return payer, ok, nil
+}
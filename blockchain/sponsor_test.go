@@ -0,0 +1,134 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"platform-backend/config"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSponsor(status *int32, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.WriteHeader(int(atomic.LoadInt32(status)))
+	}))
+}
+
+func newTestAccountNode(cpuAvailable int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"account_name": "player", "cpu_limit": {"available": %d}, "net_limit": {"available": 10000}}`,
+			cpuAvailable)
+	}))
+}
+
+func testSponsorTrx() *eos.Transaction {
+	action := &eos.Action{
+		Account:       "game",
+		Name:          "action",
+		Authorization: []eos.PermissionLevel{{Actor: "player", Permission: "game"}},
+		ActionData:    eos.NewActionDataFromHexData([]byte{}),
+	}
+	return eos.NewTransaction([]*eos.Action{action}, nil)
+}
+
+func TestSponsorClientBreaker(t *testing.T) {
+	status, requests := int32(http.StatusServiceUnavailable), int32(0)
+	sponsor := newTestSponsor(&status, &requests)
+	defer sponsor.Close()
+
+	cfg := &config.SponsorConfig{BreakerThreshold: 2, BreakerTimeout: 60}
+	client := NewSponsorClient(sponsor.URL, cfg, eos.New(sponsor.URL), prometheus.NewRegistry())
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Sponsor(testSponsorTrx())
+		assert.True(t, errors.Is(err, ErrSponsorUnavailable))
+		assert.True(t, IsRetryableError(err))
+	}
+	// failed request isn't retried by client
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// circuit is open, sponsor isn't requested
+	_, err := client.Sponsor(testSponsorTrx())
+	assert.True(t, errors.Is(err, ErrSponsorUnavailable))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestSponsorClientBreakerLocalError(t *testing.T) {
+	status, requests := int32(http.StatusServiceUnavailable), int32(0)
+	sponsor := newTestSponsor(&status, &requests)
+	defer sponsor.Close()
+
+	cfg := &config.SponsorConfig{BreakerThreshold: 2, BreakerTimeout: 60}
+	client := NewSponsorClient(sponsor.URL, cfg, eos.New(sponsor.URL), prometheus.NewRegistry())
+
+	_, err := client.Sponsor(testSponsorTrx())
+	assert.True(t, errors.Is(err, ErrSponsorUnavailable))
+
+	// marshal error isn't sponsor success, failures aren't reset
+	brokenTrx := testSponsorTrx()
+	brokenTrx.Actions[0].ActionData = eos.NewActionData(make(chan int))
+	_, err = client.Sponsor(brokenTrx)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrSponsorUnavailable))
+
+	_, err = client.Sponsor(testSponsorTrx())
+	assert.True(t, errors.Is(err, ErrSponsorUnavailable))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// circuit is open after two sponsor failures
+	_, err = client.Sponsor(testSponsorTrx())
+	assert.True(t, errors.Is(err, ErrSponsorUnavailable))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestSponsorClientRejected(t *testing.T) {
+	status, requests := int32(http.StatusBadRequest), int32(0)
+	sponsor := newTestSponsor(&status, &requests)
+	defer sponsor.Close()
+
+	cfg := &config.SponsorConfig{BreakerThreshold: 1, BreakerTimeout: 60}
+	client := NewSponsorClient(sponsor.URL, cfg, eos.New(sponsor.URL), prometheus.NewRegistry())
+
+	_, err := client.Sponsor(testSponsorTrx())
+	assert.True(t, errors.Is(err, ErrSponsorRejected))
+	assert.False(t, IsRetryableError(err))
+	// rejection isn't retried and doesn't open circuit
+	_, err = client.Sponsor(testSponsorTrx())
+	assert.True(t, errors.Is(err, ErrSponsorRejected))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestSponsorClientFallback(t *testing.T) {
+	status, requests := int32(http.StatusBadGateway), int32(0)
+	sponsor := newTestSponsor(&status, &requests)
+	defer sponsor.Close()
+
+	cfg := &config.SponsorConfig{
+		BreakerThreshold: 5,
+		Fallback:         true,
+		FallbackMinCpu:   5000,
+		FallbackMinNet:   1000,
+	}
+
+	rich := newTestAccountNode(10000)
+	defer rich.Close()
+	client := NewSponsorClient(sponsor.URL, cfg, eos.New(rich.URL), prometheus.NewRegistry())
+	trx, err := client.Sponsor(testSponsorTrx())
+	require.NoError(t, err)
+	assert.Empty(t, trx.Signatures)
+	assert.Len(t, trx.Actions, 1)
+
+	poor := newTestAccountNode(100)
+	defer poor.Close()
+	client = NewSponsorClient(sponsor.URL, cfg, eos.New(poor.URL), prometheus.NewRegistry())
+	_, err = client.Sponsor(testSponsorTrx())
+	assert.True(t, errors.Is(err, ErrSponsorUnavailable))
+}
@@ -0,0 +1,109 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"net/http"
+	"platform-backend/models"
+	"time"
+)
+
+// TrxExpiration is expiration of pushed transactions, expired transaction is never included to a block
+const TrxExpiration = 30 * time.Second
+
+// chain api transaction states
+const (
+	trxStateLocallyApplied = "LOCALLY_APPLIED"
+	trxStateInBlock        = "IN_BLOCK"
+	trxStateIrreversible   = "IRREVERSIBLE"
+	trxStateForkedOut      = "FORKED_OUT"
+	trxStateFailed         = "FAILED"
+	trxStateUnknown        = "UNKNOWN"
+)
+
+// IrreversibleBlock is the last irreversible block of the chain
+type IrreversibleBlock struct {
+	Num       uint32
+	Timestamp time.Time
+}
+
+// chain api get_transaction_status response (parse only used fields)
+type trxStatusResp struct {
+	State       string `json:"state"`
+	BlockNumber uint32 `json:"block_number"`
+}
+
+// GetIrreversibleBlock returns the last irreversible block from get_info with its time
+func (b *Blockchain) GetIrreversibleBlock() (*IrreversibleBlock, error) {
+	info, err := b.Api.GetInfo()
+	if err != nil {
+		return nil, err
+	}
+	block, err := b.Api.GetBlockByNum(info.LastIrreversibleBlockNum)
+	if err != nil {
+		return nil, err
+	}
+	return &IrreversibleBlock{Num: info.LastIrreversibleBlockNum, Timestamp: block.Timestamp.Time}, nil
+}
+
+// GetTransactionStatus looks up pushed transaction in chain api,
+// returns ErrTrxNotFound if transaction isn't included to a block
+func (b *Blockchain) GetTransactionStatus(
+	trxID string,
+	lib *IrreversibleBlock,
+) (models.GameSessionTrxStatus, uint32, error) {
+	body, err := json.Marshal(map[string]string{"id": trxID})
+	if err != nil {
+		return models.TrxPending, 0, err
+	}
+	resp, err := b.Api.HttpClient.Post(b.Api.BaseURL+"/v1/chain/get_transaction_status",
+		"application/json", bytes.NewReader(body))
+	if err != nil {
+		return models.TrxPending, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr eos.APIError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+			return models.TrxPending, 0, fmt.Errorf("get_transaction_status: status code %d", resp.StatusCode)
+		}
+		return models.TrxPending, 0, apiErr
+	}
+
+	var status trxStatusResp
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return models.TrxPending, 0, err
+	}
+	return parseTrxStatus(&status, lib)
+}
+
+func parseTrxStatus(resp *trxStatusResp, lib *IrreversibleBlock) (models.GameSessionTrxStatus, uint32, error) {
+	switch resp.State {
+	case trxStateIrreversible:
+		return models.TrxIrreversible, resp.BlockNumber, nil
+	case trxStateInBlock:
+		if resp.BlockNumber <= lib.Num {
+			return models.TrxIrreversible, resp.BlockNumber, nil
+		}
+		return models.TrxExecuted, resp.BlockNumber, nil
+	case trxStateFailed:
+		return models.TrxDropped, 0, nil
+	case trxStateLocallyApplied, trxStateForkedOut, trxStateUnknown:
+		// transaction can be still included to a block until it's expired
+		return models.TrxPending, 0, ErrTrxNotFound
+	}
+	return models.TrxPending, 0, fmt.Errorf("unknown transaction state: %s", resp.State)
+}
+
+// IsTrxExpired reports whether transaction pushed at pushed time can't be included to a block anymore
+func (lib *IrreversibleBlock) IsTrxExpired(pushed time.Time) bool {
+	return lib.IsExpirationPassed(pushed.Add(TrxExpiration))
+}
+
+// IsExpirationPassed reports whether transaction with given expiration can't be included to a block anymore
+func (lib *IrreversibleBlock) IsExpirationPassed(expiration time.Time) bool {
+	return lib.Timestamp.After(expiration)
+}
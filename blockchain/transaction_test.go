@@ -0,0 +1,47 @@
+package blockchain
+
+import (
+	"platform-backend/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrxStatus(t *testing.T) {
+	lib := &IrreversibleBlock{Num: 100}
+
+	tests := []struct {
+		resp   *trxStatusResp
+		status models.GameSessionTrxStatus
+	}{
+		{&trxStatusResp{State: trxStateInBlock, BlockNumber: 150}, models.TrxExecuted},
+		{&trxStatusResp{State: trxStateInBlock, BlockNumber: 100}, models.TrxIrreversible},
+		{&trxStatusResp{State: trxStateIrreversible, BlockNumber: 90}, models.TrxIrreversible},
+		{&trxStatusResp{State: trxStateFailed}, models.TrxDropped},
+	}
+
+	for _, test := range tests {
+		status, blockNum, err := parseTrxStatus(test.resp, lib)
+		require.NoError(t, err)
+		assert.Equal(t, test.status, status)
+		assert.Equal(t, test.resp.BlockNumber, blockNum)
+	}
+
+	for _, state := range []string{trxStateLocallyApplied, trxStateForkedOut, trxStateUnknown} {
+		_, _, err := parseTrxStatus(&trxStatusResp{State: state}, lib)
+		assert.Equal(t, ErrTrxNotFound, err)
+	}
+
+	_, _, err := parseTrxStatus(&trxStatusResp{State: "SOMETHING"}, lib)
+	assert.Error(t, err)
+}
+
+func TestIsTrxExpired(t *testing.T) {
+	now := time.Now()
+	lib := &IrreversibleBlock{Num: 100, Timestamp: now}
+
+	assert.False(t, lib.IsTrxExpired(now.Add(-TrxExpiration)))
+	assert.True(t, lib.IsTrxExpired(now.Add(-TrxExpiration-time.Second)))
+}
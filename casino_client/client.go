@@ -0,0 +1,242 @@
+package casinoclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/eoscanada/eos-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"net/http"
+	"platform-backend/config"
+	"platform-backend/models"
+	"platform-backend/utils"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	signTransactionPath = "/sign_transaction"
+
+	signTransactionEndpoint = "sign_transaction"
+	webhookEndpoint         = "webhook"
+
+	WebhookIDHeader = "X-Platform-Webhook-Id"
+)
+
+// casino error message
+type casinoErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type casinoSettings struct {
+	timeout time.Duration
+	secret  []byte
+}
+
+// Client sends requests to casino backends, every casino has own timeout,
+// signature secret and circuit breakers of every endpoint
+type Client struct {
+	client           *http.Client
+	defaultTimeout   time.Duration
+	settings         map[uint64]*casinoSettings
+	breakerThreshold int
+	breakerTimeout   time.Duration
+
+	breakersMutex sync.Mutex
+	breakers      map[string]*utils.CircuitBreaker
+
+	requests     *prometheus.CounterVec
+	durations    *prometheus.HistogramVec
+	breakerState *prometheus.GaugeVec
+}
+
+func NewClient(cfg *config.CasinoClientConfig, reg prometheus.Registerer) *Client {
+	settings := make(map[uint64]*casinoSettings, len(cfg.Casinos))
+	for _, casino := range cfg.Casinos {
+		timeout := cfg.Timeout
+		if casino.Timeout > 0 {
+			timeout = casino.Timeout
+		}
+		settings[casino.ID] = &casinoSettings{
+			timeout: time.Duration(timeout) * time.Second,
+			secret:  []byte(casino.Secret),
+		}
+	}
+
+	c := &Client{
+		client:           &http.Client{},
+		defaultTimeout:   time.Duration(cfg.Timeout) * time.Second,
+		settings:         settings,
+		breakerThreshold: cfg.BreakerThreshold,
+		breakerTimeout:   time.Duration(cfg.BreakerTimeout) * time.Second,
+		breakers:         make(map[string]*utils.CircuitBreaker),
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "casino_requests_total",
+				Help: "Requests sent to casino API by endpoint and result",
+			}, []string{"casino", "endpoint", "result"},
+		),
+		durations: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "casino_request_duration_seconds",
+				Help:    "Casino API request time by endpoint",
+				Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+			}, []string{"casino", "endpoint"},
+		),
+		breakerState: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "casino_circuit_breaker_state",
+				Help: "0 - closed, 1 - open, 2 - half-open",
+			}, []string{"casino", "endpoint"},
+		),
+	}
+	reg.MustRegister(c.requests, c.durations, c.breakerState)
+	return c
+}
+
+// SignTransaction sends platform signed transaction to casino, casino signs and pushes it
+func (c *Client) SignTransaction(ctx context.Context, casino *models.Casino, trx *eos.SignedTransaction) error {
+	if err := CheckConfigured(casino); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(trx)
+	if err != nil {
+		return err
+	}
+
+	return c.post(ctx, casino, signTransactionEndpoint, casino.Meta.ApiURL+signTransactionPath, body, nil)
+}
+
+// SendWebhook posts session event to casino webhook url, webhook id lets casino skip duplicates
+func (c *Client) SendWebhook(ctx context.Context, casino *models.Casino, webhook *models.CasinoWebhook) error {
+	if casino.Meta == nil || casino.Meta.WebhookURL == "" {
+		return newError(ErrCasinoNotConfigured, 0, fmt.Errorf("casino %d webhook url not defined", casino.Id))
+	}
+
+	headers := map[string]string{
+		WebhookIDHeader: strconv.FormatUint(webhook.ID, 10),
+	}
+	return c.post(ctx, casino, webhookEndpoint, casino.Meta.WebhookURL, webhook.Payload, headers)
+}
+
+// CheckConfigured returns ErrCasinoNotConfigured if casino backend url is unknown
+func CheckConfigured(casino *models.Casino) error {
+	if casino.Meta == nil || casino.Meta.ApiURL == "" {
+		return newError(ErrCasinoNotConfigured, 0, fmt.Errorf("casino %d", casino.Id))
+	}
+	return nil
+}
+
+func (c *Client) post(
+	ctx context.Context,
+	casino *models.Casino,
+	endpoint string,
+	url string,
+	body []byte,
+	headers map[string]string,
+) error {
+	label := strconv.FormatUint(casino.Id, 10)
+	breaker := c.breaker(label + ":" + endpoint)
+	if err := breaker.Allow(); err != nil {
+		c.requests.WithLabelValues(label, endpoint, "circuit_open").Inc()
+		return newError(ErrCasinoUnavailable, 0, err)
+	}
+
+	start := time.Now()
+	err := c.doPost(ctx, casino.Id, url, body, headers)
+	c.durations.WithLabelValues(label, endpoint).Observe(time.Since(start).Seconds())
+
+	// only unavailability opens circuit, rejected request means casino is alive
+	if errors.Is(err, ErrCasinoUnavailable) {
+		breaker.Failure()
+	} else {
+		breaker.Success()
+	}
+	c.breakerState.WithLabelValues(label, endpoint).Set(float64(breaker.State()))
+	c.requests.WithLabelValues(label, endpoint, requestResult(err)).Inc()
+	return err
+}
+
+func (c *Client) doPost(ctx context.Context, casinoID uint64, url string, body []byte, headers map[string]string) error {
+	settings := c.casinoSettings(casinoID)
+	ctx, cancel := context.WithTimeout(ctx, settings.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return newError(ErrCasinoNotConfigured, 0, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if len(settings.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Signature(settings.secret, timestamp, body))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Debug().Msgf("Casino request error: %s", err.Error())
+		return newError(ErrCasinoUnavailable, 0, err)
+	}
+	// don't forget to close response body
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	errBody, _ := ioutil.ReadAll(resp.Body)
+	log.Debug().Msgf("Error from casino %d backend, code %s, body: %s", casinoID, resp.Status, string(errBody))
+
+	msg := "unknown casino error"
+	casinoErr := &casinoErrorResponse{}
+	if e := json.Unmarshal(errBody, casinoErr); e == nil && casinoErr.Error != "" {
+		msg = casinoErr.Error
+	}
+	return newError(statusErrorKind(resp.StatusCode), resp.StatusCode, errors.New(msg))
+}
+
+func (c *Client) casinoSettings(casinoID uint64) *casinoSettings {
+	if settings, ok := c.settings[casinoID]; ok {
+		return settings
+	}
+	return &casinoSettings{timeout: c.defaultTimeout}
+}
+
+func (c *Client) breaker(key string) *utils.CircuitBreaker {
+	c.breakersMutex.Lock()
+	defer c.breakersMutex.Unlock()
+
+	breaker, ok := c.breakers[key]
+	if !ok {
+		breaker = utils.NewCircuitBreaker(c.breakerThreshold, c.breakerTimeout)
+		c.breakers[key] = breaker
+	}
+	return breaker
+}
+
+func requestResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrCasinoUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrCasinoRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrCasinoAuth):
+		return "unauthorized"
+	case errors.Is(err, ErrCasinoRejected):
+		return "rejected"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,151 @@
+package casinoclient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"platform-backend/config"
+	"platform-backend/models"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCasino(url string) *models.Casino {
+	return &models.Casino{Id: 1, Contract: "casino", Meta: &models.CasinoMeta{ApiURL: url}}
+}
+
+func testTrx() *eos.SignedTransaction {
+	return eos.NewSignedTransaction(eos.NewTransaction(nil, nil))
+}
+
+func TestClientSignsRequest(t *testing.T) {
+	secret := []byte("secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path != signTransactionPath ||
+			!CheckSignature(secret, r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.CasinoClientConfig{
+		Timeout: 5,
+		Casinos: []config.CasinoClientSettings{{ID: 1, Secret: string(secret)}},
+	}
+	client := NewClient(cfg, prometheus.NewRegistry())
+	assert.NoError(t, client.SignTransaction(context.Background(), testCasino(server.URL), testTrx()))
+
+	// wrong secret
+	cfg.Casinos[0].Secret = "other"
+	client = NewClient(cfg, prometheus.NewRegistry())
+	err := client.SignTransaction(context.Background(), testCasino(server.URL), testTrx())
+	assert.True(t, errors.Is(err, ErrCasinoAuth))
+	assert.False(t, IsRetryableError(err))
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		kind      error
+		retryable bool
+	}{
+		{http.StatusBadRequest, ErrCasinoRejected, false},
+		{http.StatusForbidden, ErrCasinoAuth, false},
+		{http.StatusTooManyRequests, ErrCasinoRateLimited, true},
+		{http.StatusInternalServerError, ErrCasinoUnavailable, true},
+	}
+
+	for _, test := range tests {
+		status := test.status
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"error": "casino says no"}`))
+		}))
+
+		client := NewClient(&config.CasinoClientConfig{Timeout: 5, BreakerThreshold: 5}, prometheus.NewRegistry())
+		err := client.SignTransaction(context.Background(), testCasino(server.URL), testTrx())
+		server.Close()
+
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, test.kind), err.Error())
+		assert.Equal(t, test.retryable, IsRetryableError(err))
+		assert.Contains(t, err.Error(), "casino says no")
+	}
+
+	client := NewClient(&config.CasinoClientConfig{Timeout: 5}, prometheus.NewRegistry())
+	err := client.SignTransaction(context.Background(), &models.Casino{Id: 1}, testTrx())
+	assert.True(t, errors.Is(err, ErrCasinoNotConfigured))
+	assert.False(t, IsRetryableError(err))
+}
+
+func TestClientBreakerAndTimeout(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	// default timeout is enough, but casino has own short one
+	cfg := &config.CasinoClientConfig{
+		Timeout:          5,
+		BreakerThreshold: 2,
+		BreakerTimeout:   60,
+		Casinos:          []config.CasinoClientSettings{{ID: 1}},
+	}
+	client := NewClient(cfg, prometheus.NewRegistry())
+	client.settings[1].timeout = 10 * time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		err := client.SignTransaction(context.Background(), testCasino(server.URL), testTrx())
+		assert.True(t, errors.Is(err, ErrCasinoUnavailable))
+	}
+	// circuit is opened after two timeouts
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// other casino has own breaker and default timeout
+	other := testCasino(server.URL)
+	other.Id = 2
+	assert.NoError(t, client.SignTransaction(context.Background(), other, testTrx()))
+}
+
+func TestClientSendWebhook(t *testing.T) {
+	secret := []byte("secret")
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !CheckSignature(secret, r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) ||
+			r.Header.Get(WebhookIDHeader) != "7" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	cfg := &config.CasinoClientConfig{
+		Timeout: 5,
+		Casinos: []config.CasinoClientSettings{{ID: 1, Secret: string(secret)}},
+	}
+	client := NewClient(cfg, prometheus.NewRegistry())
+	webhook := &models.CasinoWebhook{ID: 7, CasinoID: 1, Payload: []byte(`{"event": "session_started"}`)}
+
+	// webhook url is optional
+	err := client.SendWebhook(context.Background(), testCasino(server.URL), webhook)
+	assert.True(t, errors.Is(err, ErrCasinoNotConfigured))
+
+	casino := testCasino("")
+	casino.Meta.WebhookURL = server.URL + "/webhook"
+	assert.NoError(t, client.SendWebhook(context.Background(), casino, webhook))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&received))
+}
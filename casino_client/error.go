@@ -0,0 +1,82 @@
+package casinoclient
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrCasinoNotConfigured = errors.New("casino api url not defined")
+
+	// retryable
+	ErrCasinoUnavailable = errors.New("casino backend unavailable")
+	ErrCasinoRateLimited = errors.New("casino backend rate limit exceeded")
+
+	// permanent
+	ErrCasinoAuth     = errors.New("casino backend rejected request signature")
+	ErrCasinoRejected = errors.New("transaction rejected by casino")
+)
+
+// Error is classified casino request error, errors.Is matches its kind
+type Error struct {
+	Kind      error
+	Retryable bool
+	// http status of casino response, zero if there is no response
+	Status int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Code returns short name of error kind, it's passed to players in failed session update
+func (e *Error) Code() string {
+	switch e.Kind {
+	case ErrCasinoNotConfigured:
+		return "casino_not_configured"
+	case ErrCasinoUnavailable:
+		return "casino_unavailable"
+	case ErrCasinoRateLimited:
+		return "casino_rate_limited"
+	case ErrCasinoAuth:
+		return "casino_auth"
+	case ErrCasinoRejected:
+		return "casino_rejected"
+	default:
+		return "casino_error"
+	}
+}
+
+func newError(kind error, status int, err error) *Error {
+	retryable := kind == ErrCasinoUnavailable || kind == ErrCasinoRateLimited
+	return &Error{Kind: kind, Retryable: retryable, Status: status, Err: err}
+}
+
+// statusErrorKind classifies not OK casino response status
+func statusErrorKind(status int) error {
+	switch {
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return ErrCasinoAuth
+	case status == http.StatusTooManyRequests:
+		return ErrCasinoRateLimited
+	case status >= http.StatusInternalServerError:
+		return ErrCasinoUnavailable
+	default:
+		return ErrCasinoRejected
+	}
+}
+
+// IsRetryableError tells whether casino request can succeed if done again
+func IsRetryableError(err error) bool {
+	var casinoErr *Error
+	return errors.As(err, &casinoErr) && casinoErr.Retryable
+}
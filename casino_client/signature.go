@@ -0,0 +1,26 @@
+package casinoclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	TimestampHeader = "X-Platform-Timestamp"
+	SignatureHeader = "X-Platform-Signature"
+)
+
+// Signature returns hex encoded HMAC-SHA256 of timestamp and request body,
+// casino checks it with the shared secret to authenticate platform requests
+func Signature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckSignature verifies request signature, it is used by casino backends
+func CheckSignature(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Signature(secret, timestamp, body)), []byte(signature))
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/kelseyhightower/envconfig"
 	"io/ioutil"
 	"os"
+	"time"
 )
 
 type DbConfig struct {
@@ -18,20 +19,127 @@ type SessionsCleaner struct {
 	MaxLastUpdate int `json:"maxLastUpdate"`
 }
 
+// Sessions left in intermediate states are inspected again periodically,
+// so sessions waiting for in-flight transactions are driven further later
+type SessionsRecoveryConfig struct {
+	// seconds
+	Interval int `default:"60" json:"interval"`
+}
+
+// Casino signed transactions outbox config
+type CasinoTrxOutboxConfig struct {
+	Workers      int `default:"4" json:"workers"`
+	PollInterval int `default:"1" json:"pollInterval"`
+	MaxAttempts  int `default:"5" json:"maxAttempts"`
+	MinBackoff   int `default:"1" json:"minBackoff"`
+	MaxBackoff   int `default:"60" json:"maxBackoff"`
+}
+
+// Casino webhooks delivery config
+type CasinoWebhooksConfig struct {
+	Workers      int `default:"2" json:"workers"`
+	PollInterval int `default:"1" json:"pollInterval"`
+	MaxAttempts  int `default:"10" json:"maxAttempts"`
+	MinBackoff   int `default:"5" json:"minBackoff"`
+	MaxBackoff   int `default:"600" json:"maxBackoff"`
+}
+
+// Casino backends client config
+type CasinoClientConfig struct {
+	// seconds
+	Timeout int64 `default:"30" json:"timeout"`
+	// consecutive failures to stop requests to casino
+	BreakerThreshold int `default:"5" json:"breakerThreshold"`
+	// seconds
+	BreakerTimeout int64                  `default:"30" json:"breakerTimeout"`
+	Casinos        []CasinoClientSettings `json:"casinos"`
+}
+
+type CasinoClientSettings struct {
+	ID uint64 `json:"id"`
+	// seconds, default timeout is used if zero
+	Timeout int64 `json:"timeout"`
+	// HMAC key of requests signature, requests aren't signed if empty
+	Secret string `json:"secret"`
+}
+
+// Pushed session transactions finality tracker config
+type TrxTrackerConfig struct {
+	Interval int `default:"5" json:"interval"`
+	// seconds, maximum delay of the next check of not final transaction
+	MaxBackoff int `default:"60" json:"maxBackoff"`
+	BatchSize  int `default:"100" json:"batchSize"`
+}
+
+// Sponsorship provider client config
+type SponsorConfig struct {
+	// seconds
+	// request isn't retried by client, failed push is retried with trxPush settings
+	Timeout int64 `default:"5" json:"timeout"`
+	// consecutive failures to stop requests to sponsor
+	BreakerThreshold int `default:"5" json:"breakerThreshold"`
+	// seconds
+	BreakerTimeout int64 `default:"30" json:"breakerTimeout"`
+	// push not sponsored trx if sponsor is unavailable and payer has enough own resources
+	Fallback bool `json:"fallback"`
+	// microseconds
+	FallbackMinCpu int64 `default:"5000" json:"fallbackMinCpu"`
+	// bytes
+	FallbackMinNet int64 `default:"1000" json:"fallbackMinNet"`
+}
+
 type BlockchainConfig struct {
-	NodeUrl    string `json:"nodeUrl"`
-	SponsorUrl string `json:"sponsorUrl"`
-	Contracts  struct {
+	// used if node urls list is empty
+	NodeUrl  string   `json:"nodeUrl"`
+	NodeUrls []string `json:"nodeUrls"`
+	// seconds
+	HealthCheckInterval int64 `default:"5" json:"healthCheckInterval"`
+	// node is excluded while it is more blocks behind the best one
+	MaxHeadBlockLag uint32        `default:"10" json:"maxHeadBlockLag"`
+	SponsorUrl      string        `json:"sponsorUrl"`
+	Sponsor         SponsorConfig `json:"sponsor"`
+	Contracts       struct {
 		Platform string `json:"platform"`
 	} `json:"contracts"`
+	// private keys, used by local signer only
 	Permissions struct {
 		Deposit    string `json:"deposit"`
 		GameAction string `json:"gameaction"`
 		SigniDice  string `json:"signidice"`
 	} `json:"permissions"`
-	DisableSponsor  bool  `json:"disableSponsor"`
-	TrxPushAttempts int   `default:"5" json:"trxPushAttempts"`
-	ListingCacheTTL int64 `json:"listingCacheTTL"`
+	Signer          SignerConfig `json:"signer"`
+	DisableSponsor  bool         `json:"disableSponsor"`
+	TrxPushAttempts int          `default:"5" json:"trxPushAttempts"`
+	// retryable push errors backoff, milliseconds
+	TrxPushMinBackoff int64 `default:"200" json:"trxPushMinBackoff"`
+	TrxPushMaxBackoff int64 `default:"3000" json:"trxPushMaxBackoff"`
+	ListingCacheTTL   int64 `json:"listingCacheTTL"`
+}
+
+// Nodes returns configured node urls
+func (c *BlockchainConfig) Nodes() []string {
+	if len(c.NodeUrls) > 0 {
+		return c.NodeUrls
+	}
+	if c.NodeUrl != "" {
+		return []string{c.NodeUrl}
+	}
+	return nil
+}
+
+// Transactions signer config, "local" signs with permissions private keys,
+// "keosd" sends transactions to keosd compatible signing service
+type SignerConfig struct {
+	Type string `default:"local" json:"type"`
+	Url  string `json:"url"`
+	// seconds
+	Timeout int64 `default:"10" json:"timeout"`
+	// public keys of permissions, required by keosd signer
+	PubKeys struct {
+		Deposit    string `json:"deposit"`
+		GameAction string `json:"gameaction"`
+		SigniDice  string `json:"signidice"`
+	} `json:"pubKeys"`
 }
 
 type AuthConfig struct {
@@ -51,11 +159,58 @@ type AmcConfig struct {
 	ReconnectionAttempts int    `default:"5" json:"reconnectionAttempts"`
 	ReconnectionDelay    int    `default:"5" json:"reconnectionDelay"`
 	Token                string `json:"token"`
+	// forces replay from the offset instead of the last processed one
+	ReplayFromOffset *uint64 `json:"replayFromOffset"`
+}
+
+type EventProcessorConfig struct {
+	Workers   int `default:"8" json:"workers"`
+	QueueSize int `default:"100" json:"queueSize"`
+	// failed events retry policy, backoffs in seconds
+	RetryMaxAttempts int               `default:"10" json:"retryMaxAttempts"`
+	RetryMinBackoff  int64             `default:"5" json:"retryMinBackoff"`
+	RetryMaxBackoff  int64             `default:"600" json:"retryMaxBackoff"`
+	Source           EventSourceConfig `json:"source"`
+	// event types without handlers to subscribe to, they are stored as unknown event updates
+	ExtraEventTypes []int `json:"extraEventTypes"`
+}
+
+// Events are received from action monitor unless replay file is set
+type EventSourceConfig struct {
+	// NDJSON file with recorded events to replay
+	ReplayFile string `json:"replayFile"`
+	// 1 replays in real time, 2 twice faster, 0 without delays
+	ReplaySpeed float64 `default:"1" json:"replaySpeed"`
+	// replay doesn't push transactions, send webhooks, notify players and store processed offset,
+	// only session changes are stored
+	ReplayDryRun bool `json:"replayDryRun"`
+	// received events are appended to the file if set
+	RecordFile string `json:"recordFile"`
+}
+
+// Admin http api config, api is disabled if token is empty
+type AdminConfig struct {
+	Token string `json:"token"`
 }
 
 type SignidiceConfig struct {
 	AccountName string `json:"accountName"`
-	Key         string `json:"key"`
+	// base64 encoded pem key, added to keyring with "default" id
+	Key  string               `json:"key"`
+	Keys []SignidiceKeyConfig `json:"keys"`
+	// json array of keys, reread on reload, keys with same id override config keys
+	KeysFile string `json:"keysFile"`
+	// seconds, keys file isn't reread periodically if zero
+	ReloadInterval int `default:"60" json:"reloadInterval"`
+}
+
+type SignidiceKeyConfig struct {
+	ID string `json:"id"`
+	// base64 encoded pem key
+	Key string `json:"key"`
+	// validity window bounds, unbounded if omitted
+	NotBefore *time.Time `json:"notBefore"`
+	NotAfter  *time.Time `json:"notAfter"`
 }
 
 type AffiliateStatsConfig struct {
@@ -68,20 +223,33 @@ type ActiveFeaturesConfig struct {
 }
 
 type Config struct {
-	Db              DbConfig             `json:"db"`
-	Amc             AmcConfig            `json:"amc"`
-	SessionsCleaner SessionsCleaner      `json:"sessionsCleaner"`
-	Blockchain      BlockchainConfig     `json:"blockchain"`
-	Auth            AuthConfig           `json:"auth"`
-	Signidice       SignidiceConfig      `json:"signidice"`
-	AffiliateStats  AffiliateStatsConfig `json:"affiliateStats"`
-	ActiveFeatures  ActiveFeaturesConfig `json:"activeFeatures"`
-	LogLevel        string               `json:"loglevel"`
-	Port            string               `json:"port"`
+	Db               DbConfig               `json:"db"`
+	Amc              AmcConfig              `json:"amc"`
+	EventProcessor   EventProcessorConfig   `json:"eventProcessor"`
+	SessionsCleaner  SessionsCleaner        `json:"sessionsCleaner"`
+	SessionsRecovery SessionsRecoveryConfig `json:"sessionsRecovery"`
+	Blockchain       BlockchainConfig       `json:"blockchain"`
+	CasinoTrxOutbox  CasinoTrxOutboxConfig  `json:"casinoTrxOutbox"`
+	TrxTracker       TrxTrackerConfig       `json:"trxTracker"`
+	CasinoClient     CasinoClientConfig     `json:"casinoClient"`
+	CasinoWebhooks   CasinoWebhooksConfig   `json:"casinoWebhooks"`
+	Auth             AuthConfig             `json:"auth"`
+	Admin            AdminConfig            `json:"admin"`
+	Signidice        SignidiceConfig        `json:"signidice"`
+	AffiliateStats   AffiliateStatsConfig   `json:"affiliateStats"`
+	ActiveFeatures   ActiveFeaturesConfig   `json:"activeFeatures"`
+	LogLevel         string                 `json:"loglevel"`
+	Port             string                 `json:"port"`
 }
 
+// Read returns config file values over defaults,
+// envconfig sets default of every unset env var, so it's processed before the file is read
 func Read(fileName string) (*Config, error) {
 	appConfig := &Config{}
+	err := envconfig.Process("", appConfig)
+	if err != nil {
+		return nil, err
+	}
 	data, err := ioutil.ReadFile(fileName)
 	if err == nil {
 		err = json.Unmarshal(data, appConfig)
@@ -91,9 +259,5 @@ func Read(fileName string) (*Config, error) {
 	} else if !os.IsNotExist(err) {
 		return nil, err
 	}
-	err = envconfig.Process("", appConfig)
-	if err != nil {
-		return nil, err
-	}
 	return appConfig, nil
 }
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTestConfig(t *testing.T, data string) *Config {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "config.json")
+	require.NoError(t, ioutil.WriteFile(fileName, []byte(data), 0600))
+	cfg, err := Read(fileName)
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestReadReplaySpeed(t *testing.T) {
+	cfg := readTestConfig(t, `{"eventProcessor": {"source": {"replaySpeed": 0}}}`)
+	// zero means replay without delays
+	assert.Equal(t, 0.0, cfg.EventProcessor.Source.ReplaySpeed)
+
+	cfg = readTestConfig(t, `{}`)
+	assert.Equal(t, 1.0, cfg.EventProcessor.Source.ReplaySpeed)
+}
+
+func TestReadSignidiceReloadInterval(t *testing.T) {
+	cfg := readTestConfig(t, `{"signidice": {"reloadInterval": 0}}`)
+	// zero means keys aren't reread
+	assert.Equal(t, 0, cfg.Signidice.ReloadInterval)
+
+	cfg = readTestConfig(t, `{}`)
+	assert.Equal(t, 60, cfg.Signidice.ReloadInterval)
+}
+
+func TestReadDefaults(t *testing.T) {
+	cfg := readTestConfig(t, `{
+		"eventProcessor": {"workers": 2},
+		"casinoTrxOutbox": {"workers": 1, "maxAttempts": 3},
+		"casinoWebhooks": {"workers": 1},
+		"trxTracker": {"batchSize": 10},
+		"activeFeatures": {"referrals": false}
+	}`)
+	assert.Equal(t, 2, cfg.EventProcessor.Workers)
+	assert.Equal(t, 1, cfg.CasinoTrxOutbox.Workers)
+	assert.Equal(t, 3, cfg.CasinoTrxOutbox.MaxAttempts)
+	assert.Equal(t, 1, cfg.CasinoWebhooks.Workers)
+	assert.Equal(t, 10, cfg.TrxTracker.BatchSize)
+	assert.False(t, cfg.ActiveFeatures.Referrals)
+
+	// fields missing in file keep defaults
+	assert.Equal(t, 100, cfg.EventProcessor.QueueSize)
+	assert.Equal(t, 60, cfg.CasinoTrxOutbox.MaxBackoff)
+	assert.Equal(t, 10, cfg.CasinoWebhooks.MaxAttempts)
+	assert.True(t, cfg.ActiveFeatures.Bonus)
+}
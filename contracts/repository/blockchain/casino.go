@@ -180,10 +180,11 @@ func toModelCasino(c *Casino) *models.Casino {
 	}
 
 	return &models.Casino{
-		Id:       uint64(c.Id),
-		Contract: c.Contract,
-		Paused:   !(c.Paused == 0),
-		Meta:     meta,
+		Id:        uint64(c.Id),
+		Contract:  c.Contract,
+		Paused:    !(c.Paused == 0),
+		Meta:      meta,
+		RsaPubkey: c.RsaPubkey,
 	}
 }
 
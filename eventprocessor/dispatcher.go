@@ -0,0 +1,256 @@
+package eventprocessor
+
+import (
+	"context"
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"platform-backend/models"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type queuedEvent struct {
+	event *eventlistener.Event
+	// not nil for retried events
+	failed   *models.FailedEvent
+	queuedAt time.Time
+}
+
+// Dispatcher processes events in parallel, events are sharded by request id,
+// so events of the same session are processed strictly in order
+type Dispatcher struct {
+	processor *EventProcessor
+	shards    []chan *queuedEvent
+	repo      Repository
+	offsets   *offsetTracker
+	// key of events source processed offset is stored under
+	offsetSource string
+
+	queueDepth *prometheus.GaugeVec
+	shardLag   *prometheus.GaugeVec
+	// time since event receiving, it isn't lag behind blockchain
+	// TODO: observe block to processing lag when action monitor events get block time
+	processingLag  prometheus.Histogram
+	lastReceived   prometheus.Gauge
+	lastProcessed  prometheus.Gauge
+	eventsReceived prometheus.Counter
+}
+
+const (
+	// offsetCommitInterval is how often the last processed offset is stored
+	offsetCommitInterval = time.Second
+	// retryPollInterval is how often dead-letter queue is checked for due events
+	retryPollInterval = time.Second
+	// claimed failed event isn't claimed again until lease expires
+	failedEventLease = 5 * time.Minute
+)
+
+func NewDispatcher(
+	processor *EventProcessor,
+	repo Repository,
+	offsetSource string,
+	workers int,
+	queueSize int,
+	reg prometheus.Registerer,
+) *Dispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	queueDepth := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "event_shard_queue_depth",
+			Help: "Events waiting in shard queue",
+		}, []string{"shard"},
+	)
+	shardLag := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "event_shard_lag_seconds",
+			Help: "Time the last processed event waited in shard queue",
+		}, []string{"shard"},
+	)
+	processingLag := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "event_received_to_processed_seconds",
+			Help:    "Time from receiving event to the end of its processing",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+		},
+	)
+	lastReceived := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "event_last_received_offset",
+			Help: "Offset of the last event received from source",
+		},
+	)
+	lastProcessed := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "event_last_processed_offset",
+			Help: "Offset all events up to which are processed",
+		},
+	)
+	eventsReceived := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "events_received_total",
+			Help: "Events received from source",
+		},
+	)
+	reg.MustRegister(queueDepth, shardLag, processingLag, lastReceived, lastProcessed, eventsReceived)
+
+	shards := make([]chan *queuedEvent, workers)
+	for i := range shards {
+		shards[i] = make(chan *queuedEvent, queueSize)
+	}
+
+	return &Dispatcher{
+		processor:      processor,
+		shards:         shards,
+		repo:           repo,
+		offsets:        newOffsetTracker(),
+		offsetSource:   offsetSource,
+		queueDepth:     queueDepth,
+		shardLag:       shardLag,
+		processingLag:  processingLag,
+		lastReceived:   lastReceived,
+		lastProcessed:  lastProcessed,
+		eventsReceived: eventsReceived,
+	}
+}
+
+// Dispatch queues event to its shard, blocks while shard queue is full
+func (d *Dispatcher) Dispatch(ctx context.Context, event *eventlistener.Event) {
+	d.offsets.add(event.Offset)
+	d.eventsReceived.Inc()
+	d.lastReceived.Set(float64(event.Offset))
+	d.enqueue(ctx, &queuedEvent{event: event})
+}
+
+// enqueue uses the same shard for live and retried events of a session to keep their order
+func (d *Dispatcher) enqueue(ctx context.Context, queued *queuedEvent) {
+	shard := int(queued.event.RequestID % uint64(len(d.shards)))
+	queued.queuedAt = time.Now()
+	select {
+	case d.shards[shard] <- queued:
+		d.queueDepth.WithLabelValues(strconv.Itoa(shard)).Inc()
+	case <-ctx.Done():
+	}
+}
+
+// StartOffset returns offset to subscribe from: the last processed one,
+// it is delivered again but skipped by session offset check
+func (d *Dispatcher) StartOffset(ctx context.Context) (uint64, error) {
+	offset, err := d.repo.GetLastOffset(ctx, d.offsetSource)
+	if err != nil || offset == nil {
+		return 0, err
+	}
+	return *offset, nil
+}
+
+// Run processes queued events until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Info().Msgf("Event dispatcher is started with %d shards", len(d.shards))
+
+	wg := sync.WaitGroup{}
+	for i := range d.shards {
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+			d.runShard(ctx, shard)
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.runRetries(ctx)
+	}()
+
+	ticker := time.NewTicker(offsetCommitInterval)
+	var committed *uint64
+	for done := false; !done; {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			ticker.Stop()
+			wg.Wait()
+			done = true
+		}
+		committed = d.commitOffset(committed)
+	}
+
+	log.Info().Msg("Event dispatcher is stopped")
+}
+
+// commitOffset stores the last processed offset if it is changed since committed one
+func (d *Dispatcher) commitOffset(committed *uint64) *uint64 {
+	last := d.offsets.lastProcessed()
+	if last == nil || committed != nil && *committed == *last {
+		return committed
+	}
+	d.lastProcessed.Set(float64(*last))
+	if d.processor.dryRun {
+		return last
+	}
+
+	// use own context, so the final offset is stored on shutdown too
+	ctx, cancel := context.WithTimeout(context.Background(), offsetCommitInterval)
+	defer cancel()
+
+	if err := d.repo.SetLastOffset(ctx, d.offsetSource, *last); err != nil {
+		log.Error().Msgf("Failed to store last processed offset: %s", err.Error())
+		return committed
+	}
+	return last
+}
+
+func (d *Dispatcher) runShard(ctx context.Context, shard int) {
+	label := strconv.Itoa(shard)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case queued := <-d.shards[shard]:
+			d.queueDepth.WithLabelValues(label).Dec()
+			d.shardLag.WithLabelValues(label).Set(time.Since(queued.queuedAt).Seconds())
+			if queued.failed != nil {
+				d.processor.Retry(ctx, queued.failed)
+				continue
+			}
+			// not stored event is delivered again after restart
+			if !d.processor.Process(ctx, queued.event) {
+				continue
+			}
+			d.offsets.complete(queued.event.Offset)
+			d.processingLag.Observe(time.Since(queued.queuedAt).Seconds())
+		}
+	}
+}
+
+// runRetries dispatches due events from dead-letter queue until ctx is done
+func (d *Dispatcher) runRetries(ctx context.Context) {
+	ticker := time.NewTicker(retryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		failed, err := d.repo.ClaimFailedEvents(ctx, len(d.shards), failedEventLease)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error().Msgf("Failed events claim error: %s", err.Error())
+			}
+			continue
+		}
+
+		for _, event := range failed {
+			log.Info().Msgf("Retry failed event, session: %d, offset: %d, attempts: %d",
+				event.SessionID, event.Offset, event.Attempts)
+			d.enqueue(ctx, &queuedEvent{event: event.Event, failed: event})
+		}
+	}
+}
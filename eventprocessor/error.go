@@ -0,0 +1,7 @@
+package eventprocessor
+
+import "errors"
+
+var (
+	ErrFailedEventNotFound = errors.New("failed event not found")
+)
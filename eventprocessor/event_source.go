@@ -0,0 +1,12 @@
+package eventprocessor
+
+import (
+	"context"
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+)
+
+// EventSource delivers action monitor events to processor
+type EventSource interface {
+	// Run sends events starting from the offset until ctx is done
+	Run(ctx context.Context, offset uint64, events chan<- *eventlistener.Event) error
+}
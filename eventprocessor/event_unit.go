@@ -0,0 +1,42 @@
+package eventprocessor
+
+import (
+	"context"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+)
+
+// EventUnit collects session writes of one event, subscribers are notified only after they are committed
+type EventUnit struct {
+	gamesessions.UnitOfWork
+	// dry run unit commits only session writes, notifications, webhooks and after commit fns are dropped
+	dryRun      bool
+	updates     []*models.GameSessionUpdate
+	afterCommit []func(context.Context) error
+}
+
+// Notify schedules session update notification, it's sent after commit
+func (u *EventUnit) Notify(update *models.GameSessionUpdate) {
+	if u.dryRun {
+		return
+	}
+	u.updates = append(u.updates, update)
+}
+
+// AddCasinoWebhook queues webhook, it's delivered only if unit is committed
+func (u *EventUnit) AddCasinoWebhook(ctx context.Context, webhook *models.CasinoWebhook) error {
+	if u.dryRun {
+		return nil
+	}
+	return u.UnitOfWork.AddCasinoWebhook(ctx, webhook)
+}
+
+// AfterCommit schedules fn, it's called only if unit is committed, so blockchain transaction
+// isn't pushed again when failed event is retried. Session left in trx sent state by failed fn
+// is driven further by sessions recovery.
+func (u *EventUnit) AfterCommit(fn func(context.Context) error) {
+	if u.dryRun {
+		return
+	}
+	u.afterCommit = append(u.afterCommit, fn)
+}
@@ -31,20 +31,36 @@ type messageUpdateData struct {
 	Msg []uint64 `json:"msg"`
 }
 
-func notifySubscibers(ctx context.Context, p *EventProcessor, session *models.GameSession) error {
-	updates, err := p.repos.GameSession.GetGameSessionUpdates(ctx, session.ID)
-	if err != nil {
-		return err
-	}
-	updateMsgs := make([]*models.GameSessionUpdateMsg, len(updates))
-	for i, update := range updates {
-		updateMsgs[i] = models.ToGameSessionUpdateMsg(update)
+type unknownEventUpdateData struct {
+	EventType eventlistener.EventType `json:"eventType"`
+	Data      json.RawMessage         `json:"data"`
+}
+
+// notifySubscibers sends only the new update, previous offset lets client detect missed updates
+func notifySubscibers(ctx context.Context, p *EventProcessor, session *models.GameSession, update *models.GameSessionUpdate) error {
+	var prevOffset *uint64
+	// update with zero offset can't have predecessors with offset
+	if update.Offset == nil || *update.Offset > 0 {
+		var maxOffset *uint64
+		if update.Offset != nil {
+			offset := *update.Offset - 1
+			maxOffset = &offset
+		}
+
+		var err error
+		prevOffset, err = p.repos.GameSession.GetLastGameSessionUpdateOffset(ctx, session.ID, maxOffset)
+		if err != nil {
+			return err
+		}
 	}
-	go p.useCases.Subscriptions.Notify(session.Player, "session_update", updateMsgs)
+
+	updateMsgs := models.ToGameSessionUpdateMsgs([]*models.GameSessionUpdate{update}, prevOffset)
+	// not in goroutine, updates of session are delivered in commit order
+	p.useCases.Subscriptions.Notify(session.Player, "session_update", updateMsgs)
 	return nil
 }
 
-func onGameStarted(ctx context.Context, p *EventProcessor, event *eventlistener.Event, session *models.GameSession) error {
+func onGameStarted(ctx context.Context, p *EventProcessor, unit *EventUnit, event *eventlistener.Event, session *models.GameSession) error {
 	log.Debug().Msgf("Got started event for session: %d", session.ID)
 
 	update := &models.GameSessionUpdate{
@@ -55,7 +71,7 @@ func onGameStarted(ctx context.Context, p *EventProcessor, event *eventlistener.
 		Offset:     &event.Offset,
 	}
 
-	err := p.repos.GameSession.AddGameSessionUpdate(ctx, update)
+	err := unit.AddGameSessionUpdate(ctx, update)
 	if err != nil && err != gamesessions.ErrUpdateAlreadyProcessed {
 		return err
 	} else {
@@ -66,21 +82,25 @@ func onGameStarted(ctx context.Context, p *EventProcessor, event *eventlistener.
 
 	// if not already processed event
 	if err == nil {
-		err = p.repos.GameSession.UpdateSessionState(ctx, session.ID, models.GameStartedInBC)
+		err = unit.UpdateSessionState(ctx, session.ID, models.GameStartedInBC)
+		if err != nil {
+			return err
+		}
+		err = gamesessions.AddCasinoWebhook(ctx, unit, p.repos.Contracts, models.WebhookSessionStarted, session, nil, "")
 		if err != nil {
 			return err
 		}
 	}
 
-	err = notifySubscibers(ctx, p, session)
-	if err != nil {
-		return err
+	// notify only about newly added update
+	if err == nil {
+		unit.Notify(update)
 	}
 
 	return nil
 }
 
-func onActionRequest(ctx context.Context, p *EventProcessor, event *eventlistener.Event, session *models.GameSession) error {
+func onActionRequest(ctx context.Context, p *EventProcessor, unit *EventUnit, event *eventlistener.Event, session *models.GameSession) error {
 	log.Debug().Msgf("Got action request event for session: %d", session.ID)
 
 	// first action
@@ -94,17 +114,20 @@ func onActionRequest(ctx context.Context, p *EventProcessor, event *eventlistene
 			return err
 		}
 
-		log.Debug().Msgf("Try to perform first game action for session: %d", session.ID)
-		err = p.useCases.GameSession.GameAction(ctx, session.ID, action.Type, action.Params)
+		err = unit.UpdateSessionState(ctx, session.ID, models.GameActionTrxSent)
 		if err != nil {
 			return err
 		}
-
-		err = p.repos.GameSession.DeleteFirstGameAction(ctx, session.ID)
+		err = unit.DeleteFirstGameAction(ctx, session.ID)
 		if err != nil {
 			return err
 		}
 
+		// trx is pushed only once even if event is retried
+		unit.AfterCommit(func(ctx context.Context) error {
+			log.Debug().Msgf("Try to perform first game action for session: %d", session.ID)
+			return p.useCases.GameSession.PushGameAction(ctx, session, action.Type, action.Params)
+		})
 		return nil
 	}
 
@@ -116,7 +139,7 @@ func onActionRequest(ctx context.Context, p *EventProcessor, event *eventlistene
 		Offset:     &event.Offset,
 	}
 
-	err := p.repos.GameSession.AddGameSessionUpdate(ctx, update)
+	err := unit.AddGameSessionUpdate(ctx, update)
 	if err != nil && err != gamesessions.ErrUpdateAlreadyProcessed {
 		return err
 	} else {
@@ -127,7 +150,7 @@ func onActionRequest(ctx context.Context, p *EventProcessor, event *eventlistene
 
 	// if not already processed event
 	if err == nil {
-		err = p.repos.GameSession.UpdateSessionState(ctx, session.ID, models.RequestedGameAction)
+		err = unit.UpdateSessionState(ctx, session.ID, models.RequestedGameAction)
 		if err != nil {
 			return err
 		}
@@ -136,7 +159,7 @@ func onActionRequest(ctx context.Context, p *EventProcessor, event *eventlistene
 	return nil
 }
 
-func onSignidicePartOneRequest(ctx context.Context, p *EventProcessor, event *eventlistener.Event, session *models.GameSession) error {
+func onSignidicePartOneRequest(ctx context.Context, p *EventProcessor, unit *EventUnit, event *eventlistener.Event, session *models.GameSession) error {
 	log.Debug().Msgf("Got signidice one request event for session: %d", session.ID)
 
 	gs, err := p.repos.GameSession.GetGameSession(ctx, event.RequestID)
@@ -155,22 +178,25 @@ func onSignidicePartOneRequest(ctx context.Context, p *EventProcessor, event *ev
 		return err
 	}
 
-	err = p.useCases.Signidice.PerformSignidice(ctx, game.Contract, data.Digest, gs.BlockchainSesID)
-	if err != nil {
-		return err
-	}
-
-	err = p.repos.GameSession.UpdateSessionState(ctx, session.ID, models.SignidicePartOneTrxSent)
+	err = unit.UpdateSessionState(ctx, session.ID, models.SignidicePartOneTrxSent)
 	if err != nil {
 		return err
 	}
 
-	log.Debug().Msgf("Successfully signed and sent signidice for session: %d", gs.ID)
+	// trx is pushed only once even if event is retried
+	unit.AfterCommit(func(ctx context.Context) error {
+		err := p.useCases.Signidice.PerformSignidice(ctx, gs, game.Contract, data.Digest)
+		if err != nil {
+			return err
+		}
+		log.Debug().Msgf("Successfully signed and sent signidice for session: %d", gs.ID)
+		return nil
+	})
 
 	return nil
 }
 
-func onGameFinished(ctx context.Context, p *EventProcessor, event *eventlistener.Event, session *models.GameSession) error {
+func onGameFinished(ctx context.Context, p *EventProcessor, unit *EventUnit, event *eventlistener.Event, session *models.GameSession) error {
 	log.Debug().Msgf("Got finished event for session: %d", session.ID)
 
 	var eventData finishedEventData
@@ -203,7 +229,7 @@ func onGameFinished(ctx context.Context, p *EventProcessor, event *eventlistener
 		Offset:     &event.Offset,
 	}
 
-	err = p.repos.GameSession.AddGameSessionUpdate(ctx, update)
+	err = unit.AddGameSessionUpdate(ctx, update)
 	if err != nil && err != gamesessions.ErrUpdateAlreadyProcessed {
 		return err
 	}
@@ -213,26 +239,31 @@ func onGameFinished(ctx context.Context, p *EventProcessor, event *eventlistener
 
 	// if not already processed event
 	if err == nil {
-		err = p.repos.GameSession.UpdateSessionPlayerWin(ctx, session.ID, eventData.PlayerWin.String())
+		err = unit.UpdateSessionPlayerWin(ctx, session.ID, eventData.PlayerWin.String())
 		if err != nil {
 			return err
 		}
 
-		err = p.repos.GameSession.UpdateSessionState(ctx, session.ID, models.GameFinished)
+		err = unit.UpdateSessionState(ctx, session.ID, models.GameFinished)
+		if err != nil {
+			return err
+		}
+		err = gamesessions.AddCasinoWebhook(ctx, unit, p.repos.Contracts, models.WebhookSessionFinished, session,
+			&eventData.PlayerWin, "")
 		if err != nil {
 			return err
 		}
 	}
 
-	err = notifySubscibers(ctx, p, session)
-	if err != nil {
-		return err
+	// notify only about newly added update
+	if err == nil {
+		unit.Notify(update)
 	}
 
 	return nil
 }
 
-func onGameFailed(ctx context.Context, p *EventProcessor, event *eventlistener.Event, session *models.GameSession) error {
+func onGameFailed(ctx context.Context, p *EventProcessor, unit *EventUnit, event *eventlistener.Event, session *models.GameSession) error {
 	log.Debug().Msgf("Got failed event for session: %d", session.ID)
 
 	update := &models.GameSessionUpdate{
@@ -243,7 +274,7 @@ func onGameFailed(ctx context.Context, p *EventProcessor, event *eventlistener.E
 		Offset:     &event.Offset,
 	}
 
-	err := p.repos.GameSession.AddGameSessionUpdate(ctx, update)
+	err := unit.AddGameSessionUpdate(ctx, update)
 	if err != nil && err != gamesessions.ErrUpdateAlreadyProcessed {
 		return err
 	} else {
@@ -252,28 +283,32 @@ func onGameFailed(ctx context.Context, p *EventProcessor, event *eventlistener.E
 		)
 	}
 
-	// if not already processed event
-	if err == nil {
+	// if not already processed event and session isn't failed by backend before
+	if err == nil && session.State != models.GameFailed {
 		p.failedSessionCounter.WithLabelValues(strconv.Itoa(int(session.State))).Add(1)
-		err = p.repos.GameSession.UpdateSessionStateBeforeFail(ctx, session.ID, session.State)
+		err = unit.UpdateSessionStateBeforeFail(ctx, session.ID, session.State)
 		if err != nil {
 			return err
 		}
-		err = p.repos.GameSession.UpdateSessionState(ctx, session.ID, models.GameFailed)
+		err = unit.UpdateSessionState(ctx, session.ID, models.GameFailed)
+		if err != nil {
+			return err
+		}
+		err = gamesessions.AddCasinoWebhook(ctx, unit, p.repos.Contracts, models.WebhookSessionFailed, session, nil, "")
 		if err != nil {
 			return err
 		}
 	}
 
-	err = notifySubscibers(ctx, p, session)
-	if err != nil {
-		return err
+	// notify only about newly added update
+	if err == nil {
+		unit.Notify(update)
 	}
 
 	return nil
 }
 
-func onGameMessage(ctx context.Context, p *EventProcessor, event *eventlistener.Event, session *models.GameSession) error {
+func onGameMessage(ctx context.Context, p *EventProcessor, unit *EventUnit, event *eventlistener.Event, session *models.GameSession) error {
 	log.Debug().Msgf("Got game message event for session: %d", session.ID)
 	var eventData messageEventData
 	err := json.Unmarshal(event.Data, &eventData)
@@ -304,7 +339,7 @@ func onGameMessage(ctx context.Context, p *EventProcessor, event *eventlistener.
 		Offset:     &event.Offset,
 	}
 
-	err = p.repos.GameSession.AddGameSessionUpdate(ctx, update)
+	err = unit.AddGameSessionUpdate(ctx, update)
 	if err != nil && err != gamesessions.ErrUpdateAlreadyProcessed {
 		return err
 	}
@@ -312,10 +347,41 @@ func onGameMessage(ctx context.Context, p *EventProcessor, event *eventlistener.
 		session.ID, event.EventType, event.Offset,
 	)
 
-	err = notifySubscibers(ctx, p, session)
+	// notify only about newly added update
+	if err == nil {
+		unit.Notify(update)
+	}
+
+	return nil
+}
+
+// onUnknownEvent stores event without registered handler as is and forwards it to subscribers
+func onUnknownEvent(ctx context.Context, p *EventProcessor, unit *EventUnit, event *eventlistener.Event, session *models.GameSession) error {
+	updateData, err := json.Marshal(unknownEventUpdateData{
+		EventType: event.EventType,
+		Data:      event.Data,
+	})
 	if err != nil {
 		return err
 	}
 
+	update := &models.GameSessionUpdate{
+		SessionID:  session.ID,
+		UpdateType: models.UnknownEventUpdate,
+		Timestamp:  time.Now(),
+		Data:       updateData,
+		Offset:     &event.Offset,
+	}
+
+	err = unit.AddGameSessionUpdate(ctx, update)
+	if err != nil && err != gamesessions.ErrUpdateAlreadyProcessed {
+		return err
+	}
+
+	// notify only about newly added update
+	if err == nil {
+		unit.Notify(update)
+	}
+
 	return nil
 }
@@ -0,0 +1,87 @@
+package eventprocessor
+
+import (
+	"context"
+	"errors"
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/eoscanada/eos-go"
+	"github.com/jackc/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	gamesessions "platform-backend/game_sessions"
+	"strconv"
+)
+
+// handler label of events without registered handler
+const unknownHandlerName = "unknown"
+
+var eventTypeNames = map[eventlistener.EventType]string{
+	gameStarted:             "game_started",
+	actionRequest:           "action_request",
+	signidicePartOneRequest: "signidice_part_one_request",
+	gameFinished:            "game_finished",
+	gameFailed:              "game_failed",
+	gameMessage:             "game_message",
+}
+
+type processorMetrics struct {
+	handlerDuration *prometheus.HistogramVec
+	errors          *prometheus.CounterVec
+}
+
+func newProcessorMetrics(reg prometheus.Registerer) *processorMetrics {
+	m := &processorMetrics{
+		handlerDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "event_handler_duration_seconds",
+				Help:    "Event handling time including session writes commit",
+				Buckets: prometheus.ExponentialBuckets(0.005, 2, 14),
+			}, []string{"handler"},
+		),
+		errors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "event_errors_total",
+				Help: "Event handling errors by handler and cause, retried event is counted on each failure",
+			}, []string{"handler", "cause"},
+		),
+	}
+	reg.MustRegister(m.handlerDuration, m.errors)
+	return m
+}
+
+// handlerName returns metrics label of event type handler
+func handlerName(eventType eventlistener.EventType, registered bool) string {
+	if !registered {
+		return unknownHandlerName
+	}
+	if name, ok := eventTypeNames[eventType]; ok {
+		return name
+	}
+	return "event_" + strconv.Itoa(int(eventType))
+}
+
+// errorCause returns metrics label of event processing error
+func errorCause(err error) string {
+	var (
+		pgErr  *pgconn.PgError
+		eosErr eos.APIError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, gamesessions.ErrIllegalStateTransition):
+		return "illegal_state_transition"
+	case errors.Is(err, gamesessions.ErrSessionVersionConflict),
+		errors.Is(err, gamesessions.ErrConcurrentGameAction):
+		return "concurrent_update"
+	case errors.Is(err, gamesessions.ErrGameSessionNotFound):
+		return "session_not_found"
+	case errors.As(err, &pgErr):
+		return "database"
+	case errors.As(err, &eosErr):
+		return "blockchain"
+	default:
+		return "other"
+	}
+}
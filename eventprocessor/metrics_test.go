@@ -0,0 +1,37 @@
+package eventprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	gamesessions "platform-backend/game_sessions"
+)
+
+func TestErrorCause(t *testing.T) {
+	tests := []struct {
+		err   error
+		cause string
+	}{
+		{context.DeadlineExceeded, "timeout"},
+		{fmt.Errorf("push: %w", context.Canceled), "canceled"},
+		{gamesessions.ErrIllegalStateTransition, "illegal_state_transition"},
+		{gamesessions.ErrSessionVersionConflict, "concurrent_update"},
+		{&pgconn.PgError{Code: "40001"}, "database"},
+		{eos.APIError{Code: 500}, "blockchain"},
+		{errors.New("boom"), "other"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.cause, errorCause(test.err), test.err.Error())
+	}
+}
+
+func TestHandlerName(t *testing.T) {
+	assert.Equal(t, "game_finished", handlerName(gameFinished, true))
+	assert.Equal(t, "event_9", handlerName(9, true))
+	assert.Equal(t, unknownHandlerName, handlerName(9, false))
+}
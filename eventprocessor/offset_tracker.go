@@ -0,0 +1,46 @@
+package eventprocessor
+
+import "sync"
+
+// offsetTracker tracks the last offset such that all dispatched events up to it are processed
+type offsetTracker struct {
+	sync.Mutex
+	// dispatched and not yet committed offsets in dispatch order
+	inFlight []uint64
+	done     map[uint64]bool
+	last     *uint64
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		done: make(map[uint64]bool),
+	}
+}
+
+func (t *offsetTracker) add(offset uint64) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.inFlight = append(t.inFlight, offset)
+}
+
+func (t *offsetTracker) complete(offset uint64) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.done[offset] = true
+	for len(t.inFlight) > 0 && t.done[t.inFlight[0]] {
+		last := t.inFlight[0]
+		t.last = &last
+		delete(t.done, last)
+		t.inFlight = t.inFlight[1:]
+	}
+}
+
+// lastProcessed returns nil if no events were processed
+func (t *offsetTracker) lastProcessed() *uint64 {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.last
+}
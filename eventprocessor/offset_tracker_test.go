@@ -0,0 +1,31 @@
+package eventprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker()
+	assert.Nil(t, tracker.lastProcessed())
+
+	for _, offset := range []uint64{10, 11, 15, 20} {
+		tracker.add(offset)
+	}
+
+	// events from different shards complete out of order
+	tracker.complete(15)
+	assert.Nil(t, tracker.lastProcessed())
+
+	tracker.complete(10)
+	assert.Equal(t, uint64(10), *tracker.lastProcessed())
+
+	tracker.complete(11)
+	assert.Equal(t, uint64(15), *tracker.lastProcessed())
+
+	tracker.complete(20)
+	assert.Equal(t, uint64(20), *tracker.lastProcessed())
+	assert.Len(t, tracker.inFlight, 0)
+	assert.Len(t, tracker.done, 0)
+}
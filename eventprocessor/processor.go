@@ -9,6 +9,8 @@ import (
 	"platform-backend/models"
 	"platform-backend/repositories"
 	"platform-backend/usecases"
+	"platform-backend/utils"
+	"time"
 )
 
 // game events
@@ -21,9 +23,10 @@ const (
 	gameMessage             = 6
 )
 
-type UpdateHandler = func(context.Context, *EventProcessor, *eventlistener.Event, *models.GameSession) error
+// UpdateHandler must write session changes via unit, they are committed together with session offset
+type UpdateHandler = func(context.Context, *EventProcessor, *EventUnit, *eventlistener.Event, *models.GameSession) error
 
-var handlersMap = map[eventlistener.EventType]UpdateHandler{
+var defaultHandlers = map[eventlistener.EventType]UpdateHandler{
 	gameStarted:             onGameStarted,
 	actionRequest:           onActionRequest,
 	signidicePartOneRequest: onSignidicePartOneRequest,
@@ -32,17 +35,36 @@ var handlersMap = map[eventlistener.EventType]UpdateHandler{
 	gameMessage:             onGameMessage,
 }
 
+// RetryPolicy describes retries of failed events
+type RetryPolicy struct {
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	MaxAttempts int
+}
+
+// backoff returns exponential delay before the next attempt
+func (r *RetryPolicy) backoff(attempts int) time.Duration {
+	return utils.Backoff(r.MinBackoff, r.MaxBackoff, attempts)
+}
+
 type EventProcessor struct {
 	repos                *repositories.Repos
+	eventsRepo           Repository
 	blockchain           *blockchain.Blockchain
 	useCases             *usecases.UseCases
+	retryPolicy          *RetryPolicy
+	handlers             *HandlersRegistry
+	metrics              *processorMetrics
 	failedSessionCounter *prometheus.CounterVec
+	dryRun               bool
 }
 
 func New(
 	repos *repositories.Repos,
+	eventsRepo Repository,
 	blockchain *blockchain.Blockchain,
 	useCases *usecases.UseCases,
+	retryPolicy *RetryPolicy,
 	reg prometheus.Registerer,
 ) *EventProcessor {
 	failedSessionCounter := prometheus.NewCounterVec(
@@ -53,51 +75,190 @@ func New(
 
 	reg.MustRegister(failedSessionCounter)
 
+	handlers := NewHandlersRegistry(onUnknownEvent)
+	for eventType, handler := range defaultHandlers {
+		handlers.Register(eventType, handler)
+	}
+
 	return &EventProcessor{
 		repos:                repos,
+		eventsRepo:           eventsRepo,
 		blockchain:           blockchain,
 		useCases:             useCases,
+		retryPolicy:          retryPolicy,
+		handlers:             handlers,
+		metrics:              newProcessorMetrics(reg),
 		failedSessionCounter: failedSessionCounter,
 	}
 }
 
-func (p *EventProcessor) Process(ctx context.Context, event *eventlistener.Event) {
+// EnableDryRun makes processor store only session changes of events without pushing transactions,
+// queuing webhooks and notifying players, processed offset isn't stored by dispatcher then
+func (p *EventProcessor) EnableDryRun() {
+	p.dryRun = true
+}
+
+// Process returns false if event is neither handled nor stored to dead-letter queue,
+// its offset mustn't be committed then
+func (p *EventProcessor) Process(ctx context.Context, event *eventlistener.Event) bool {
+	return p.process(ctx, event, nil)
+}
+
+// Retry processes event from dead-letter queue
+func (p *EventProcessor) Retry(ctx context.Context, failed *models.FailedEvent) {
+	_ = p.process(ctx, failed.Event, failed)
+}
+
+// process handles live event if failed is nil, otherwise retries failed event
+func (p *EventProcessor) process(ctx context.Context, event *eventlistener.Event, failed *models.FailedEvent) bool {
 	gsRepo := p.repos.GameSession
 
 	bcSession, err := gsRepo.GetSessionByBlockChainID(ctx, event.RequestID)
 	if err != nil {
 		log.Debug().Msgf("Couldn't find session with requestID %v", event.RequestID)
-		return
+		p.resolveFailedEvent(ctx, failed)
+		return true
 	}
 
 	// already processed offset
 	if bcSession.LastOffset >= event.Offset {
 		log.Debug().Msgf("Skip already processed event for session: %d with offset: %d", bcSession.ID, event.Offset)
-		return
+		p.resolveFailedEvent(ctx, failed)
+		return true
 	}
 
-	handler, ok := handlersMap[event.EventType]
-	if !ok { // should never happen
-		log.Panic().Msgf("Got unknown event type: %d", event.EventType)
-		return
+	handler, ok := p.handlers.Handler(event.EventType)
+	if !ok {
+		log.Warn().Msgf("Got unknown event type: %d, session: %d", event.EventType, bcSession.ID)
 	}
+	name := handlerName(event.EventType, ok)
 
-	handleError := handler(ctx, p, event, bcSession)
-	if handleError != nil {
-		log.Error().Msgf("Failed to process event, %+v, reason: %s", event, handleError.Error())
-		return
+	// keep session events order, event is parked behind not resolved failed ones
+	if failed == nil {
+		blocked, err := p.eventsRepo.HasBlockingFailedEvents(ctx, bcSession.ID, event.Offset)
+		if err != nil {
+			return p.onEventFailed(ctx, name, bcSession, event, nil, err)
+		}
+		if blocked {
+			log.Info().Msgf("Park event behind failed ones, session: %d, offset: %d", bcSession.ID, event.Offset)
+			return p.addFailedEvent(ctx, bcSession.ID, event, "session has failed events before", 0)
+		}
 	}
 
-	err = gsRepo.UpdateSessionOffset(ctx, bcSession.ID, event.Offset)
+	start := time.Now()
+	uow, err := gsRepo.BeginUnitOfWork(ctx)
 	if err != nil {
-		log.Error().Msgf("Failed to update session offset, reason: %s", err.Error())
+		return p.onEventFailed(ctx, name, bcSession, event, failed, err)
+	}
+	unit := &EventUnit{UnitOfWork: uow, dryRun: p.dryRun}
+
+	err = handler(ctx, p, unit, event, bcSession)
+	if err == nil {
+		err = unit.UpdateSessionOffset(ctx, bcSession.ID, event.Offset)
+	}
+	if err == nil {
+		err = unit.Commit(ctx)
+	}
+	p.metrics.handlerDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		_ = unit.Rollback(ctx)
+		return p.onEventFailed(ctx, name, bcSession, event, failed, err)
+	}
+
+	for _, update := range unit.updates {
+		if err := notifySubscibers(ctx, p, bcSession, update); err != nil {
+			log.Error().Msgf("Failed to notify about session %d update, reason: %s", bcSession.ID, err.Error())
+		}
 	}
+
+	for _, fn := range unit.afterCommit {
+		if err := fn(ctx); err != nil {
+			log.Error().Msgf("Failed to complete event after commit, session: %d, offset: %d, reason: %s",
+				bcSession.ID, event.Offset, err.Error())
+			p.metrics.errors.WithLabelValues(name, errorCause(err)).Inc()
+		}
+	}
+
+	p.resolveFailedEvent(ctx, failed)
+	return true
+}
+
+// onEventFailed stores failed event to dead-letter queue or schedules the next retry,
+// returns false if live event isn't stored
+func (p *EventProcessor) onEventFailed(
+	ctx context.Context,
+	handler string,
+	session *models.GameSession,
+	event *eventlistener.Event,
+	failed *models.FailedEvent,
+	cause error,
+) bool {
+	log.Error().Msgf("Failed to process event, %+v, reason: %s", event, cause.Error())
+	p.metrics.errors.WithLabelValues(handler, errorCause(cause)).Inc()
+
+	if failed == nil {
+		return p.addFailedEvent(ctx, session.ID, event, cause.Error(), p.retryPolicy.backoff(1))
+	}
+
+	attempts := failed.Attempts + 1
+	status := models.FailedEventPending
+	if attempts >= p.retryPolicy.MaxAttempts {
+		log.Warn().Msgf("Event retry attempts are exhausted, session: %d, offset: %d", session.ID, event.Offset)
+		status = models.FailedEventExhausted
+	}
+	// failed event is claimed again after lease expiration if update is lost
+	err := p.eventsRepo.UpdateFailedEventRetry(ctx, failed.ID, status, p.retryPolicy.backoff(attempts), cause.Error())
+	if err != nil {
+		log.Error().Msgf("Failed to store failed event, %+v, reason: %s", event, err.Error())
+	}
+	return true
+}
+
+// addFailedEvent stores live event to dead-letter queue, it's retried until stored or ctx is done,
+// so shard is blocked and later session events aren't processed before it
+func (p *EventProcessor) addFailedEvent(
+	ctx context.Context,
+	sessionID uint64,
+	event *eventlistener.Event,
+	reason string,
+	delay time.Duration,
+) bool {
+	for attempt := 1; ; attempt++ {
+		err := p.eventsRepo.AddFailedEvent(ctx, sessionID, event, reason, delay)
+		if err == nil {
+			return true
+		}
+		log.Error().Msgf("Failed to store failed event, %+v, attempt: %d, reason: %s", event, attempt, err.Error())
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(p.retryPolicy.backoff(attempt)):
+		}
+	}
+}
+
+func (p *EventProcessor) resolveFailedEvent(ctx context.Context, failed *models.FailedEvent) {
+	if failed == nil {
+		return
+	}
+	if err := p.eventsRepo.UpdateFailedEventStatus(ctx, failed.ID, models.FailedEventResolved); err != nil {
+		log.Error().Msgf("Failed to resolve failed event %d, reason: %s", failed.ID, err.Error())
+	}
+}
+
+// RegisterHandler sets handler of the event type, it should be done before processing is started
+func (p *EventProcessor) RegisterHandler(eventType eventlistener.EventType, handler UpdateHandler) {
+	p.handlers.Register(eventType, handler)
 }
 
-func GetEventsToSubscribe() []eventlistener.EventType {
-	var events []eventlistener.EventType
-	for key := range handlersMap {
-		events = append(events, key)
+// EventsToSubscribe returns types of events with registered handlers and extra ones
+func (p *EventProcessor) EventsToSubscribe(extra []eventlistener.EventType) []eventlistener.EventType {
+	events := p.handlers.EventTypes()
+	for _, eventType := range extra {
+		if _, ok := p.handlers.Handler(eventType); !ok {
+			events = append(events, eventType)
+		}
 	}
 	return events
 }
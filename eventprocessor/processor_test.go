@@ -0,0 +1,219 @@
+package eventprocessor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/game_sessions/repository/localstorage"
+	"platform-backend/models"
+	"platform-backend/repositories"
+	"platform-backend/subscription"
+	"platform-backend/usecases"
+)
+
+// failingEventsRepo fails to add failed events the first failures times
+type failingEventsRepo struct {
+	Repository
+	failures int
+	calls    int
+}
+
+func (r *failingEventsRepo) AddFailedEvent(
+	ctx context.Context,
+	sesID uint64,
+	event *eventlistener.Event,
+	cause string,
+	delay time.Duration,
+) error {
+	r.calls++
+	if r.calls <= r.failures {
+		return errors.New("db is down")
+	}
+	return nil
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{
+		MinBackoff:  5 * time.Second,
+		MaxBackoff:  time.Minute,
+		MaxAttempts: 10,
+	}
+
+	assert.Equal(t, 5*time.Second, policy.backoff(1))
+	assert.Equal(t, 10*time.Second, policy.backoff(2))
+	assert.Equal(t, 40*time.Second, policy.backoff(4))
+	assert.Equal(t, time.Minute, policy.backoff(5))
+	assert.Equal(t, time.Minute, policy.backoff(100))
+}
+
+func TestAddFailedEventRetries(t *testing.T) {
+	repo := &failingEventsRepo{failures: 2}
+	p := &EventProcessor{
+		eventsRepo:  repo,
+		retryPolicy: &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	}
+
+	assert.True(t, p.addFailedEvent(context.Background(), 1, &eventlistener.Event{}, "cause", 0))
+	assert.Equal(t, 3, repo.calls)
+
+	// not stored event offset isn't completed
+	repo = &failingEventsRepo{failures: 100}
+	p.eventsRepo = repo
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, p.addFailedEvent(ctx, 1, &eventlistener.Event{}, "cause", 0))
+	assert.Equal(t, 1, repo.calls)
+}
+
+// noFailedEventsRepo has no failed events and stores new ones
+type noFailedEventsRepo struct {
+	Repository
+	added int
+}
+
+func (r *noFailedEventsRepo) HasBlockingFailedEvents(ctx context.Context, sesID uint64, offset uint64) (bool, error) {
+	return false, nil
+}
+
+func (r *noFailedEventsRepo) AddFailedEvent(
+	ctx context.Context,
+	sesID uint64,
+	event *eventlistener.Event,
+	cause string,
+	delay time.Duration,
+) error {
+	r.added++
+	return nil
+}
+
+func TestAfterCommitIsCalledOnlyOnCommit(t *testing.T) {
+	ctx := context.Background()
+	sessionsRepo := localstorage.NewGameSessionsLocalRepo()
+	require.NoError(t, sessionsRepo.AddGameSession(ctx, &models.GameSession{ID: 1, BlockchainSesID: 1}))
+	eventsRepo := &noFailedEventsRepo{}
+	p := &EventProcessor{
+		repos:       &repositories.Repos{GameSession: sessionsRepo},
+		eventsRepo:  eventsRepo,
+		retryPolicy: &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		handlers:    NewHandlersRegistry(onUnknownEvent),
+		metrics:     newProcessorMetrics(prometheus.NewRegistry()),
+	}
+
+	calls := 0
+	p.RegisterHandler(100, func(ctx context.Context, p *EventProcessor, unit *EventUnit,
+		event *eventlistener.Event, session *models.GameSession) error {
+		unit.AfterCommit(func(ctx context.Context) error {
+			calls++
+			return errors.New("push failed")
+		})
+		return nil
+	})
+	p.RegisterHandler(101, func(ctx context.Context, p *EventProcessor, unit *EventUnit,
+		event *eventlistener.Event, session *models.GameSession) error {
+		unit.AfterCommit(func(ctx context.Context) error {
+			calls++
+			return nil
+		})
+		return errors.New("handler failed")
+	})
+
+	// failed after commit call doesn't fail committed event
+	assert.True(t, p.Process(ctx, &eventlistener.Event{RequestID: 1, Offset: 1, EventType: 100}))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 0, eventsRepo.added)
+
+	assert.True(t, p.Process(ctx, &eventlistener.Event{RequestID: 1, Offset: 2, EventType: 101}))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, eventsRepo.added)
+}
+
+func TestDryRunStoresOnlySessionChanges(t *testing.T) {
+	ctx := context.Background()
+	sessionsRepo := localstorage.NewGameSessionsLocalRepo()
+	require.NoError(t, sessionsRepo.AddGameSession(ctx, &models.GameSession{ID: 1, BlockchainSesID: 1}))
+	p := &EventProcessor{
+		repos:       &repositories.Repos{GameSession: sessionsRepo},
+		eventsRepo:  &noFailedEventsRepo{},
+		retryPolicy: &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		handlers:    NewHandlersRegistry(onUnknownEvent),
+		metrics:     newProcessorMetrics(prometheus.NewRegistry()),
+	}
+	p.EnableDryRun()
+
+	calls := 0
+	p.RegisterHandler(100, func(ctx context.Context, p *EventProcessor, unit *EventUnit,
+		event *eventlistener.Event, session *models.GameSession) error {
+		unit.AfterCommit(func(ctx context.Context) error {
+			calls++
+			return nil
+		})
+		unit.Notify(&models.GameSessionUpdate{SessionID: session.ID})
+		if err := unit.AddCasinoWebhook(ctx, &models.CasinoWebhook{SessionID: session.ID}); err != nil {
+			return err
+		}
+		return unit.UpdateSessionState(ctx, session.ID, models.GameStartedInBC)
+	})
+
+	assert.True(t, p.Process(ctx, &eventlistener.Event{RequestID: 1, Offset: 1, EventType: 100}))
+	assert.Equal(t, 0, calls)
+
+	session, err := sessionsRepo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameStartedInBC, session.State)
+	assert.Equal(t, uint64(1), session.LastOffset)
+	webhooks, err := sessionsRepo.GetCasinoWebhooks(ctx, &gamesessions.CasinoWebhooksQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, webhooks)
+}
+
+// recordingSubscriptions records notified session updates
+type recordingSubscriptions struct {
+	subscription.UseCase
+	msgs []*models.GameSessionUpdateMsg
+}
+
+func (s *recordingSubscriptions) Notify(user string, reason string, payload interface{}) {
+	s.msgs = append(s.msgs, payload.([]*models.GameSessionUpdateMsg)...)
+}
+
+func TestSessionUpdatesNotifiedInOrder(t *testing.T) {
+	ctx := context.Background()
+	sessionsRepo := localstorage.NewGameSessionsLocalRepo()
+	require.NoError(t, sessionsRepo.AddGameSession(ctx, &models.GameSession{ID: 1, BlockchainSesID: 1}))
+	subs := &recordingSubscriptions{}
+	p := &EventProcessor{
+		repos:       &repositories.Repos{GameSession: sessionsRepo},
+		useCases:    &usecases.UseCases{Subscriptions: subs},
+		eventsRepo:  &noFailedEventsRepo{},
+		retryPolicy: &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		handlers:    NewHandlersRegistry(onUnknownEvent),
+		metrics:     newProcessorMetrics(prometheus.NewRegistry()),
+	}
+	p.RegisterHandler(100, func(ctx context.Context, p *EventProcessor, unit *EventUnit,
+		event *eventlistener.Event, session *models.GameSession) error {
+		offset := event.Offset
+		update := &models.GameSessionUpdate{SessionID: session.ID, Offset: &offset}
+		unit.Notify(update)
+		return unit.AddGameSessionUpdate(ctx, update)
+	})
+
+	for offset := uint64(1); offset <= 3; offset++ {
+		assert.True(t, p.Process(ctx, &eventlistener.Event{RequestID: 1, Offset: offset, EventType: 100}))
+	}
+
+	// every update is notified when Process returns, next one follows its previous offset
+	require.Len(t, subs.msgs, 3)
+	for i, msg := range subs.msgs {
+		assert.Equal(t, uint64(i+1), *msg.Offset)
+		if i > 0 {
+			assert.Equal(t, uint64(i), *msg.PrevOffset)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package eventprocessor
+
+import (
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"sort"
+	"sync"
+)
+
+// HandlersRegistry maps event types to handlers, events without registered handler go to fallback one
+type HandlersRegistry struct {
+	mu       sync.RWMutex
+	handlers map[eventlistener.EventType]UpdateHandler
+	fallback UpdateHandler
+}
+
+func NewHandlersRegistry(fallback UpdateHandler) *HandlersRegistry {
+	return &HandlersRegistry{
+		handlers: make(map[eventlistener.EventType]UpdateHandler),
+		fallback: fallback,
+	}
+}
+
+// Register sets handler of the event type, replaces previously registered one
+func (r *HandlersRegistry) Register(eventType eventlistener.EventType, handler UpdateHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[eventType] = handler
+}
+
+// Handler returns handler of the event type and false if it's the fallback one
+func (r *HandlersRegistry) Handler(eventType eventlistener.EventType) (UpdateHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if handler, ok := r.handlers[eventType]; ok {
+		return handler, true
+	}
+	return r.fallback, false
+}
+
+// EventTypes returns sorted types of registered handlers
+func (r *HandlersRegistry) EventTypes() []eventlistener.EventType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]eventlistener.EventType, 0, len(r.handlers))
+	for eventType := range r.handlers {
+		types = append(types, eventType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
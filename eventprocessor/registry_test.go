@@ -0,0 +1,35 @@
+package eventprocessor
+
+import (
+	"context"
+	"testing"
+
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/stretchr/testify/assert"
+	"platform-backend/models"
+)
+
+func TestHandlersRegistry(t *testing.T) {
+	var called string
+	handler := func(name string) UpdateHandler {
+		return func(context.Context, *EventProcessor, *EventUnit, *eventlistener.Event, *models.GameSession) error {
+			called = name
+			return nil
+		}
+	}
+
+	registry := NewHandlersRegistry(handler("fallback"))
+	registry.Register(7, handler("seven"))
+	registry.Register(2, handler("two"))
+	assert.Equal(t, []eventlistener.EventType{2, 7}, registry.EventTypes())
+
+	h, ok := registry.Handler(7)
+	assert.True(t, ok)
+	assert.NoError(t, h(context.Background(), nil, nil, nil, nil))
+	assert.Equal(t, "seven", called)
+
+	h, ok = registry.Handler(3)
+	assert.False(t, ok)
+	assert.NoError(t, h(context.Background(), nil, nil, nil, nil))
+	assert.Equal(t, "fallback", called)
+}
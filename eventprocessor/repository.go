@@ -0,0 +1,29 @@
+package eventprocessor
+
+import (
+	"context"
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"platform-backend/models"
+	"time"
+)
+
+type Repository interface {
+	// GetLastOffset returns last fully processed offset of events source, nil if nothing was processed yet
+	GetLastOffset(ctx context.Context, source string) (*uint64, error)
+	// SetLastOffset stores offset of events source, stored offset is never decreased
+	SetLastOffset(ctx context.Context, source string, offset uint64) error
+
+	// dead-letter queue
+	AddFailedEvent(ctx context.Context, sesID uint64, event *eventlistener.Event, cause string, delay time.Duration) error
+	// HasBlockingFailedEvents checks for not resolved failed events of the session before offset
+	HasBlockingFailedEvents(ctx context.Context, sesID uint64, offset uint64) (bool, error)
+	// ClaimFailedEvents returns due events which are the first not resolved ones of their sessions,
+	// claimed events aren't returned again until lease expires
+	ClaimFailedEvents(ctx context.Context, limit int, lease time.Duration) ([]*models.FailedEvent, error)
+	UpdateFailedEventRetry(ctx context.Context, id uint64, status models.FailedEventStatus, delay time.Duration, cause string) error
+	UpdateFailedEventStatus(ctx context.Context, id uint64, status models.FailedEventStatus) error
+	// GetFailedEvents returns last failed events, all statuses if status is nil
+	GetFailedEvents(ctx context.Context, status *models.FailedEventStatus, limit int) ([]*models.FailedEvent, error)
+	RequeueFailedEvent(ctx context.Context, id uint64) error
+	DiscardFailedEvent(ctx context.Context, id uint64) error
+}
@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/jackc/pgx/v4"
+	"platform-backend/eventprocessor"
+	"platform-backend/models"
+	"time"
+)
+
+const (
+	failedEventColumns = `id, ses_id, "offset", event, error, status, attempts, next_attempt, created`
+
+	insertFailedEventStmt = `
+        INSERT INTO failed_events
+            (ses_id, "offset", event, error, next_attempt)
+        VALUES
+            ($1, $2, $3, $4, now() + $5 * INTERVAL '1 millisecond')
+        ON CONFLICT ("offset") DO NOTHING`
+	selectBlockingFailedEventsCntStmt = `
+        SELECT count(*) FROM failed_events
+        WHERE ses_id = $1 AND "offset" < $2 AND status IN (0, 1)`
+	// only the first not resolved event of a session can be retried
+	claimFailedEventsStmt = `
+        WITH heads AS (
+            SELECT DISTINCT ON (ses_id) id, status, next_attempt FROM failed_events
+            WHERE status IN (0, 1)
+            ORDER BY ses_id, "offset"
+        )
+        UPDATE failed_events SET next_attempt = now() + $2 * INTERVAL '1 millisecond'
+        WHERE id IN (
+            SELECT id FROM heads WHERE status = 0 AND next_attempt <= now() LIMIT $1
+        )
+        RETURNING ` + failedEventColumns
+	updateFailedEventRetryStmt = `
+        UPDATE failed_events
+        SET attempts = attempts + 1, status = $2, next_attempt = now() + $3 * INTERVAL '1 millisecond', error = $4
+        WHERE id = $1`
+	updateFailedEventStatusStmt = "UPDATE failed_events SET status = $2 WHERE id = $1"
+	selectFailedEventsStmt      = "SELECT " + failedEventColumns + " FROM failed_events WHERE ($1::SMALLINT IS NULL OR status = $1) ORDER BY id DESC LIMIT $2"
+	requeueFailedEventStmt      = "UPDATE failed_events SET status = 0, attempts = 0, next_attempt = now() WHERE id = $1 AND status <> 2"
+	discardFailedEventStmt      = "UPDATE failed_events SET status = 3 WHERE id = $1 AND status IN (0, 1)"
+)
+
+type FailedEvent struct {
+	ID          uint64    `db:"id"`
+	SessionID   uint64    `db:"ses_id"`
+	Offset      uint64    `db:"offset"`
+	Event       []byte    `db:"event"`
+	Error       string    `db:"error"`
+	Status      uint16    `db:"status"`
+	Attempts    int       `db:"attempts"`
+	NextAttempt time.Time `db:"next_attempt"`
+	Created     time.Time `db:"created"`
+}
+
+func (e *FailedEvent) Scan(row pgx.Row) error {
+	return row.Scan(
+		&e.ID,
+		&e.SessionID,
+		&e.Offset,
+		&e.Event,
+		&e.Error,
+		&e.Status,
+		&e.Attempts,
+		&e.NextAttempt,
+		&e.Created,
+	)
+}
+
+func (r *EventsPostgresRepo) AddFailedEvent(
+	ctx context.Context,
+	sesID uint64,
+	event *eventlistener.Event,
+	cause string,
+	delay time.Duration,
+) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, insertFailedEventStmt, sesID, event.Offset, data, cause, delay.Milliseconds())
+	return err
+}
+
+func (r *EventsPostgresRepo) HasBlockingFailedEvents(ctx context.Context, sesID uint64, offset uint64) (bool, error) {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var cnt uint
+	err = conn.QueryRow(ctx, selectBlockingFailedEventsCntStmt, sesID, offset).Scan(&cnt)
+	if err != nil {
+		return false, err
+	}
+
+	return cnt > 0, nil
+}
+
+func (r *EventsPostgresRepo) ClaimFailedEvents(ctx context.Context, limit int, lease time.Duration) ([]*models.FailedEvent, error) {
+	return r.selectFailedEvents(ctx, claimFailedEventsStmt, limit, lease.Milliseconds())
+}
+
+func (r *EventsPostgresRepo) UpdateFailedEventRetry(
+	ctx context.Context,
+	id uint64,
+	status models.FailedEventStatus,
+	delay time.Duration,
+	cause string,
+) error {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, updateFailedEventRetryStmt, id, uint16(status), delay.Milliseconds(), cause)
+	return err
+}
+
+func (r *EventsPostgresRepo) UpdateFailedEventStatus(ctx context.Context, id uint64, status models.FailedEventStatus) error {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, updateFailedEventStatusStmt, id, uint16(status))
+	return err
+}
+
+func (r *EventsPostgresRepo) GetFailedEvents(
+	ctx context.Context,
+	status *models.FailedEventStatus,
+	limit int,
+) ([]*models.FailedEvent, error) {
+	var dbStatus *uint16
+	if status != nil {
+		s := uint16(*status)
+		dbStatus = &s
+	}
+	return r.selectFailedEvents(ctx, selectFailedEventsStmt, dbStatus, limit)
+}
+
+func (r *EventsPostgresRepo) RequeueFailedEvent(ctx context.Context, id uint64) error {
+	return r.execFailedEventCmd(ctx, requeueFailedEventStmt, id)
+}
+
+func (r *EventsPostgresRepo) DiscardFailedEvent(ctx context.Context, id uint64) error {
+	return r.execFailedEventCmd(ctx, discardFailedEventStmt, id)
+}
+
+func (r *EventsPostgresRepo) execFailedEventCmd(ctx context.Context, stmt string, id uint64) error {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return eventprocessor.ErrFailedEventNotFound
+	}
+	return nil
+}
+
+func (r *EventsPostgresRepo) selectFailedEvents(ctx context.Context, stmt string, args ...interface{}) ([]*models.FailedEvent, error) {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*models.FailedEvent, 0)
+	for rows.Next() {
+		dbEvent := new(FailedEvent)
+		if err = dbEvent.Scan(rows); err != nil {
+			return nil, err
+		}
+		event, err := toModelFailedEvent(dbEvent)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func toModelFailedEvent(e *FailedEvent) (*models.FailedEvent, error) {
+	event := new(eventlistener.Event)
+	if err := json.Unmarshal(e.Event, event); err != nil {
+		return nil, err
+	}
+	return &models.FailedEvent{
+		ID:          e.ID,
+		SessionID:   e.SessionID,
+		Offset:      e.Offset,
+		Event:       event,
+		Error:       e.Error,
+		Status:      models.FailedEventStatus(e.Status),
+		Attempts:    e.Attempts,
+		NextAttempt: e.NextAttempt,
+		Created:     e.Created,
+	}, nil
+}
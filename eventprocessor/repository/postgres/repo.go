@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	selectLastOffsetStmt = `SELECT "offset" FROM events_offset WHERE source = $1`
+	upsertLastOffsetStmt = `
+        INSERT INTO events_offset (source, "offset")
+        VALUES ($1, $2)
+        ON CONFLICT (source) DO UPDATE SET "offset" = GREATEST(events_offset."offset", EXCLUDED."offset")`
+)
+
+type EventsPostgresRepo struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewEventsPostgresRepo(dbPool *pgxpool.Pool) *EventsPostgresRepo {
+	return &EventsPostgresRepo{dbPool: dbPool}
+}
+
+func (r *EventsPostgresRepo) GetLastOffset(ctx context.Context, source string) (*uint64, error) {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var offset uint64
+	err = conn.QueryRow(ctx, selectLastOffsetStmt, source).Scan(&offset)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &offset, nil
+}
+
+func (r *EventsPostgresRepo) SetLastOffset(ctx context.Context, source string, offset uint64) error {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, upsertLastOffsetStmt, source, offset)
+	return err
+}
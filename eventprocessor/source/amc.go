@@ -0,0 +1,70 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/rs/zerolog/log"
+	"platform-backend/config"
+	"time"
+)
+
+// AmcSource receives events from the action monitor
+type AmcSource struct {
+	config     *config.AmcConfig
+	eventTypes []eventlistener.EventType
+	debug      bool
+}
+
+func NewAmcSource(config *config.AmcConfig, eventTypes []eventlistener.EventType, debug bool) *AmcSource {
+	return &AmcSource{
+		config:     config,
+		eventTypes: eventTypes,
+		debug:      debug,
+	}
+}
+
+func (s *AmcSource) Run(ctx context.Context, offset uint64, events chan<- *eventlistener.Event) error {
+	messages := make(chan *eventlistener.EventMessage)
+	listener := eventlistener.NewEventListener(s.config.Url, messages)
+	// setup reconnection options
+	listener.ReconnectionAttempts = s.config.ReconnectionAttempts
+	listener.ReconnectionDelay = time.Duration(s.config.ReconnectionDelay)
+
+	log.Info().Msgf("Connecting to the action monitor on %s", s.config.Url)
+
+	if s.debug {
+		eventlistener.EnableDebugLogging()
+	}
+
+	// set auth token
+	listener.SetToken(s.config.Token)
+
+	go listener.Run(ctx)
+
+	log.Info().Msgf("Subscribing to events from offset %d", offset)
+	if ok, err := listener.BatchSubscribe(s.eventTypes, offset); err != nil || !ok {
+		return fmt.Errorf("action monitor subscribe to events, error: %v", err)
+	}
+
+	log.Info().Msgf("Subscribed to all events!")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msgf("Action monitor client is stopped")
+			return nil
+		case eventMessage, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			for _, event := range eventMessage.Events {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/rs/zerolog/log"
+	"os"
+	"time"
+)
+
+// max size of single recorded event line
+const maxRecordSize = 1024 * 1024
+
+// recordedEvent is a line of NDJSON record, plain events without record time are accepted too
+type recordedEvent struct {
+	eventlistener.Event
+	RecordedAt *time.Time `json:"recorded_at,omitempty"`
+}
+
+// FileSource replays events recorded to NDJSON file
+type FileSource struct {
+	path string
+	// 1 replays in real time, 2 twice faster, 0 or less without delays
+	speed float64
+}
+
+func NewFileSource(path string, speed float64) *FileSource {
+	return &FileSource{
+		path:  path,
+		speed: speed,
+	}
+}
+
+func (s *FileSource) Run(ctx context.Context, offset uint64, events chan<- *eventlistener.Event) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	log.Info().Msgf("Replaying events from %s since offset %d with speed %v", s.path, offset, s.speed)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+
+	var (
+		prevRecordedAt *time.Time
+		line           int
+		replayed       int
+	)
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		record := new(recordedEvent)
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			log.Warn().Msgf("Skip malformed record at %s:%d, %s", s.path, line, err.Error())
+			continue
+		}
+		if record.Offset < offset {
+			continue
+		}
+
+		if delay := s.delay(prevRecordedAt, record.RecordedAt); delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		prevRecordedAt = record.RecordedAt
+
+		event := record.Event
+		select {
+		case events <- &event:
+			replayed++
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// keep app running, so replayed sessions can be inspected
+	log.Info().Msgf("Replay is finished, %d events replayed", replayed)
+	<-ctx.Done()
+	return nil
+}
+
+func (s *FileSource) delay(prev *time.Time, next *time.Time) time.Duration {
+	if s.speed <= 0 || prev == nil || next == nil {
+		return 0
+	}
+	return time.Duration(float64(next.Sub(*prev)) / s.speed)
+}
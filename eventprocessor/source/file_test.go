@@ -0,0 +1,61 @@
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"platform-backend/eventprocessor"
+	"testing"
+
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sliceSource struct {
+	events []*eventlistener.Event
+}
+
+func (s *sliceSource) Run(ctx context.Context, offset uint64, events chan<- *eventlistener.Event) error {
+	for _, event := range s.events {
+		events <- event
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func collect(t *testing.T, source eventprocessor.EventSource, offset uint64, count int) []*eventlistener.Event {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan *eventlistener.Event)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- source.Run(ctx, offset, events)
+	}()
+
+	received := make([]*eventlistener.Event, 0, count)
+	for len(received) < count {
+		received = append(received, <-events)
+	}
+	cancel()
+	require.NoError(t, <-errs)
+	return received
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.ndjson")
+
+	recorded := []*eventlistener.Event{
+		{Offset: 1, RequestID: 10, EventType: 0, Data: []byte(`{"a":1}`)},
+		{Offset: 2, RequestID: 11, EventType: 1, Data: []byte(`null`)},
+		{Offset: 3, RequestID: 10, EventType: 4, Data: []byte(`{"b":"c"}`)},
+	}
+	received := collect(t, NewRecordingSource(&sliceSource{events: recorded}, path), 0, len(recorded))
+	assert.Equal(t, recorded, received)
+
+	replayed := collect(t, NewFileSource(path, 0), 2, 2)
+	assert.Equal(t, recorded[1:], replayed)
+}
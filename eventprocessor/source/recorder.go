@@ -0,0 +1,60 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/rs/zerolog/log"
+	"os"
+	"platform-backend/eventprocessor"
+	"time"
+)
+
+// RecordingSource writes events of wrapped source to NDJSON file, the file can be replayed by FileSource
+type RecordingSource struct {
+	source eventprocessor.EventSource
+	path   string
+}
+
+func NewRecordingSource(source eventprocessor.EventSource, path string) *RecordingSource {
+	return &RecordingSource{
+		source: source,
+		path:   path,
+	}
+}
+
+func (s *RecordingSource) Run(ctx context.Context, offset uint64, events chan<- *eventlistener.Event) error {
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	log.Info().Msgf("Recording events to %s", s.path)
+
+	received := make(chan *eventlistener.Event)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.source.Run(ctx, offset, received)
+	}()
+
+	encoder := json.NewEncoder(file)
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case event := <-received:
+			now := time.Now()
+			// recording errors must not stop events processing
+			if err := encoder.Encode(&recordedEvent{Event: *event, RecordedAt: &now}); err != nil {
+				log.Error().Msgf("Failed to record event with offset %d, %s", event.Offset, err.Error())
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return <-errs
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package gamesessions
+
+import (
+	"context"
+	"github.com/eoscanada/eos-go"
+	"platform-backend/contracts"
+	"platform-backend/models"
+)
+
+// AddCasinoWebhook queues session event for casino if it has webhook url,
+// webhook is committed together with session changes of uow
+func AddCasinoWebhook(
+	ctx context.Context,
+	uow UnitOfWork,
+	contractsRepo contracts.Repository,
+	event models.CasinoWebhookEvent,
+	session *models.GameSession,
+	playerWin *eos.Asset,
+	details string,
+) error {
+	casino, err := contractsRepo.GetCasino(ctx, session.CasinoID)
+	if err != nil {
+		return err
+	}
+	if casino.Meta == nil || casino.Meta.WebhookURL == "" {
+		return nil
+	}
+
+	webhook, err := models.NewCasinoWebhook(event, session, playerWin, details)
+	if err != nil {
+		return err
+	}
+	return uow.AddCasinoWebhook(ctx, webhook)
+}
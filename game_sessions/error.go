@@ -3,9 +3,16 @@ package gamesessions
 import "errors"
 
 var (
-	ErrGameSessionNotFound = errors.New("game session not found")
-	ErrFirstGameActionNotFound = errors.New("first game action not found")
-	ErrCasinoMetaEmpty = errors.New("casino meta is empty")
-	ErrCasinoUrlNotDefined = errors.New("casino api url not defined")
-	ErrUpdateAlreadyProcessed = errors.New("session update already processed")
+	ErrGameSessionNotFound      = errors.New("game session not found")
+	ErrGameSessionAlreadyExists = errors.New("game session already exists")
+	ErrSessionIDAllocation      = errors.New("failed to allocate unique session id")
+	ErrFirstGameActionNotFound  = errors.New("first game action not found")
+	ErrCasinoMetaEmpty          = errors.New("casino meta is empty")
+	ErrCasinoUrlNotDefined      = errors.New("casino api url not defined")
+	ErrUpdateAlreadyProcessed   = errors.New("session update already processed")
+	ErrIllegalStateTransition   = errors.New("illegal session state transition")
+	ErrSessionVersionConflict   = errors.New("session was changed concurrently")
+	ErrConcurrentGameAction     = errors.New("another game action is already in progress")
+	ErrSessionTrxDropped        = errors.New("session transaction was dropped by blockchain")
+	ErrCasinoWebhookNotFound    = errors.New("casino webhook not found")
 )
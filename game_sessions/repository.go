@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/eoscanada/eos-go"
 	"platform-backend/models"
+	"time"
 )
 
 type FilterType string
@@ -14,18 +15,135 @@ const (
 	Losts FilterType = "losts"
 )
 
+const (
+	DefaultSessionsLimit = 30
+	MaxSessionsLimit     = 100
+)
+
+// SessionsCursor points to the last session of the previous page
+type SessionsCursor struct {
+	LastUpdate int64
+	ID         uint64
+}
+
+// SessionsQuery describes sessions page, nil fields are not filtered
+type SessionsQuery struct {
+	Cursor   *SessionsCursor
+	Limit    int
+	GameID   *uint64
+	CasinoID *uint64
+	State    *models.GameSessionState
+	// last update bounds (inclusive), unix time
+	From *int64
+	To   *int64
+}
+
+func (q *SessionsQuery) PageLimit() int {
+	if q == nil || q.Limit <= 0 {
+		return DefaultSessionsLimit
+	}
+	if q.Limit > MaxSessionsLimit {
+		return MaxSessionsLimit
+	}
+	return q.Limit
+}
+
+// Match checks session against query filters and cursor
+func (q *SessionsQuery) Match(ses *models.GameSession) bool {
+	if q == nil {
+		return true
+	}
+	if q.Cursor != nil {
+		if ses.LastUpdate > q.Cursor.LastUpdate ||
+			ses.LastUpdate == q.Cursor.LastUpdate && ses.ID >= q.Cursor.ID {
+			return false
+		}
+	}
+	if q.GameID != nil && ses.GameID != *q.GameID {
+		return false
+	}
+	if q.CasinoID != nil && ses.CasinoID != *q.CasinoID {
+		return false
+	}
+	if q.State != nil && ses.State != *q.State {
+		return false
+	}
+	if q.From != nil && ses.LastUpdate < *q.From {
+		return false
+	}
+	if q.To != nil && ses.LastUpdate > *q.To {
+		return false
+	}
+	return true
+}
+
+const (
+	DefaultCasinoWebhooksLimit = 100
+	MaxCasinoWebhooksLimit     = 1000
+)
+
+// CasinoWebhooksQuery filters queued webhooks, nil fields are not filtered
+type CasinoWebhooksQuery struct {
+	CasinoID  *uint64
+	SessionID *uint64
+	Status    *models.CasinoWebhookStatus
+	Limit     int
+}
+
+func (q *CasinoWebhooksQuery) PageLimit() int {
+	if q.Limit <= 0 {
+		return DefaultCasinoWebhooksLimit
+	}
+	if q.Limit > MaxCasinoWebhooksLimit {
+		return MaxCasinoWebhooksLimit
+	}
+	return q.Limit
+}
+
+// UnitOfWork groups session writes, they are applied atomically on Commit
+type UnitOfWork interface {
+	AddGameSessionUpdate(ctx context.Context, upd *models.GameSessionUpdate) error
+	UpdateSessionState(ctx context.Context, id uint64, state models.GameSessionState) error
+	// returns ErrSessionVersionConflict if session is changed since version was read
+	CompareAndSwapSessionState(ctx context.Context, id uint64, version uint64, state models.GameSessionState) error
+	UpdateSessionStateBeforeFail(ctx context.Context, id uint64, prevState models.GameSessionState) error
+	UpdateSessionPlayerWin(ctx context.Context, id uint64, playerWin string) error
+	UpdateSessionOffset(ctx context.Context, id uint64, offset uint64) error
+	DeleteFirstGameAction(ctx context.Context, sesID uint64) error
+	AddGameSession(ctx context.Context, ses *models.GameSession) error
+	// adds deposit to the current one, session is locked until commit, so concurrent deposits aren't lost
+	AddSessionDeposit(ctx context.Context, id uint64, deposit eos.Asset) error
+	AddGameSessionTransaction(ctx context.Context, trxID string, sesID uint64,
+		actionType uint16, actionParams []uint64) error
+	// outbox transaction is sent only if session changes are committed
+	AddCasinoTrx(ctx context.Context, trx *models.CasinoTrx) error
+	UpdateCasinoTrxSent(ctx context.Context, id uint64, trxID string) error
+	// webhook is delivered only if session changes are committed
+	AddCasinoWebhook(ctx context.Context, webhook *models.CasinoWebhook) error
+
+	Commit(ctx context.Context) error
+	// Rollback discards writes, does nothing after Commit
+	Rollback(ctx context.Context) error
+}
+
 type Repository interface {
+	BeginUnitOfWork(ctx context.Context) (UnitOfWork, error)
+
 	HasGameSession(ctx context.Context, id uint64) (bool, error)
 	GetGameSession(ctx context.Context, id uint64) (*models.GameSession, error)
-	GetGlobalSessions(ctx context.Context, filter FilterType) ([]*models.GameSession, error)
+	// returns finished sessions only, query state must be nil
+	GetGlobalSessions(ctx context.Context, filter FilterType, query *SessionsQuery) ([]*models.GameSession, error)
+	GetSessionsByStates(ctx context.Context, states []models.GameSessionState) ([]*models.GameSession, error)
 	GetSessionByBlockChainID(ctx context.Context, bcID uint64) (*models.GameSession, error)
 	UpdateSessionState(ctx context.Context, id uint64, state models.GameSessionState) error
+	// changes state only if session version is still equal to given one
+	CompareAndSwapSessionState(ctx context.Context, id uint64, version uint64, state models.GameSessionState) error
 	UpdateSessionStateBeforeFail(ctx context.Context, id uint64, prevState models.GameSessionState) error
 	UpdateSessionOffset(ctx context.Context, id uint64, offset uint64) error
 	UpdateSessionPlayerWin(ctx context.Context, id uint64, playerWin string) error
 	UpdateSessionDeposit(ctx context.Context, id uint64, deposit string) error
 	AddGameSession(ctx context.Context, ses *models.GameSession) error
-	GetUserGameSessions(ctx context.Context, accountName string) ([]*models.GameSession, error)
+	GetUserGameSessions(ctx context.Context, accountName string, query *SessionsQuery) ([]*models.GameSession, error)
 	GetAllGameSessions(ctx context.Context) ([]*models.GameSession, error)
 	DeleteGameSession(ctx context.Context, id uint64) error
 
@@ -34,10 +152,46 @@ type Repository interface {
 	DeleteFirstGameAction(ctx context.Context, sesID uint64) error
 
 	GetGameSessionUpdates(ctx context.Context, id uint64) ([]*models.GameSessionUpdate, error)
+	// returns updates appended after the update with given action monitor offset
+	GetGameSessionUpdatesSince(ctx context.Context, id uint64, offset uint64) ([]*models.GameSessionUpdate, error)
+	// returns max offset of session updates not greater than maxOffset (any if nil), nil if no such updates
+	GetLastGameSessionUpdateOffset(ctx context.Context, id uint64, maxOffset *uint64) (*uint64, error)
 	AddGameSessionUpdate(ctx context.Context, upd *models.GameSessionUpdate) error
 	DeleteGameSessionUpdates(ctx context.Context, sesId uint64) error
-	GetCasinoSessions(ctx context.Context, filter FilterType, casinoId eos.Uint64) ([]*models.GameSession, error)
+	// returns finished sessions only, query state must be nil
+	GetCasinoSessions(ctx context.Context, filter FilterType, casinoId eos.Uint64, query *SessionsQuery) ([]*models.GameSession, error)
 
 	AddGameSessionTransaction(ctx context.Context, trxID string, sesID uint64,
 		actionType uint16, actionParams []uint64) error
+	CountGameSessionTransactions(ctx context.Context, sesID uint64) (int, error)
+	GetGameSessionTransactions(ctx context.Context, sesID uint64) ([]*models.GameSessionTrx, error)
+	// returns transactions which are not irreversible or dropped yet and due to be checked
+	GetPendingGameSessionTransactions(ctx context.Context, limit int) ([]*models.GameSessionTrx, error)
+	UpdateGameSessionTransactionStatus(ctx context.Context, trxID string,
+		status models.GameSessionTrxStatus, blockNum *uint32) error
+	// postpones the next status check of transaction and counts the check
+	PostponeGameSessionTransactionCheck(ctx context.Context, trxID string, delay time.Duration) error
+
+	// casino signed transactions outbox
+	AddCasinoTrx(ctx context.Context, trx *models.CasinoTrx) error
+	// claims pending transactions ready to be sent, claimed ones aren't returned again until lease expires
+	ClaimCasinoTrxs(ctx context.Context, limit int, lease time.Duration) ([]*models.CasinoTrx, error)
+	HasPendingCasinoTrx(ctx context.Context, sesID uint64) (bool, error)
+	// stores transaction signed by attempt before it's sent
+	UpdateCasinoTrxSigned(ctx context.Context, id uint64, trxID string, signedTrx []byte) error
+	UpdateCasinoTrxSent(ctx context.Context, id uint64, trxID string) error
+	UpdateCasinoTrxRetry(ctx context.Context, id uint64, delay time.Duration, lastErr string) error
+	UpdateCasinoTrxFailed(ctx context.Context, id uint64, lastErr string) error
+
+	// casino webhooks queue
+	AddCasinoWebhook(ctx context.Context, webhook *models.CasinoWebhook) error
+	// claims pending webhooks ready to be delivered, claimed ones aren't returned again until lease expires
+	ClaimCasinoWebhooks(ctx context.Context, limit int, lease time.Duration) ([]*models.CasinoWebhook, error)
+	UpdateCasinoWebhookDelivered(ctx context.Context, id uint64) error
+	UpdateCasinoWebhookRetry(ctx context.Context, id uint64, delay time.Duration, lastErr string) error
+	UpdateCasinoWebhookFailed(ctx context.Context, id uint64, lastErr string) error
+	// returns ErrCasinoWebhookNotFound if there is no such webhook
+	RequeueCasinoWebhook(ctx context.Context, id uint64) error
+	// returns the newest webhooks first
+	GetCasinoWebhooks(ctx context.Context, query *CasinoWebhooksQuery) ([]*models.CasinoWebhook, error)
 }
@@ -0,0 +1,86 @@
+package localstorage
+
+import (
+	"context"
+	"platform-backend/models"
+	"time"
+)
+
+type CasinoTrx struct {
+	*models.CasinoTrx
+	NextAttempt time.Time
+	LastError   string
+}
+
+func (r *GameSessionsLocalRepo) AddCasinoTrx(_ context.Context, trx *models.CasinoTrx) error {
+	trx.ID = uint64(len(r.casinoTrxs) + 1)
+	r.casinoTrxs = append(r.casinoTrxs, &CasinoTrx{CasinoTrx: trx, NextAttempt: time.Now()})
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) ClaimCasinoTrxs(_ context.Context, limit int, lease time.Duration) ([]*models.CasinoTrx, error) {
+	now := time.Now()
+	claimed := make([]*models.CasinoTrx, 0)
+	for _, trx := range r.casinoTrxs {
+		if len(claimed) == limit {
+			break
+		}
+		if trx.Status != models.CasinoTrxPending || trx.NextAttempt.After(now) {
+			continue
+		}
+		trx.Attempts++
+		trx.NextAttempt = now.Add(lease)
+		copied := *trx.CasinoTrx
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *GameSessionsLocalRepo) HasPendingCasinoTrx(_ context.Context, sesID uint64) (bool, error) {
+	for _, trx := range r.casinoTrxs {
+		if trx.SessionID == sesID && trx.Status == models.CasinoTrxPending {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateCasinoTrxSigned(_ context.Context, id uint64, trxID string, signedTrx []byte) error {
+	if trx := r.getCasinoTrx(id); trx != nil {
+		trx.TrxID = trxID
+		trx.SignedTrx = signedTrx
+	}
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateCasinoTrxSent(_ context.Context, id uint64, trxID string) error {
+	if trx := r.getCasinoTrx(id); trx != nil {
+		trx.Status = models.CasinoTrxSent
+		trx.TrxID = trxID
+		trx.LastError = ""
+	}
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateCasinoTrxRetry(_ context.Context, id uint64, delay time.Duration, lastErr string) error {
+	if trx := r.getCasinoTrx(id); trx != nil {
+		trx.NextAttempt = time.Now().Add(delay)
+		trx.LastError = lastErr
+	}
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateCasinoTrxFailed(_ context.Context, id uint64, lastErr string) error {
+	if trx := r.getCasinoTrx(id); trx != nil {
+		trx.Status = models.CasinoTrxFailed
+		trx.LastError = lastErr
+	}
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) getCasinoTrx(id uint64) *CasinoTrx {
+	if id == 0 || id > uint64(len(r.casinoTrxs)) {
+		return nil
+	}
+	return r.casinoTrxs[id-1]
+}
@@ -0,0 +1,125 @@
+package localstorage
+
+import (
+	"context"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"time"
+)
+
+func (r *GameSessionsLocalRepo) AddCasinoWebhook(_ context.Context, webhook *models.CasinoWebhook) error {
+	r.webhooksMutex.Lock()
+	defer r.webhooksMutex.Unlock()
+
+	webhook.ID = uint64(len(r.casinoWebhooks) + 1)
+	webhook.NextAttempt = time.Now()
+	webhook.Created = webhook.NextAttempt
+	copied := *webhook
+	r.casinoWebhooks = append(r.casinoWebhooks, &copied)
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) ClaimCasinoWebhooks(
+	_ context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*models.CasinoWebhook, error) {
+	r.webhooksMutex.Lock()
+	defer r.webhooksMutex.Unlock()
+
+	now := time.Now()
+	claimed := make([]*models.CasinoWebhook, 0)
+	for _, webhook := range r.casinoWebhooks {
+		if len(claimed) == limit {
+			break
+		}
+		if webhook.Status != models.CasinoWebhookPending || webhook.NextAttempt.After(now) {
+			continue
+		}
+		webhook.Attempts++
+		webhook.NextAttempt = now.Add(lease)
+		copied := *webhook
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateCasinoWebhookDelivered(_ context.Context, id uint64) error {
+	r.webhooksMutex.Lock()
+	defer r.webhooksMutex.Unlock()
+
+	if webhook := r.getCasinoWebhook(id); webhook != nil {
+		webhook.Status = models.CasinoWebhookDelivered
+		webhook.LastError = ""
+	}
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateCasinoWebhookRetry(
+	_ context.Context,
+	id uint64,
+	delay time.Duration,
+	lastErr string,
+) error {
+	r.webhooksMutex.Lock()
+	defer r.webhooksMutex.Unlock()
+
+	if webhook := r.getCasinoWebhook(id); webhook != nil {
+		webhook.NextAttempt = time.Now().Add(delay)
+		webhook.LastError = lastErr
+	}
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateCasinoWebhookFailed(_ context.Context, id uint64, lastErr string) error {
+	r.webhooksMutex.Lock()
+	defer r.webhooksMutex.Unlock()
+
+	if webhook := r.getCasinoWebhook(id); webhook != nil {
+		webhook.Status = models.CasinoWebhookFailed
+		webhook.LastError = lastErr
+	}
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) RequeueCasinoWebhook(_ context.Context, id uint64) error {
+	r.webhooksMutex.Lock()
+	defer r.webhooksMutex.Unlock()
+
+	webhook := r.getCasinoWebhook(id)
+	if webhook == nil {
+		return gamesessions.ErrCasinoWebhookNotFound
+	}
+	webhook.Status = models.CasinoWebhookPending
+	webhook.Attempts = 0
+	webhook.NextAttempt = time.Now()
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) GetCasinoWebhooks(
+	_ context.Context,
+	query *gamesessions.CasinoWebhooksQuery,
+) ([]*models.CasinoWebhook, error) {
+	r.webhooksMutex.Lock()
+	defer r.webhooksMutex.Unlock()
+
+	webhooks := make([]*models.CasinoWebhook, 0)
+	for i := len(r.casinoWebhooks) - 1; i >= 0 && len(webhooks) < query.PageLimit(); i-- {
+		webhook := r.casinoWebhooks[i]
+		if query.CasinoID != nil && webhook.CasinoID != *query.CasinoID ||
+			query.SessionID != nil && webhook.SessionID != *query.SessionID ||
+			query.Status != nil && webhook.Status != *query.Status {
+			continue
+		}
+		copied := *webhook
+		webhooks = append(webhooks, &copied)
+	}
+	return webhooks, nil
+}
+
+func (r *GameSessionsLocalRepo) getCasinoWebhook(id uint64) *models.CasinoWebhook {
+	if id == 0 || id > uint64(len(r.casinoWebhooks)) {
+		return nil
+	}
+	return r.casinoWebhooks[id-1]
+}
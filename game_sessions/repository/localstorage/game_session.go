@@ -3,8 +3,12 @@ package localstorage
 import (
 	"context"
 	"errors"
+	"github.com/eoscanada/eos-go"
 	gamesessions "platform-backend/game_sessions"
 	"platform-backend/models"
+	"platform-backend/utils"
+	"sort"
+	"time"
 )
 
 func (r *GameSessionsLocalRepo) HasGameSession(ctx context.Context, id uint64) (bool, error) {
@@ -40,10 +44,90 @@ func (r *GameSessionsLocalRepo) GetSessionByBlockChainID(ctx context.Context, bc
 	return nil, gamesessions.ErrGameSessionNotFound
 }
 
+func (r *GameSessionsLocalRepo) GetSessionsByStates(
+	ctx context.Context,
+	states []models.GameSessionState,
+) ([]*models.GameSession, error) {
+	sessions := make([]*models.GameSession, 0)
+	for _, ses := range r.gameSessions {
+		for _, state := range states {
+			if models.GameSessionState(ses.State) == state {
+				sessions = append(sessions, toModelGameSession(ses))
+				break
+			}
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (r *GameSessionsLocalRepo) GetGlobalSessions(
+	ctx context.Context,
+	filter gamesessions.FilterType,
+	query *gamesessions.SessionsQuery,
+) ([]*models.GameSession, error) {
+	return r.filterSessions(filter, query, func(ses *GameSession) bool {
+		return models.GameSessionState(ses.State) == models.GameFinished
+	})
+}
+
+func (r *GameSessionsLocalRepo) GetCasinoSessions(
+	ctx context.Context,
+	filter gamesessions.FilterType,
+	casinoId eos.Uint64,
+	query *gamesessions.SessionsQuery,
+) ([]*models.GameSession, error) {
+	return r.filterSessions(filter, query, func(ses *GameSession) bool {
+		return models.GameSessionState(ses.State) == models.GameFinished && ses.CasinoID == uint64(casinoId)
+	})
+}
+
+func (r *GameSessionsLocalRepo) filterSessions(
+	filter gamesessions.FilterType,
+	query *gamesessions.SessionsQuery,
+	match func(*GameSession) bool,
+) ([]*models.GameSession, error) {
+	sessions := make([]*models.GameSession, 0)
+	for _, ses := range r.gameSessions {
+		if !match(ses) || !query.Match(toModelGameSession(ses)) {
+			continue
+		}
+		switch filter {
+		case gamesessions.All:
+		case gamesessions.Wins:
+			if ses.PlayerWinAmount == nil || ses.PlayerWinAmount.Amount <= 0 {
+				continue
+			}
+		case gamesessions.Losts:
+			if ses.PlayerWinAmount == nil || ses.PlayerWinAmount.Amount >= 0 {
+				continue
+			}
+		default:
+			return nil, errors.New("bad filter")
+		}
+		sessions = append(sessions, toModelGameSession(ses))
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].LastUpdate == sessions[j].LastUpdate {
+			return sessions[i].ID > sessions[j].ID
+		}
+		return sessions[i].LastUpdate > sessions[j].LastUpdate
+	})
+
+	if limit := query.PageLimit(); len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+
+	return sessions, nil
+}
+
 func (r *GameSessionsLocalRepo) UpdateSessionOffset(ctx context.Context, id uint64, offset uint64) error {
-	ses, err := r.GetGameSession(ctx, id)
-	if err != nil {
-		return err
+	ses, ok := r.gameSessions[id]
+	if !ok {
+		return gamesessions.ErrGameSessionNotFound
 	}
 
 	ses.LastOffset = offset
@@ -51,18 +135,83 @@ func (r *GameSessionsLocalRepo) UpdateSessionOffset(ctx context.Context, id uint
 }
 
 func (r *GameSessionsLocalRepo) UpdateSessionState(ctx context.Context, id uint64, newState models.GameSessionState) error {
-	ses, err := r.GetGameSession(ctx, id)
+	return r.updateSessionState(id, nil, newState)
+}
+
+func (r *GameSessionsLocalRepo) CompareAndSwapSessionState(
+	ctx context.Context,
+	id uint64,
+	version uint64,
+	newState models.GameSessionState,
+) error {
+	return r.updateSessionState(id, &version, newState)
+}
+
+func (r *GameSessionsLocalRepo) updateSessionState(id uint64, expectedVersion *uint64, newState models.GameSessionState) error {
+	ses, ok := r.gameSessions[id]
+	if !ok {
+		return gamesessions.ErrGameSessionNotFound
+	}
+
+	if expectedVersion != nil && *expectedVersion != ses.Version {
+		return gamesessions.ErrSessionVersionConflict
+	}
+
+	if models.GameSessionState(ses.State).IsRepeatedTerminal(newState) {
+		return nil
+	}
+
+	if err := r.stateMachine.Transit(id, models.GameSessionState(ses.State), newState); err != nil {
+		return err
+	}
+
+	ses.State = uint16(newState)
+	ses.LastUpdate = time.Now().Unix()
+	ses.Version++
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateSessionStateBeforeFail(ctx context.Context, id uint64, prevState models.GameSessionState) error {
+	ses, ok := r.gameSessions[id]
+	if !ok {
+		return gamesessions.ErrGameSessionNotFound
+	}
+
+	ses.StateBeforeFail = &prevState
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateSessionPlayerWin(ctx context.Context, id uint64, playerWin string) error {
+	ses, ok := r.gameSessions[id]
+	if !ok {
+		return gamesessions.ErrGameSessionNotFound
+	}
+
+	winAmount, err := utils.ToBetAsset(playerWin)
 	if err != nil {
 		return err
 	}
+	ses.PlayerWinAmount = winAmount
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateSessionDeposit(ctx context.Context, id uint64, deposit string) error {
+	ses, ok := r.gameSessions[id]
+	if !ok {
+		return gamesessions.ErrGameSessionNotFound
+	}
 
-	ses.State = newState
+	depositAsset, err := utils.ToBetAsset(deposit)
+	if err != nil {
+		return err
+	}
+	ses.Deposit = depositAsset
 	return nil
 }
 
 func (r *GameSessionsLocalRepo) AddGameSession(ctx context.Context, ses *models.GameSession) error {
 	if _, ok := r.gameSessions[ses.ID]; ok {
-		return errors.New("session already exists")
+		return gamesessions.ErrGameSessionAlreadyExists
 	}
 	r.gameSessions[ses.ID] = &GameSession{
 		ID:              ses.ID,
@@ -71,6 +220,9 @@ func (r *GameSessionsLocalRepo) AddGameSession(ctx context.Context, ses *models.
 		CasinoID:        ses.CasinoID,
 		BlockchainSesID: ses.BlockchainSesID,
 		State:           uint16(ses.State),
+		LastOffset:      ses.LastOffset,
+		Deposit:         ses.Deposit,
+		LastUpdate:      ses.LastUpdate,
 		Updates:         make([]*models.GameSessionUpdate, 0, 100),
 	}
 	return nil
@@ -81,16 +233,14 @@ func (r *GameSessionsLocalRepo) AddFirstGameAction(ctx context.Context, sesID ui
 	return nil
 }
 
-func (r *GameSessionsLocalRepo) GetUserGameSessions(ctx context.Context, accountName string) ([]*models.GameSession, error) {
-	var sessions []*models.GameSession
-
-	for _, ses := range r.gameSessions {
-		if ses.Player == accountName {
-			sessions = append(sessions, toModelGameSession(ses))
-		}
-	}
-
-	return sessions, nil
+func (r *GameSessionsLocalRepo) GetUserGameSessions(
+	ctx context.Context,
+	accountName string,
+	query *gamesessions.SessionsQuery,
+) ([]*models.GameSession, error) {
+	return r.filterSessions(gamesessions.All, query, func(ses *GameSession) bool {
+		return ses.Player == accountName
+	})
 }
 
 func (r *GameSessionsLocalRepo) GetAllGameSessions(ctx context.Context) ([]*models.GameSession, error) {
@@ -121,5 +271,11 @@ func toModelGameSession(gs *GameSession) *models.GameSession {
 		CasinoID:        gs.CasinoID,
 		BlockchainSesID: gs.BlockchainSesID,
 		State:           models.GameSessionState(gs.State),
+		LastOffset:      gs.LastOffset,
+		Deposit:         gs.Deposit,
+		LastUpdate:      gs.LastUpdate,
+		PlayerWinAmount: gs.PlayerWinAmount,
+		StateBeforeFail: gs.StateBeforeFail,
+		Version:         gs.Version,
 	}
 }
@@ -1,10 +1,93 @@
 package localstorage
 
-import "context"
+import (
+	"context"
+	"platform-backend/models"
+	"sort"
+	"time"
+)
 
 func (r *GameSessionsLocalRepo) AddGameSessionTransaction(
 	_ context.Context,
-	_ string, _ uint64,
-	_ uint16, _ []uint64) error {
+	trxID string, sesID uint64,
+	actionType uint16, actionParams []uint64) error {
+	now := time.Now().Unix()
+	r.sessionTrxs[sesID] = append(r.sessionTrxs[sesID], &models.GameSessionTrx{
+		TrxID:        trxID,
+		SessionID:    sesID,
+		ActionType:   actionType,
+		ActionParams: actionParams,
+		Status:       models.TrxPending,
+		Created:      now,
+		NextCheck:    now,
+	})
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) CountGameSessionTransactions(_ context.Context, sesID uint64) (int, error) {
+	return len(r.sessionTrxs[sesID]), nil
+}
+
+func (r *GameSessionsLocalRepo) GetGameSessionTransactions(_ context.Context, sesID uint64) ([]*models.GameSessionTrx, error) {
+	trxs := make([]*models.GameSessionTrx, 0, len(r.sessionTrxs[sesID]))
+	for _, trx := range r.sessionTrxs[sesID] {
+		copied := *trx
+		trxs = append(trxs, &copied)
+	}
+	return trxs, nil
+}
+
+func (r *GameSessionsLocalRepo) GetPendingGameSessionTransactions(_ context.Context, limit int) ([]*models.GameSessionTrx, error) {
+	now := time.Now().Unix()
+	var trxs []*models.GameSessionTrx
+	for _, sessionTrxs := range r.sessionTrxs {
+		for _, trx := range sessionTrxs {
+			if !trx.Status.IsFinal() && trx.NextCheck <= now {
+				copied := *trx
+				trxs = append(trxs, &copied)
+			}
+		}
+	}
+
+	sort.Slice(trxs, func(i, j int) bool { return trxs[i].NextCheck < trxs[j].NextCheck })
+	if len(trxs) > limit {
+		trxs = trxs[:limit]
+	}
+	return trxs, nil
+}
+
+func (r *GameSessionsLocalRepo) PostponeGameSessionTransactionCheck(
+	_ context.Context,
+	trxID string,
+	delay time.Duration,
+) error {
+	if trx := r.getGameSessionTrx(trxID); trx != nil {
+		trx.CheckAttempts++
+		trx.NextCheck = time.Now().Add(delay).Unix()
+	}
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) UpdateGameSessionTransactionStatus(
+	_ context.Context,
+	trxID string,
+	status models.GameSessionTrxStatus,
+	blockNum *uint32,
+) error {
+	if trx := r.getGameSessionTrx(trxID); trx != nil {
+		trx.Status = status
+		trx.BlockNum = blockNum
+	}
+	return nil
+}
+
+func (r *GameSessionsLocalRepo) getGameSessionTrx(trxID string) *models.GameSessionTrx {
+	for _, sessionTrxs := range r.sessionTrxs {
+		for _, trx := range sessionTrxs {
+			if trx.TrxID == trxID {
+				return trx
+			}
+		}
+	}
 	return nil
 }
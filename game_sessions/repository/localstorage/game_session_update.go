@@ -3,6 +3,7 @@ package localstorage
 import (
 	"context"
 	"platform-backend/models"
+	"time"
 )
 
 func (r *GameSessionsLocalRepo) GetGameSessionUpdates(ctx context.Context, id uint64) ([]*models.GameSessionUpdate, error) {
@@ -20,6 +21,49 @@ func (r *GameSessionsLocalRepo) GetGameSessionUpdates(ctx context.Context, id ui
 	return make([]*models.GameSessionUpdate, 0), nil
 }
 
+func (r *GameSessionsLocalRepo) GetGameSessionUpdatesSince(ctx context.Context, id uint64, offset uint64) ([]*models.GameSessionUpdate, error) {
+	updates, err := r.GetGameSessionUpdates(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// updates without offset are placed by timestamp
+	var cursorTime time.Time
+	for _, upd := range updates {
+		if upd.Offset != nil && *upd.Offset <= offset && upd.Timestamp.After(cursorTime) {
+			cursorTime = upd.Timestamp
+		}
+	}
+
+	ret := make([]*models.GameSessionUpdate, 0)
+	for _, upd := range updates {
+		if upd.Offset != nil && *upd.Offset > offset || upd.Offset == nil && upd.Timestamp.After(cursorTime) {
+			ret = append(ret, upd)
+		}
+	}
+
+	return ret, nil
+}
+
+func (r *GameSessionsLocalRepo) GetLastGameSessionUpdateOffset(ctx context.Context, id uint64, maxOffset *uint64) (*uint64, error) {
+	updates, err := r.GetGameSessionUpdates(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var offset *uint64
+	for _, upd := range updates {
+		if upd.Offset == nil || maxOffset != nil && *upd.Offset > *maxOffset {
+			continue
+		}
+		if offset == nil || *upd.Offset > *offset {
+			offset = upd.Offset
+		}
+	}
+
+	return offset, nil
+}
+
 func (r *GameSessionsLocalRepo) AddGameSessionUpdate(ctx context.Context, upd *models.GameSessionUpdate) error {
 	_, err := r.GetGameSession(ctx, upd.SessionID)
 	if err != nil {
@@ -1,7 +1,10 @@
 package localstorage
 
 import (
+	"github.com/eoscanada/eos-go"
+	gamesessions "platform-backend/game_sessions"
 	"platform-backend/models"
+	"sync"
 )
 
 type GameSession struct {
@@ -11,17 +14,36 @@ type GameSession struct {
 	GameID          uint64
 	BlockchainSesID uint64
 	State           uint16
+	LastOffset      uint64
+	Deposit         *eos.Asset
+	LastUpdate      int64
+	PlayerWinAmount *eos.Asset
+	StateBeforeFail *models.GameSessionState
+	Version         uint64
 	Updates         []*models.GameSessionUpdate
 }
 
 type GameSessionsLocalRepo struct {
 	gameSessions     map[uint64]*GameSession
 	firstGameActions map[uint64]*models.GameAction
+	casinoTrxs       []*CasinoTrx
+	casinoWebhooks   []*models.CasinoWebhook
+	// webhooks are claimed and updated by delivery workers concurrently
+	webhooksMutex sync.Mutex
+	sessionTrxs   map[uint64][]*models.GameSessionTrx
+	stateMachine  *gamesessions.StateMachine
 }
 
 func NewGameSessionsLocalRepo() *GameSessionsLocalRepo {
+	return NewGameSessionsLocalRepoWithStateMachine(gamesessions.NewStateMachine(nil))
+}
+
+// NewGameSessionsLocalRepoWithStateMachine creates repo whose illegal transitions are counted by stateMachine
+func NewGameSessionsLocalRepoWithStateMachine(stateMachine *gamesessions.StateMachine) *GameSessionsLocalRepo {
 	return &GameSessionsLocalRepo{
-		gameSessions: make(map[uint64]*GameSession),
+		gameSessions:     make(map[uint64]*GameSession),
 		firstGameActions: make(map[uint64]*models.GameAction),
+		sessionTrxs:      make(map[uint64][]*models.GameSessionTrx),
+		stateMachine:     stateMachine,
 	}
 }
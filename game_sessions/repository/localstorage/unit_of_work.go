@@ -0,0 +1,180 @@
+package localstorage
+
+import (
+	"context"
+	"github.com/eoscanada/eos-go"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+)
+
+// unitOfWork writes to repo directly and restores touched sessions on rollback
+type unitOfWork struct {
+	repo         *GameSessionsLocalRepo
+	sessions     map[uint64]*GameSession
+	firstActions map[uint64]*models.GameAction
+	// sessions added by unit are removed on rollback
+	added map[uint64]bool
+	// session transactions count before the first added one
+	sessionTrxsLen map[uint64]int
+	// outbox records before the first update
+	casinoTrxs map[uint64]CasinoTrx
+	// outbox and webhooks count before the first added one, negative if nothing is added
+	casinoTrxsLen int
+	webhooksLen   int
+	done          bool
+}
+
+func (r *GameSessionsLocalRepo) BeginUnitOfWork(ctx context.Context) (gamesessions.UnitOfWork, error) {
+	return &unitOfWork{
+		repo:           r,
+		sessions:       make(map[uint64]*GameSession),
+		firstActions:   make(map[uint64]*models.GameAction),
+		added:          make(map[uint64]bool),
+		sessionTrxsLen: make(map[uint64]int),
+		casinoTrxs:     make(map[uint64]CasinoTrx),
+		casinoTrxsLen:  -1,
+		webhooksLen:    -1,
+	}, nil
+}
+
+// backup saves session state before the first write
+func (u *unitOfWork) backup(id uint64) {
+	if _, ok := u.sessions[id]; ok {
+		return
+	}
+	if ses, ok := u.repo.gameSessions[id]; ok {
+		backup := *ses
+		u.sessions[id] = &backup
+	}
+}
+
+func (u *unitOfWork) AddGameSessionUpdate(ctx context.Context, upd *models.GameSessionUpdate) error {
+	u.backup(upd.SessionID)
+	return u.repo.AddGameSessionUpdate(ctx, upd)
+}
+
+func (u *unitOfWork) UpdateSessionState(ctx context.Context, id uint64, state models.GameSessionState) error {
+	u.backup(id)
+	return u.repo.UpdateSessionState(ctx, id, state)
+}
+
+func (u *unitOfWork) CompareAndSwapSessionState(ctx context.Context, id uint64, version uint64,
+	state models.GameSessionState) error {
+	u.backup(id)
+	return u.repo.CompareAndSwapSessionState(ctx, id, version, state)
+}
+
+func (u *unitOfWork) UpdateSessionStateBeforeFail(ctx context.Context, id uint64, prevState models.GameSessionState) error {
+	u.backup(id)
+	return u.repo.UpdateSessionStateBeforeFail(ctx, id, prevState)
+}
+
+func (u *unitOfWork) UpdateSessionPlayerWin(ctx context.Context, id uint64, playerWin string) error {
+	u.backup(id)
+	return u.repo.UpdateSessionPlayerWin(ctx, id, playerWin)
+}
+
+func (u *unitOfWork) UpdateSessionOffset(ctx context.Context, id uint64, offset uint64) error {
+	u.backup(id)
+	return u.repo.UpdateSessionOffset(ctx, id, offset)
+}
+
+func (u *unitOfWork) DeleteFirstGameAction(ctx context.Context, sesID uint64) error {
+	if _, ok := u.firstActions[sesID]; !ok {
+		u.firstActions[sesID] = u.repo.firstGameActions[sesID]
+	}
+	return u.repo.DeleteFirstGameAction(ctx, sesID)
+}
+
+func (u *unitOfWork) AddGameSession(ctx context.Context, ses *models.GameSession) error {
+	if err := u.repo.AddGameSession(ctx, ses); err != nil {
+		return err
+	}
+	u.added[ses.ID] = true
+	return nil
+}
+
+func (u *unitOfWork) AddSessionDeposit(ctx context.Context, id uint64, deposit eos.Asset) error {
+	ses, ok := u.repo.gameSessions[id]
+	if !ok {
+		return gamesessions.ErrGameSessionNotFound
+	}
+	u.backup(id)
+	totalDeposit := deposit
+	if ses.Deposit != nil {
+		totalDeposit = ses.Deposit.Add(deposit)
+	}
+	ses.Deposit = &totalDeposit
+	return nil
+}
+
+func (u *unitOfWork) AddGameSessionTransaction(ctx context.Context, trxID string, sesID uint64,
+	actionType uint16, actionParams []uint64) error {
+	if _, ok := u.sessionTrxsLen[sesID]; !ok {
+		u.sessionTrxsLen[sesID] = len(u.repo.sessionTrxs[sesID])
+	}
+	return u.repo.AddGameSessionTransaction(ctx, trxID, sesID, actionType, actionParams)
+}
+
+func (u *unitOfWork) AddCasinoTrx(ctx context.Context, trx *models.CasinoTrx) error {
+	if u.casinoTrxsLen < 0 {
+		u.casinoTrxsLen = len(u.repo.casinoTrxs)
+	}
+	return u.repo.AddCasinoTrx(ctx, trx)
+}
+
+func (u *unitOfWork) UpdateCasinoTrxSent(ctx context.Context, id uint64, trxID string) error {
+	if trx := u.repo.getCasinoTrx(id); trx != nil {
+		if _, ok := u.casinoTrxs[id]; !ok {
+			backup := *trx
+			copied := *trx.CasinoTrx
+			backup.CasinoTrx = &copied
+			u.casinoTrxs[id] = backup
+		}
+	}
+	return u.repo.UpdateCasinoTrxSent(ctx, id, trxID)
+}
+
+func (u *unitOfWork) AddCasinoWebhook(ctx context.Context, webhook *models.CasinoWebhook) error {
+	if u.webhooksLen < 0 {
+		u.webhooksLen = len(u.repo.casinoWebhooks)
+	}
+	return u.repo.AddCasinoWebhook(ctx, webhook)
+}
+
+func (u *unitOfWork) Commit(ctx context.Context) error {
+	u.done = true
+	return nil
+}
+
+func (u *unitOfWork) Rollback(ctx context.Context) error {
+	if u.done {
+		return nil
+	}
+	u.done = true
+
+	for id, ses := range u.sessions {
+		u.repo.gameSessions[id] = ses
+	}
+	for sesID, action := range u.firstActions {
+		if action != nil {
+			u.repo.firstGameActions[sesID] = action
+		}
+	}
+	for id := range u.added {
+		delete(u.repo.gameSessions, id)
+	}
+	for sesID, trxsLen := range u.sessionTrxsLen {
+		u.repo.sessionTrxs[sesID] = u.repo.sessionTrxs[sesID][:trxsLen]
+	}
+	for id, trx := range u.casinoTrxs {
+		*u.repo.getCasinoTrx(id) = trx
+	}
+	if u.casinoTrxsLen >= 0 {
+		u.repo.casinoTrxs = u.repo.casinoTrxs[:u.casinoTrxsLen]
+	}
+	if u.webhooksLen >= 0 {
+		u.repo.casinoWebhooks = u.repo.casinoWebhooks[:u.webhooksLen]
+	}
+	return nil
+}
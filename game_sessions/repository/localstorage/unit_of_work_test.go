@@ -0,0 +1,144 @@
+package localstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+)
+
+func TestUnitOfWorkRollback(t *testing.T) {
+	ctx := context.Background()
+	repo := NewGameSessionsLocalRepo()
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.GameStartedInBC}))
+	require.NoError(t, repo.AddFirstGameAction(ctx, 1, &models.GameAction{Type: 1}))
+
+	uow, err := repo.BeginUnitOfWork(ctx)
+	require.NoError(t, err)
+	offset := uint64(10)
+	require.NoError(t, uow.AddGameSessionUpdate(ctx, &models.GameSessionUpdate{SessionID: 1, Offset: &offset}))
+	require.NoError(t, uow.UpdateSessionState(ctx, 1, models.RequestedGameAction))
+	require.NoError(t, uow.UpdateSessionOffset(ctx, 1, offset))
+	require.NoError(t, uow.DeleteFirstGameAction(ctx, 1))
+	require.NoError(t, uow.Rollback(ctx))
+
+	ses, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameStartedInBC, ses.State)
+	assert.Equal(t, uint64(0), ses.LastOffset)
+	updates, err := repo.GetGameSessionUpdates(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, updates)
+	_, err = repo.GetFirstAction(ctx, 1)
+	assert.NoError(t, err)
+}
+
+func TestUnitOfWorkCommit(t *testing.T) {
+	ctx := context.Background()
+	repo := NewGameSessionsLocalRepo()
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.GameStartedInBC}))
+
+	uow, err := repo.BeginUnitOfWork(ctx)
+	require.NoError(t, err)
+	require.NoError(t, uow.UpdateSessionState(ctx, 1, models.RequestedGameAction))
+	require.NoError(t, uow.Commit(ctx))
+	// rollback after commit does nothing
+	require.NoError(t, uow.Rollback(ctx))
+
+	ses, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.RequestedGameAction, ses.State)
+}
+
+func TestUnitOfWorkCasinoWebhooks(t *testing.T) {
+	ctx := context.Background()
+	repo := NewGameSessionsLocalRepo()
+	session := &models.GameSession{ID: 1, CasinoID: 2, State: models.GameStartedInBC}
+	require.NoError(t, repo.AddGameSession(ctx, session))
+
+	started, err := models.NewCasinoWebhook(models.WebhookSessionStarted, session, nil, "")
+	require.NoError(t, err)
+	uow, err := repo.BeginUnitOfWork(ctx)
+	require.NoError(t, err)
+	require.NoError(t, uow.AddCasinoWebhook(ctx, started))
+	require.NoError(t, uow.Commit(ctx))
+
+	// webhook of rolled back unit isn't delivered
+	failed, err := models.NewCasinoWebhook(models.WebhookSessionFailed, session, nil, "")
+	require.NoError(t, err)
+	uow, err = repo.BeginUnitOfWork(ctx)
+	require.NoError(t, err)
+	require.NoError(t, uow.AddCasinoWebhook(ctx, failed))
+	require.NoError(t, uow.Rollback(ctx))
+
+	claimed, err := repo.ClaimCasinoWebhooks(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, models.WebhookSessionStarted, claimed[0].Event)
+	assert.Equal(t, 1, claimed[0].Attempts)
+
+	// claimed webhook is leased
+	claimed, err = repo.ClaimCasinoWebhooks(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	casinoID := uint64(2)
+	webhooks, err := repo.GetCasinoWebhooks(ctx, &gamesessions.CasinoWebhooksQuery{CasinoID: &casinoID})
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, models.CasinoWebhookPending, webhooks[0].Status)
+}
+
+func TestRepeatedTerminalState(t *testing.T) {
+	ctx := context.Background()
+	repo := NewGameSessionsLocalRepo()
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.GameStartedInBC}))
+	require.NoError(t, repo.UpdateSessionState(ctx, 1, models.GameFailed))
+	ses, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+
+	// failed event of contract after backend failed session
+	require.NoError(t, repo.UpdateSessionState(ctx, 1, models.GameFailed))
+	failed, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ses.Version, failed.Version)
+
+	assert.Equal(t, gamesessions.ErrIllegalStateTransition, repo.UpdateSessionState(ctx, 1, models.GameFinished))
+}
+
+func TestUnitOfWorkRollbackAddedRecords(t *testing.T) {
+	ctx := context.Background()
+	repo := NewGameSessionsLocalRepo()
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.GameStartedInBC}))
+	sent := &models.CasinoTrx{SessionID: 1}
+	require.NoError(t, repo.AddCasinoTrx(ctx, sent))
+
+	uow, err := repo.BeginUnitOfWork(ctx)
+	require.NoError(t, err)
+	require.NoError(t, uow.AddGameSession(ctx, &models.GameSession{ID: 2, State: models.NewGameTrxSent}))
+	require.NoError(t, uow.AddCasinoTrx(ctx, &models.CasinoTrx{SessionID: 2}))
+	require.NoError(t, uow.UpdateCasinoTrxSent(ctx, sent.ID, "trx1"))
+	require.NoError(t, uow.AddSessionDeposit(ctx, 1, eos.Asset{Amount: 100000, Symbol: eos.Symbol{Precision: 4, Symbol: "BET"}}))
+	require.NoError(t, uow.AddGameSessionTransaction(ctx, "trx1", 1, 0, nil))
+	require.NoError(t, uow.Rollback(ctx))
+
+	_, err = repo.GetGameSession(ctx, 2)
+	assert.Equal(t, gamesessions.ErrGameSessionNotFound, err)
+	ses, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Nil(t, ses.Deposit)
+	trxs, err := repo.GetGameSessionTransactions(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, trxs)
+	pending, err := repo.HasPendingCasinoTrx(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, pending)
+	pending, err = repo.HasPendingCasinoTrx(ctx, 2)
+	require.NoError(t, err)
+	assert.False(t, pending)
+}
@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"platform-backend/db"
+	"platform-backend/models"
+	"platform-backend/utils"
+	"time"
+)
+
+const (
+	insertCasinoTrxStmt = `
+        INSERT INTO casino_trx_outbox
+            (ses_id, casino_id, kind, trx, action_type, action_params, deposit)
+        VALUES
+            ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id`
+	// claimed records are leased: they are not claimed again until lease expiration
+	claimCasinoTrxsStmt = `
+        UPDATE casino_trx_outbox
+        SET attempts = attempts + 1, next_attempt = now() + $2 * INTERVAL '1 millisecond'
+        WHERE id IN (
+            SELECT id FROM casino_trx_outbox
+            WHERE status = 0 AND next_attempt <= now()
+            ORDER BY id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, ses_id, casino_id, kind, trx, action_type, action_params, deposit, status, attempts,
+            trx_id, signed_trx`
+	selectPendingCasinoTrxCntStmt = "SELECT count(*) FROM casino_trx_outbox WHERE ses_id = $1 AND status = 0"
+	updateCasinoTrxSignedStmt     = "UPDATE casino_trx_outbox SET trx_id = $2, signed_trx = $3 WHERE id = $1"
+	updateCasinoTrxSentStmt       = "UPDATE casino_trx_outbox SET status = 1, trx_id = $2, last_error = NULL WHERE id = $1"
+	updateCasinoTrxRetryStmt      = "UPDATE casino_trx_outbox SET next_attempt = now() + $2 * INTERVAL '1 millisecond', last_error = $3 WHERE id = $1"
+	updateCasinoTrxFailedStmt     = "UPDATE casino_trx_outbox SET status = 2, last_error = $2 WHERE id = $1"
+)
+
+type CasinoTrx struct {
+	ID           uint64              `db:"id"`
+	SessionID    uint64              `db:"ses_id"`
+	CasinoID     uint64              `db:"casino_id"`
+	Kind         uint16              `db:"kind"`
+	Trx          []byte              `db:"trx"`
+	ActionType   uint16              `db:"action_type"`
+	ActionParams pgtype.NumericArray `db:"action_params"`
+	Deposit      *string             `db:"deposit"`
+	Status       uint16              `db:"status"`
+	Attempts     int                 `db:"attempts"`
+	TrxID        *string             `db:"trx_id"`
+	SignedTrx    []byte              `db:"signed_trx"`
+}
+
+func (t *CasinoTrx) Scan(row pgx.Row) error {
+	return row.Scan(
+		&t.ID,
+		&t.SessionID,
+		&t.CasinoID,
+		&t.Kind,
+		&t.Trx,
+		&t.ActionType,
+		&t.ActionParams,
+		&t.Deposit,
+		&t.Status,
+		&t.Attempts,
+		&t.TrxID,
+		&t.SignedTrx,
+	)
+}
+
+func (r *GameSessionsPostgresRepo) AddCasinoTrx(ctx context.Context, trx *models.CasinoTrx) error {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	return addCasinoTrx(ctx, conn, trx)
+}
+
+func addCasinoTrx(ctx context.Context, q querier, trx *models.CasinoTrx) error {
+	var deposit *string
+	if trx.Deposit != nil {
+		s := trx.Deposit.String()
+		deposit = &s
+	}
+
+	return q.QueryRow(ctx, insertCasinoTrxStmt,
+		trx.SessionID, trx.CasinoID, uint16(trx.Kind), trx.Trx,
+		trx.ActionType, trx.ActionParams, deposit,
+	).Scan(&trx.ID)
+}
+
+func (r *GameSessionsPostgresRepo) ClaimCasinoTrxs(ctx context.Context, limit int, lease time.Duration) ([]*models.CasinoTrx, error) {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, claimCasinoTrxsStmt, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trxs := make([]*models.CasinoTrx, 0)
+	for rows.Next() {
+		trx := new(CasinoTrx)
+		if err = trx.Scan(rows); err != nil {
+			return nil, err
+		}
+		modelTrx, err := toModelCasinoTrx(trx)
+		if err != nil {
+			return nil, err
+		}
+		trxs = append(trxs, modelTrx)
+	}
+
+	return trxs, rows.Err()
+}
+
+func (r *GameSessionsPostgresRepo) HasPendingCasinoTrx(ctx context.Context, sesID uint64) (bool, error) {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var cnt uint
+	err = conn.QueryRow(ctx, selectPendingCasinoTrxCntStmt, sesID).Scan(&cnt)
+	if err != nil {
+		return false, err
+	}
+
+	return cnt > 0, nil
+}
+
+func (r *GameSessionsPostgresRepo) UpdateCasinoTrxSigned(ctx context.Context, id uint64, trxID string, signedTrx []byte) error {
+	return r.execCasinoTrxUpdate(ctx, updateCasinoTrxSignedStmt, id, trxID, signedTrx)
+}
+
+func (r *GameSessionsPostgresRepo) UpdateCasinoTrxSent(ctx context.Context, id uint64, trxID string) error {
+	return r.execCasinoTrxUpdate(ctx, updateCasinoTrxSentStmt, id, trxID)
+}
+
+func (r *GameSessionsPostgresRepo) UpdateCasinoTrxRetry(ctx context.Context, id uint64, delay time.Duration, lastErr string) error {
+	return r.execCasinoTrxUpdate(ctx, updateCasinoTrxRetryStmt, id, delay.Milliseconds(), lastErr)
+}
+
+func (r *GameSessionsPostgresRepo) UpdateCasinoTrxFailed(ctx context.Context, id uint64, lastErr string) error {
+	return r.execCasinoTrxUpdate(ctx, updateCasinoTrxFailedStmt, id, lastErr)
+}
+
+func (r *GameSessionsPostgresRepo) execCasinoTrxUpdate(ctx context.Context, stmt string, args ...interface{}) error {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, stmt, args...)
+	return err
+}
+
+func toModelCasinoTrx(t *CasinoTrx) (*models.CasinoTrx, error) {
+	ret := &models.CasinoTrx{
+		ID:         t.ID,
+		SessionID:  t.SessionID,
+		CasinoID:   t.CasinoID,
+		Kind:       models.CasinoTrxKind(t.Kind),
+		Trx:        t.Trx,
+		ActionType: t.ActionType,
+		Status:     models.CasinoTrxStatus(t.Status),
+		Attempts:   t.Attempts,
+		SignedTrx:  t.SignedTrx,
+	}
+	if t.TrxID != nil {
+		ret.TrxID = *t.TrxID
+	}
+	if err := t.ActionParams.AssignTo(&ret.ActionParams); err != nil {
+		return nil, err
+	}
+	if t.Deposit != nil {
+		deposit, err := utils.ToBetAsset(*t.Deposit)
+		if err != nil {
+			return nil, err
+		}
+		ret.Deposit = deposit
+	}
+	return ret, nil
+}
@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"platform-backend/db"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"time"
+)
+
+const (
+	casinoWebhookColumns    = "id, casino_id, ses_id, event, payload, status, attempts, next_attempt, last_error, created"
+	insertCasinoWebhookStmt = `
+        INSERT INTO casino_webhooks
+            (casino_id, ses_id, event, payload)
+        VALUES
+            ($1, $2, $3, $4)
+        RETURNING id`
+	// claimed records are leased: they are not claimed again until lease expiration
+	claimCasinoWebhooksStmt = `
+        UPDATE casino_webhooks
+        SET attempts = attempts + 1, next_attempt = now() + $2 * INTERVAL '1 millisecond'
+        WHERE id IN (
+            SELECT id FROM casino_webhooks
+            WHERE status = 0 AND next_attempt <= now()
+            ORDER BY id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + casinoWebhookColumns
+	selectCasinoWebhooksStmt = "SELECT " + casinoWebhookColumns + ` FROM casino_webhooks
+        WHERE ($1::NUMERIC IS NULL OR casino_id = $1)
+            AND ($2::NUMERIC IS NULL OR ses_id = $2)
+            AND ($3::SMALLINT IS NULL OR status = $3)
+        ORDER BY id DESC LIMIT $4`
+	updateCasinoWebhookDeliveredStmt = "UPDATE casino_webhooks SET status = 1, last_error = NULL WHERE id = $1"
+	updateCasinoWebhookRetryStmt     = "UPDATE casino_webhooks SET next_attempt = now() + $2 * INTERVAL '1 millisecond', last_error = $3 WHERE id = $1"
+	updateCasinoWebhookFailedStmt    = "UPDATE casino_webhooks SET status = 2, last_error = $2 WHERE id = $1"
+	requeueCasinoWebhookStmt         = "UPDATE casino_webhooks SET status = 0, attempts = 0, next_attempt = now() WHERE id = $1"
+)
+
+type CasinoWebhook struct {
+	ID          uint64    `db:"id"`
+	CasinoID    uint64    `db:"casino_id"`
+	SessionID   uint64    `db:"ses_id"`
+	Event       string    `db:"event"`
+	Payload     []byte    `db:"payload"`
+	Status      uint16    `db:"status"`
+	Attempts    int       `db:"attempts"`
+	NextAttempt time.Time `db:"next_attempt"`
+	LastError   *string   `db:"last_error"`
+	Created     time.Time `db:"created"`
+}
+
+func (w *CasinoWebhook) Scan(row pgx.Row) error {
+	return row.Scan(
+		&w.ID,
+		&w.CasinoID,
+		&w.SessionID,
+		&w.Event,
+		&w.Payload,
+		&w.Status,
+		&w.Attempts,
+		&w.NextAttempt,
+		&w.LastError,
+		&w.Created,
+	)
+}
+
+func (r *GameSessionsPostgresRepo) AddCasinoWebhook(ctx context.Context, webhook *models.CasinoWebhook) error {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	return addCasinoWebhook(ctx, conn, webhook)
+}
+
+func addCasinoWebhook(ctx context.Context, q querier, webhook *models.CasinoWebhook) error {
+	return q.QueryRow(ctx, insertCasinoWebhookStmt,
+		webhook.CasinoID, webhook.SessionID, string(webhook.Event), []byte(webhook.Payload),
+	).Scan(&webhook.ID)
+}
+
+func (r *GameSessionsPostgresRepo) ClaimCasinoWebhooks(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*models.CasinoWebhook, error) {
+	return r.selectCasinoWebhooks(ctx, claimCasinoWebhooksStmt, limit, lease.Milliseconds())
+}
+
+func (r *GameSessionsPostgresRepo) GetCasinoWebhooks(
+	ctx context.Context,
+	query *gamesessions.CasinoWebhooksQuery,
+) ([]*models.CasinoWebhook, error) {
+	var status *uint16
+	if query.Status != nil {
+		s := uint16(*query.Status)
+		status = &s
+	}
+	return r.selectCasinoWebhooks(ctx, selectCasinoWebhooksStmt,
+		query.CasinoID, query.SessionID, status, query.PageLimit())
+}
+
+func (r *GameSessionsPostgresRepo) UpdateCasinoWebhookDelivered(ctx context.Context, id uint64) error {
+	return r.execCasinoTrxUpdate(ctx, updateCasinoWebhookDeliveredStmt, id)
+}
+
+func (r *GameSessionsPostgresRepo) UpdateCasinoWebhookRetry(
+	ctx context.Context,
+	id uint64,
+	delay time.Duration,
+	lastErr string,
+) error {
+	return r.execCasinoTrxUpdate(ctx, updateCasinoWebhookRetryStmt, id, delay.Milliseconds(), lastErr)
+}
+
+func (r *GameSessionsPostgresRepo) UpdateCasinoWebhookFailed(ctx context.Context, id uint64, lastErr string) error {
+	return r.execCasinoTrxUpdate(ctx, updateCasinoWebhookFailedStmt, id, lastErr)
+}
+
+func (r *GameSessionsPostgresRepo) RequeueCasinoWebhook(ctx context.Context, id uint64) error {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, requeueCasinoWebhookStmt, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return gamesessions.ErrCasinoWebhookNotFound
+	}
+	return nil
+}
+
+func (r *GameSessionsPostgresRepo) selectCasinoWebhooks(
+	ctx context.Context,
+	stmt string,
+	args ...interface{},
+) ([]*models.CasinoWebhook, error) {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]*models.CasinoWebhook, 0)
+	for rows.Next() {
+		webhook := new(CasinoWebhook)
+		if err := webhook.Scan(rows); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, toModelCasinoWebhook(webhook))
+	}
+
+	return webhooks, rows.Err()
+}
+
+func toModelCasinoWebhook(w *CasinoWebhook) *models.CasinoWebhook {
+	webhook := &models.CasinoWebhook{
+		ID:          w.ID,
+		CasinoID:    w.CasinoID,
+		SessionID:   w.SessionID,
+		Event:       models.CasinoWebhookEvent(w.Event),
+		Payload:     w.Payload,
+		Status:      models.CasinoWebhookStatus(w.Status),
+		Attempts:    w.Attempts,
+		NextAttempt: w.NextAttempt,
+		Created:     w.Created,
+	}
+	if w.LastError != nil {
+		webhook.LastError = *w.LastError
+	}
+	return webhook
+}
@@ -2,8 +2,8 @@ package postgres
 
 import (
 	"context"
-	"errors"
 	"github.com/eoscanada/eos-go"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
 	"platform-backend/db"
@@ -13,30 +13,26 @@ import (
 	"time"
 )
 
-const GlobalCnt = 30
-
 const (
-	selectGameSessionByIdStmt        = "SELECT * FROM game_sessions WHERE id = $1"
-	selectGameSessionByBcIDStmt      = "SELECT * FROM game_sessions WHERE blockchain_req_id = $1"
-	selectUserGameSessionsStmt       = "SELECT * FROM game_sessions WHERE player = $1 ORDER BY last_update DESC"
-	selectGlobalSessionsStmt         = "SELECT * FROM game_sessions WHERE state = $1 ORDER BY last_update DESC LIMIT $2"
-	selectGlobalSessionsLostStmt     = "SELECT * FROM game_sessions WHERE state = $1 AND player_win_amount SIMILAR TO '-%' ORDER BY last_update DESC LIMIT $2"
-	selectGlobalSessionsWinsStmt     = "SELECT * FROM game_sessions WHERE state = $1 AND player_win_amount NOT SIMILAR TO '(-%|0.0000 %)' ORDER BY last_update DESC LIMIT $2"
-	selectCasinoSessionsStmt         = "SELECT * FROM game_sessions WHERE state = $1 AND casino_id = $2 ORDER BY last_update DESC LIMIT $3"
-	selectCasinoSessionsLostStmt     = "SELECT * FROM game_sessions WHERE state = $1 AND casino_id = $2 AND player_win_amount SIMILAR TO '-%' ORDER BY last_update DESC LIMIT $3"
-	selectCasinoSessionsWinsStmt     = "SELECT * FROM game_sessions WHERE state = $1 AND casino_id = $2 AND player_win_amount NOT SIMILAR TO '(-%|0.0000 %)' ORDER BY last_update DESC LIMIT $3"
-	selectAllGameSessionsStmt        = "SELECT * FROM game_sessions"
-	selectFirstGameActionStmt        = "SELECT * FROM first_game_actions WHERE ses_id = $1"
-	updateSessionStateStmt           = "UPDATE game_sessions SET state = $2, last_update = $3 WHERE id = $1"
-	updateSessionDepositStmt         = "UPDATE game_sessions SET deposit = $2 WHERE id = $1"
-	updateSessionPlayerWinStmt       = "UPDATE game_sessions SET player_win_amount = $2 WHERE id = $1"
-	updateSessionOffsetStmt          = "UPDATE game_sessions SET last_offset = $2 WHERE id = $1"
-	updateSessionStateBeforeFailStmt = "UPDATE game_sessions SET state_before_fail = $2 WHERE id = $1"
-	selectGameSessionCntByIdStmt     = "SELECT count(*) FROM game_sessions WHERE id = $1"
-	insertGameSessionStmt            = "INSERT INTO game_sessions VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)"
-	insertFirstGameActionStmt        = "INSERT INTO first_game_actions VALUES ($1, $2, $3)"
-	deleteGameSessionByIdStmt        = "DELETE FROM game_sessions WHERE id = $1"
-	deleteFirstGameActionStmt        = "DELETE FROM first_game_actions WHERE ses_id = $1"
+	selectGameSessionByIdStmt         = "SELECT * FROM game_sessions WHERE id = $1"
+	selectGameSessionByBcIDStmt       = "SELECT * FROM game_sessions WHERE blockchain_req_id = $1"
+	selectAllGameSessionsStmt         = "SELECT * FROM game_sessions"
+	selectGameSessionsByStatesStmt    = "SELECT * FROM game_sessions WHERE state = ANY($1::SMALLINT[]) ORDER BY id"
+	selectFirstGameActionStmt         = "SELECT * FROM first_game_actions WHERE ses_id = $1"
+	selectSessionStateForUpdateStmt   = "SELECT state, version FROM game_sessions WHERE id = $1 FOR UPDATE"
+	updateSessionStateStmt            = "UPDATE game_sessions SET state = $2, last_update = $3, version = version + 1 WHERE id = $1"
+	selectSessionDepositForUpdateStmt = "SELECT deposit FROM game_sessions WHERE id = $1 FOR UPDATE"
+	updateSessionDepositStmt          = "UPDATE game_sessions SET deposit = $2 WHERE id = $1"
+	updateSessionPlayerWinStmt        = "UPDATE game_sessions SET player_win_amount = $2 WHERE id = $1"
+	updateSessionOffsetStmt           = "UPDATE game_sessions SET last_offset = $2 WHERE id = $1"
+	updateSessionStateBeforeFailStmt  = "UPDATE game_sessions SET state_before_fail = $2 WHERE id = $1"
+	selectGameSessionCntByIdStmt      = "SELECT count(*) FROM game_sessions WHERE id = $1"
+	insertGameSessionStmt             = "INSERT INTO game_sessions VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)"
+	insertFirstGameActionStmt         = "INSERT INTO first_game_actions VALUES ($1, $2, $3)"
+	deleteGameSessionByIdStmt         = "DELETE FROM game_sessions WHERE id = $1"
+	deleteFirstGameActionStmt         = "DELETE FROM first_game_actions WHERE ses_id = $1"
+
+	sqlDuplicateUniqueErrorCode = "23505"
 )
 
 type GameSession struct {
@@ -51,6 +47,7 @@ type GameSession struct {
 	LastUpdate      int64   `db:"last_update"`
 	PlayerWinAmount *string `db:"player_win_amount"`
 	StateBeforeFail *uint64 `db:"state_before_fail"`
+	Version         uint64  `db:"version"`
 }
 
 func (s *GameSession) Scan(row pgx.Row) error {
@@ -66,6 +63,7 @@ func (s *GameSession) Scan(row pgx.Row) error {
 		&s.LastUpdate,
 		&s.PlayerWinAmount,
 		&s.StateBeforeFail,
+		&s.Version,
 	)
 }
 
@@ -118,70 +116,65 @@ func (r *GameSessionsPostgresRepo) GetGameSession(ctx context.Context, id uint64
 	return toModelGameSession(session)
 }
 
-func (r *GameSessionsPostgresRepo) GetCasinoSessions(ctx context.Context, filter gamesessions.FilterType, casinoId eos.Uint64) ([]*models.GameSession, error) {
-	conn, err := db.DbPool.Acquire(ctx)
-	if err != nil {
+func (r *GameSessionsPostgresRepo) GetCasinoSessions(
+	ctx context.Context,
+	filter gamesessions.FilterType,
+	casinoId eos.Uint64,
+	query *gamesessions.SessionsQuery,
+) ([]*models.GameSession, error) {
+	b := new(sessionsQueryBuilder)
+	b.where("state = " + b.arg(uint16(models.GameFinished)))
+	b.where("casino_id = " + b.arg(uint64(casinoId)))
+	if err := b.whereFilter(filter); err != nil {
 		return nil, err
 	}
-	defer conn.Release()
+	b.whereQuery(query)
 
-	var rows pgx.Rows
-	switch filter {
-	case gamesessions.All:
-		rows, err = conn.Query(ctx, selectCasinoSessionsStmt, models.GameFinished, casinoId, GlobalCnt)
-	case gamesessions.Wins:
-		rows, err = conn.Query(ctx, selectCasinoSessionsWinsStmt, models.GameFinished, casinoId, GlobalCnt)
-	case gamesessions.Losts:
-		rows, err = conn.Query(ctx, selectCasinoSessionsLostStmt, models.GameFinished, casinoId, GlobalCnt)
-	default:
-		return nil, errors.New("bad filter")
-	}
+	stmt, args := b.build(query.PageLimit())
+	return r.selectSessions(ctx, stmt, args...)
+}
 
-	if err != nil {
+func (r *GameSessionsPostgresRepo) GetGlobalSessions(
+	ctx context.Context,
+	filter gamesessions.FilterType,
+	query *gamesessions.SessionsQuery,
+) ([]*models.GameSession, error) {
+	b := new(sessionsQueryBuilder)
+	b.where("state = " + b.arg(uint16(models.GameFinished)))
+	if err := b.whereFilter(filter); err != nil {
 		return nil, err
 	}
+	b.whereQuery(query)
 
-	gameSessions := make([]*models.GameSession, 0, GlobalCnt)
-	for rows.Next() {
-		session := new(GameSession)
-		err = session.Scan(rows)
-		if err != nil {
-			return nil, err
-		}
-		ses, err := toModelGameSession(session)
-		if err != nil {
-			return nil, err
-		}
-		gameSessions = append(gameSessions, ses)
-	}
+	stmt, args := b.build(query.PageLimit())
+	return r.selectSessions(ctx, stmt, args...)
+}
 
-	return gameSessions, nil
+func (r *GameSessionsPostgresRepo) GetSessionsByStates(
+	ctx context.Context,
+	states []models.GameSessionState,
+) ([]*models.GameSession, error) {
+	dbStates := make([]int16, len(states))
+	for i, state := range states {
+		dbStates[i] = int16(state)
+	}
+	return r.selectSessions(ctx, selectGameSessionsByStatesStmt, dbStates)
 }
 
-func (r *GameSessionsPostgresRepo) GetGlobalSessions(ctx context.Context, filter gamesessions.FilterType) ([]*models.GameSession, error) {
+func (r *GameSessionsPostgresRepo) selectSessions(ctx context.Context, stmt string, args ...interface{}) ([]*models.GameSession, error) {
 	conn, err := db.DbPool.Acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Release()
 
-	var rows pgx.Rows
-	switch filter {
-	case gamesessions.All:
-		rows, err = conn.Query(ctx, selectGlobalSessionsStmt, models.GameFinished, GlobalCnt)
-	case gamesessions.Wins:
-		rows, err = conn.Query(ctx, selectGlobalSessionsWinsStmt, models.GameFinished, GlobalCnt)
-	case gamesessions.Losts:
-		rows, err = conn.Query(ctx, selectGlobalSessionsLostStmt, models.GameFinished, GlobalCnt)
-	default:
-		return nil, errors.New("bad filter")
-	}
-
+	rows, err := conn.Query(ctx, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	gameSessions := make([]*models.GameSession, 0, GlobalCnt)
+	gameSessions := make([]*models.GameSession, 0)
 	for rows.Next() {
 		session := new(GameSession)
 		err = session.Scan(rows)
@@ -255,7 +248,11 @@ func (r *GameSessionsPostgresRepo) UpdateSessionDeposit(ctx context.Context, id
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, updateSessionDepositStmt, id, deposit)
+	return updateSessionDeposit(ctx, conn, id, deposit)
+}
+
+func updateSessionDeposit(ctx context.Context, conn executor, id uint64, deposit string) error {
+	_, err := conn.Exec(ctx, updateSessionDepositStmt, id, deposit)
 	return err
 }
 
@@ -271,13 +268,81 @@ func (r *GameSessionsPostgresRepo) UpdateSessionPlayerWin(ctx context.Context, i
 }
 
 func (r *GameSessionsPostgresRepo) UpdateSessionState(ctx context.Context, id uint64, newState models.GameSessionState) error {
+	return r.updateSessionState(ctx, id, nil, newState)
+}
+
+func (r *GameSessionsPostgresRepo) CompareAndSwapSessionState(
+	ctx context.Context,
+	id uint64,
+	version uint64,
+	newState models.GameSessionState,
+) error {
+	return r.updateSessionState(ctx, id, &version, newState)
+}
+
+// updateSessionState changes state if transition is legal and version matches expected one (if not nil)
+func (r *GameSessionsPostgresRepo) updateSessionState(
+	ctx context.Context,
+	id uint64,
+	expectedVersion *uint64,
+	newState models.GameSessionState,
+) error {
 	conn, err := db.DbPool.Acquire(ctx)
 	if err != nil {
 		return err
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, updateSessionStateStmt, id, uint16(newState), time.Now().Unix())
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.transitSessionState(ctx, tx, id, expectedVersion, newState)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	return err
+}
+
+// transitSessionState must be called within transaction, session row stays locked until it ends
+func (r *GameSessionsPostgresRepo) transitSessionState(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uint64,
+	expectedVersion *uint64,
+	newState models.GameSessionState,
+) error {
+	// lock session row, so state can't be changed between validation and update
+	var (
+		state   uint16
+		version uint64
+	)
+	err := tx.QueryRow(ctx, selectSessionStateForUpdateStmt, id).Scan(&state, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return gamesessions.ErrGameSessionNotFound
+		}
+		return err
+	}
+
+	if expectedVersion != nil && *expectedVersion != version {
+		return gamesessions.ErrSessionVersionConflict
+	}
+
+	if models.GameSessionState(state).IsRepeatedTerminal(newState) {
+		return nil
+	}
+
+	err = r.stateMachine.Transit(id, models.GameSessionState(state), newState)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, updateSessionStateStmt, id, uint16(newState), time.Now().Unix())
 	return err
 }
 
@@ -299,7 +364,11 @@ func (r *GameSessionsPostgresRepo) AddGameSession(ctx context.Context, ses *mode
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, insertGameSessionStmt,
+	return addGameSession(ctx, conn, ses)
+}
+
+func addGameSession(ctx context.Context, conn executor, ses *models.GameSession) error {
+	_, err := conn.Exec(ctx, insertGameSessionStmt,
 		ses.ID,
 		ses.Player,
 		ses.GameID,
@@ -312,6 +381,11 @@ func (r *GameSessionsPostgresRepo) AddGameSession(ctx context.Context, ses *mode
 		nil,
 		nil,
 	)
+	if pgErr, ok := err.(*pgconn.PgError); ok {
+		if pgErr.Code == sqlDuplicateUniqueErrorCode {
+			return gamesessions.ErrGameSessionAlreadyExists
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -338,33 +412,17 @@ func (r *GameSessionsPostgresRepo) AddFirstGameAction(ctx context.Context, sesID
 	return nil
 }
 
-func (r *GameSessionsPostgresRepo) GetUserGameSessions(ctx context.Context, accountName string) ([]*models.GameSession, error) {
-	conn, err := db.DbPool.Acquire(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Release()
-
-	rows, err := conn.Query(ctx, selectUserGameSessionsStmt, accountName)
-	if err != nil {
-		return nil, err
-	}
-
-	gameSessions := make([]*models.GameSession, 0)
-	for rows.Next() {
-		session := new(GameSession)
-		err = session.Scan(rows)
-		if err != nil {
-			return nil, err
-		}
-		ses, err := toModelGameSession(session)
-		if err != nil {
-			return nil, err
-		}
-		gameSessions = append(gameSessions, ses)
-	}
-
-	return gameSessions, nil
+func (r *GameSessionsPostgresRepo) GetUserGameSessions(
+	ctx context.Context,
+	accountName string,
+	query *gamesessions.SessionsQuery,
+) ([]*models.GameSession, error) {
+	b := new(sessionsQueryBuilder)
+	b.where("player = " + b.arg(accountName))
+	b.whereQuery(query)
+
+	stmt, args := b.build(query.PageLimit())
+	return r.selectSessions(ctx, stmt, args...)
 }
 
 func (r *GameSessionsPostgresRepo) GetAllGameSessions(ctx context.Context) ([]*models.GameSession, error) {
@@ -428,6 +486,7 @@ func toModelGameSession(gs *GameSession) (*models.GameSession, error) {
 		State:           models.GameSessionState(gs.State),
 		LastOffset:      gs.LastOffset,
 		LastUpdate:      gs.LastUpdate,
+		Version:         gs.Version,
 	}
 
 	if gs.Deposit == nil {
@@ -2,18 +2,56 @@ package postgres
 
 import (
 	"context"
-
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
 	"platform-backend/db"
+	"platform-backend/models"
+	"time"
 )
 
 const (
 	InsertGameSessionTransaction = `
         INSERT INTO game_session_txns 
-            (trx_id, ses_id, action_type, action_params)
+            (trx_id, ses_id, action_type, action_params, created, next_check)
         VALUES
-            ($1, $2, $3, $4)`
+            ($1, $2, $3, $4, $5, $5)`
+	CountGameSessionTransactions = "SELECT count(*) FROM game_session_txns WHERE ses_id = $1"
+
+	selectGameSessionTrxColumns       = "trx_id, ses_id, action_type, action_params, status, block_num, created, check_attempts, next_check"
+	selectGameSessionTransactionsStmt = "SELECT " + selectGameSessionTrxColumns + " FROM game_session_txns WHERE ses_id = $1 ORDER BY created"
+	selectPendingGameSessionTrxsStmt  = "SELECT " + selectGameSessionTrxColumns + " FROM game_session_txns " +
+		"WHERE status IN (0, 1) AND next_check <= $2 ORDER BY next_check LIMIT $1"
+	updateGameSessionTrxStatusStmt  = "UPDATE game_session_txns SET status = $2, block_num = $3 WHERE trx_id = $1"
+	postponeGameSessionTrxCheckStmt = "UPDATE game_session_txns " +
+		"SET check_attempts = check_attempts + 1, next_check = $2 WHERE trx_id = $1"
 )
 
+type GameSessionTrx struct {
+	TrxID         string              `db:"trx_id"`
+	SessionID     uint64              `db:"ses_id"`
+	ActionType    uint16              `db:"action_type"`
+	ActionParams  pgtype.NumericArray `db:"action_params"`
+	Status        int16               `db:"status"`
+	BlockNum      *int64              `db:"block_num"`
+	Created       int64               `db:"created"`
+	CheckAttempts int                 `db:"check_attempts"`
+	NextCheck     int64               `db:"next_check"`
+}
+
+func (t *GameSessionTrx) Scan(row pgx.Row) error {
+	return row.Scan(
+		&t.TrxID,
+		&t.SessionID,
+		&t.ActionType,
+		&t.ActionParams,
+		&t.Status,
+		&t.BlockNum,
+		&t.Created,
+		&t.CheckAttempts,
+		&t.NextCheck,
+	)
+}
+
 func (r *GameSessionsPostgresRepo) AddGameSessionTransaction(ctx context.Context, trxID string, sesID uint64,
 	actionType uint16, actionParams []uint64) error {
 	conn, err := db.DbPool.Acquire(ctx)
@@ -21,6 +59,120 @@ func (r *GameSessionsPostgresRepo) AddGameSessionTransaction(ctx context.Context
 		return err
 	}
 	defer conn.Release()
-	_, err = conn.Exec(ctx, InsertGameSessionTransaction, trxID, sesID, actionType, actionParams)
+	return addGameSessionTransaction(ctx, conn, trxID, sesID, actionType, actionParams)
+}
+
+func addGameSessionTransaction(ctx context.Context, conn executor, trxID string, sesID uint64,
+	actionType uint16, actionParams []uint64) error {
+	_, err := conn.Exec(ctx, InsertGameSessionTransaction, trxID, sesID, actionType, actionParams, time.Now().Unix())
+	return err
+}
+
+func (r *GameSessionsPostgresRepo) CountGameSessionTransactions(ctx context.Context, sesID uint64) (int, error) {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var cnt int
+	err = conn.QueryRow(ctx, CountGameSessionTransactions, sesID).Scan(&cnt)
+	return cnt, err
+}
+
+func (r *GameSessionsPostgresRepo) GetGameSessionTransactions(ctx context.Context, sesID uint64) ([]*models.GameSessionTrx, error) {
+	return r.selectGameSessionTrxs(ctx, selectGameSessionTransactionsStmt, sesID)
+}
+
+func (r *GameSessionsPostgresRepo) GetPendingGameSessionTransactions(ctx context.Context, limit int) ([]*models.GameSessionTrx, error) {
+	return r.selectGameSessionTrxs(ctx, selectPendingGameSessionTrxsStmt, limit, time.Now().Unix())
+}
+
+func (r *GameSessionsPostgresRepo) UpdateGameSessionTransactionStatus(
+	ctx context.Context,
+	trxID string,
+	status models.GameSessionTrxStatus,
+	blockNum *uint32,
+) error {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	var block *int64
+	if blockNum != nil {
+		num := int64(*blockNum)
+		block = &num
+	}
+	_, err = conn.Exec(ctx, updateGameSessionTrxStatusStmt, trxID, int16(status), block)
+	return err
+}
+
+func (r *GameSessionsPostgresRepo) PostponeGameSessionTransactionCheck(
+	ctx context.Context,
+	trxID string,
+	delay time.Duration,
+) error {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, postponeGameSessionTrxCheckStmt, trxID, time.Now().Add(delay).Unix())
 	return err
 }
+
+func (r *GameSessionsPostgresRepo) selectGameSessionTrxs(
+	ctx context.Context,
+	stmt string,
+	args ...interface{},
+) ([]*models.GameSessionTrx, error) {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trxs := make([]*models.GameSessionTrx, 0)
+	for rows.Next() {
+		trx := new(GameSessionTrx)
+		if err := trx.Scan(rows); err != nil {
+			return nil, err
+		}
+		modelTrx, err := toModelGameSessionTrx(trx)
+		if err != nil {
+			return nil, err
+		}
+		trxs = append(trxs, modelTrx)
+	}
+
+	return trxs, rows.Err()
+}
+
+func toModelGameSessionTrx(t *GameSessionTrx) (*models.GameSessionTrx, error) {
+	trx := &models.GameSessionTrx{
+		TrxID:         t.TrxID,
+		SessionID:     t.SessionID,
+		ActionType:    t.ActionType,
+		Status:        models.GameSessionTrxStatus(t.Status),
+		Created:       t.Created,
+		CheckAttempts: t.CheckAttempts,
+		NextCheck:     t.NextCheck,
+	}
+	if err := t.ActionParams.AssignTo(&trx.ActionParams); err != nil {
+		return nil, err
+	}
+	if t.BlockNum != nil {
+		blockNum := uint32(*t.BlockNum)
+		trx.BlockNum = &blockNum
+	}
+	return trx, nil
+}
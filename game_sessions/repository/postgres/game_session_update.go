@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"context"
-	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"platform-backend/db"
 	gamesessions "platform-backend/game_sessions"
@@ -12,10 +11,23 @@ import (
 
 const (
 	selectGameSessionUpdatesByIdStmt = "SELECT * FROM game_session_updates WHERE ses_id = $1 ORDER BY timestamp ASC"
-	insertGameSessionUpdateStmt      = "INSERT INTO game_session_updates VALUES ($1, $2, $3, $4, $5)"
+	// updates without offset (created by backend itself) are placed by timestamp
+	selectGameSessionUpdatesSinceStmt = `
+        SELECT * FROM game_session_updates
+        WHERE ses_id = $1 AND (
+            "offset" > $2 OR (
+                "offset" IS NULL AND timestamp > (
+                    SELECT COALESCE(MAX(timestamp), '-infinity') FROM game_session_updates
+                    WHERE ses_id = $1 AND "offset" <= $2
+                )
+            )
+        )
+        ORDER BY timestamp ASC`
+	selectLastGameSessionUpdateOffsetStmt = `
+        SELECT MAX("offset") FROM game_session_updates
+        WHERE ses_id = $1 AND ($2::NUMERIC IS NULL OR "offset" <= $2)`
+	insertGameSessionUpdateStmt      = `INSERT INTO game_session_updates VALUES ($1, $2, $3, $4, $5) ON CONFLICT ("offset") DO NOTHING`
 	deleteGameSessionUpdatesByIdStmt = "DELETE FROM game_session_updates WHERE ses_id = $1"
-
-	sqlDuplicateUniqueErrorCode = "23505"
 )
 
 type GameSessionUpdate struct {
@@ -63,6 +75,49 @@ func (r *GameSessionsPostgresRepo) GetGameSessionUpdates(ctx context.Context, id
 	return sessionUpdates, nil
 }
 
+func (r *GameSessionsPostgresRepo) GetGameSessionUpdatesSince(ctx context.Context, id uint64, offset uint64) ([]*models.GameSessionUpdate, error) {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, selectGameSessionUpdatesSinceStmt, id, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessionUpdates := make([]*models.GameSessionUpdate, 0)
+
+	for rows.Next() {
+		upd := new(GameSessionUpdate)
+		err := upd.Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessionUpdates = append(sessionUpdates, toModelGameSessionUpdate(upd))
+	}
+
+	return sessionUpdates, nil
+}
+
+func (r *GameSessionsPostgresRepo) GetLastGameSessionUpdateOffset(ctx context.Context, id uint64, maxOffset *uint64) (*uint64, error) {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var offset *uint64
+	err = conn.QueryRow(ctx, selectLastGameSessionUpdateOffsetStmt, id, maxOffset).Scan(&offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return offset, nil
+}
+
 func (r *GameSessionsPostgresRepo) AddGameSessionUpdate(ctx context.Context, upd *models.GameSessionUpdate) error {
 	conn, err := db.DbPool.Acquire(ctx)
 	if err != nil {
@@ -70,13 +125,19 @@ func (r *GameSessionsPostgresRepo) AddGameSessionUpdate(ctx context.Context, upd
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, insertGameSessionUpdateStmt, upd.SessionID, upd.UpdateType, upd.Timestamp, upd.Data, upd.Offset)
-	if pgErr, ok := err.(*pgconn.PgError); ok {
-		if pgErr.Code == sqlDuplicateUniqueErrorCode {
-			return gamesessions.ErrUpdateAlreadyProcessed
-		}
+	return addGameSessionUpdate(ctx, conn, upd)
+}
+
+// addGameSessionUpdate doesn't fail on duplicated offset, so it can be used within transaction
+func addGameSessionUpdate(ctx context.Context, conn executor, upd *models.GameSessionUpdate) error {
+	tag, err := conn.Exec(ctx, insertGameSessionUpdateStmt, upd.SessionID, upd.UpdateType, upd.Timestamp, upd.Data, upd.Offset)
+	if err != nil {
+		return err
 	}
-	return err
+	if tag.RowsAffected() == 0 {
+		return gamesessions.ErrUpdateAlreadyProcessed
+	}
+	return nil
 }
 
 func (r *GameSessionsPostgresRepo) DeleteGameSessionUpdates(ctx context.Context, sesId uint64) error {
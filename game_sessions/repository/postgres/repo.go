@@ -1,13 +1,18 @@
 package postgres
 
-import "github.com/jackc/pgx/v4/pgxpool"
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	gamesessions "platform-backend/game_sessions"
+)
 
 type GameSessionsPostgresRepo struct {
-	dbPool *pgxpool.Pool
+	dbPool       *pgxpool.Pool
+	stateMachine *gamesessions.StateMachine
 }
 
-func NewGameSessionsPostgresRepo(dbPool *pgxpool.Pool) *GameSessionsPostgresRepo {
+func NewGameSessionsPostgresRepo(dbPool *pgxpool.Pool, stateMachine *gamesessions.StateMachine) *GameSessionsPostgresRepo {
 	return &GameSessionsPostgresRepo{
-		dbPool: dbPool,
+		dbPool:       dbPool,
+		stateMachine: stateMachine,
 	}
 }
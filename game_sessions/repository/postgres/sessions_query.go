@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"errors"
+	gamesessions "platform-backend/game_sessions"
+	"strconv"
+	"strings"
+)
+
+const (
+	selectSessionsStmt = "SELECT * FROM game_sessions"
+	sessionsOrderStmt  = "ORDER BY last_update DESC, id DESC"
+
+	winsCondition  = "player_win_amount NOT SIMILAR TO '(-%|0.0000 %)'"
+	lostsCondition = "player_win_amount SIMILAR TO '-%'"
+)
+
+// sessionsQueryBuilder builds keyset paginated sessions select
+type sessionsQueryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (b *sessionsQueryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *sessionsQueryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+func (b *sessionsQueryBuilder) whereFilter(filter gamesessions.FilterType) error {
+	switch filter {
+	case gamesessions.All:
+	case gamesessions.Wins:
+		b.where(winsCondition)
+	case gamesessions.Losts:
+		b.where(lostsCondition)
+	default:
+		return errors.New("bad filter")
+	}
+	return nil
+}
+
+func (b *sessionsQueryBuilder) whereQuery(query *gamesessions.SessionsQuery) {
+	if query == nil {
+		return
+	}
+	if query.Cursor != nil {
+		b.where("(last_update, id) < (" + b.arg(query.Cursor.LastUpdate) + ", " + b.arg(query.Cursor.ID) + ")")
+	}
+	if query.GameID != nil {
+		b.where("game_id = " + b.arg(*query.GameID))
+	}
+	if query.CasinoID != nil {
+		b.where("casino_id = " + b.arg(*query.CasinoID))
+	}
+	if query.State != nil {
+		b.where("state = " + b.arg(uint16(*query.State)))
+	}
+	if query.From != nil {
+		b.where("last_update >= " + b.arg(*query.From))
+	}
+	if query.To != nil {
+		b.where("last_update <= " + b.arg(*query.To))
+	}
+}
+
+func (b *sessionsQueryBuilder) build(limit int) (string, []interface{}) {
+	stmt := selectSessionsStmt
+	if len(b.conditions) > 0 {
+		stmt += " WHERE " + strings.Join(b.conditions, " AND ")
+	}
+	stmt += " " + sessionsOrderStmt + " LIMIT " + b.arg(limit)
+	return stmt, b.args
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"github.com/stretchr/testify/assert"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"testing"
+)
+
+func TestSessionsQueryBuilder(t *testing.T) {
+	var (
+		gameID = uint64(2)
+		state  = models.GameFinished
+		from   = int64(100)
+	)
+
+	b := new(sessionsQueryBuilder)
+	b.where("player = " + b.arg("testuser"))
+	assert.NoError(t, b.whereFilter(gamesessions.Wins))
+	b.whereQuery(&gamesessions.SessionsQuery{
+		Cursor: &gamesessions.SessionsCursor{LastUpdate: 200, ID: 10},
+		GameID: &gameID,
+		State:  &state,
+		From:   &from,
+	})
+
+	stmt, args := b.build(30)
+	assert.Equal(t, "SELECT * FROM game_sessions WHERE player = $1 AND "+winsCondition+
+		" AND (last_update, id) < ($2, $3) AND game_id = $4 AND state = $5 AND last_update >= $6"+
+		" ORDER BY last_update DESC, id DESC LIMIT $7", stmt)
+	assert.Equal(t, []interface{}{"testuser", int64(200), uint64(10), gameID, uint16(state), from, 30}, args)
+
+	assert.Error(t, new(sessionsQueryBuilder).whereFilter("unknown"))
+}
+
+func TestSessionsQueryLimit(t *testing.T) {
+	var query *gamesessions.SessionsQuery
+	assert.Equal(t, gamesessions.DefaultSessionsLimit, query.PageLimit())
+	assert.Equal(t, 5, (&gamesessions.SessionsQuery{Limit: 5}).PageLimit())
+	assert.Equal(t, gamesessions.MaxSessionsLimit, (&gamesessions.SessionsQuery{Limit: 1000}).PageLimit())
+}
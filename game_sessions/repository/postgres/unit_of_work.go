@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"github.com/eoscanada/eos-go"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"platform-backend/db"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"platform-backend/utils"
+)
+
+// executor is implemented by both pooled connection and transaction
+type executor interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// querier is implemented by both pooled connection and transaction
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// unitOfWork applies session writes within single transaction
+type unitOfWork struct {
+	repo *GameSessionsPostgresRepo
+	conn *pgxpool.Conn
+	tx   pgx.Tx
+}
+
+func (r *GameSessionsPostgresRepo) BeginUnitOfWork(ctx context.Context) (gamesessions.UnitOfWork, error) {
+	conn, err := db.DbPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return &unitOfWork{repo: r, conn: conn, tx: tx}, nil
+}
+
+func (u *unitOfWork) AddGameSessionUpdate(ctx context.Context, upd *models.GameSessionUpdate) error {
+	return addGameSessionUpdate(ctx, u.tx, upd)
+}
+
+func (u *unitOfWork) UpdateSessionState(ctx context.Context, id uint64, state models.GameSessionState) error {
+	return u.repo.transitSessionState(ctx, u.tx, id, nil, state)
+}
+
+func (u *unitOfWork) CompareAndSwapSessionState(ctx context.Context, id uint64, version uint64,
+	state models.GameSessionState) error {
+	return u.repo.transitSessionState(ctx, u.tx, id, &version, state)
+}
+
+func (u *unitOfWork) UpdateSessionStateBeforeFail(ctx context.Context, id uint64, prevState models.GameSessionState) error {
+	_, err := u.tx.Exec(ctx, updateSessionStateBeforeFailStmt, id, uint16(prevState))
+	return err
+}
+
+func (u *unitOfWork) UpdateSessionPlayerWin(ctx context.Context, id uint64, playerWin string) error {
+	_, err := u.tx.Exec(ctx, updateSessionPlayerWinStmt, id, playerWin)
+	return err
+}
+
+func (u *unitOfWork) UpdateSessionOffset(ctx context.Context, id uint64, offset uint64) error {
+	_, err := u.tx.Exec(ctx, updateSessionOffsetStmt, id, offset)
+	return err
+}
+
+func (u *unitOfWork) DeleteFirstGameAction(ctx context.Context, sesID uint64) error {
+	_, err := u.tx.Exec(ctx, deleteFirstGameActionStmt, sesID)
+	return err
+}
+
+func (u *unitOfWork) AddGameSession(ctx context.Context, ses *models.GameSession) error {
+	return addGameSession(ctx, u.tx, ses)
+}
+
+func (u *unitOfWork) AddSessionDeposit(ctx context.Context, id uint64, deposit eos.Asset) error {
+	var current string
+	if err := u.tx.QueryRow(ctx, selectSessionDepositForUpdateStmt, id).Scan(&current); err != nil {
+		if err == pgx.ErrNoRows {
+			return gamesessions.ErrGameSessionNotFound
+		}
+		return err
+	}
+	currentAsset, err := utils.ToBetAsset(current)
+	if err != nil {
+		return err
+	}
+	return updateSessionDeposit(ctx, u.tx, id, currentAsset.Add(deposit).String())
+}
+
+func (u *unitOfWork) AddGameSessionTransaction(ctx context.Context, trxID string, sesID uint64,
+	actionType uint16, actionParams []uint64) error {
+	return addGameSessionTransaction(ctx, u.tx, trxID, sesID, actionType, actionParams)
+}
+
+func (u *unitOfWork) AddCasinoTrx(ctx context.Context, trx *models.CasinoTrx) error {
+	return addCasinoTrx(ctx, u.tx, trx)
+}
+
+func (u *unitOfWork) UpdateCasinoTrxSent(ctx context.Context, id uint64, trxID string) error {
+	_, err := u.tx.Exec(ctx, updateCasinoTrxSentStmt, id, trxID)
+	return err
+}
+
+func (u *unitOfWork) AddCasinoWebhook(ctx context.Context, webhook *models.CasinoWebhook) error {
+	return addCasinoWebhook(ctx, u.tx, webhook)
+}
+
+func (u *unitOfWork) Commit(ctx context.Context) error {
+	defer u.release()
+	return u.tx.Commit(ctx)
+}
+
+func (u *unitOfWork) Rollback(ctx context.Context) error {
+	defer u.release()
+	err := u.tx.Rollback(ctx)
+	if err == pgx.ErrTxClosed {
+		return nil
+	}
+	return err
+}
+
+func (u *unitOfWork) release() {
+	if u.conn != nil {
+		u.conn.Release()
+		u.conn = nil
+	}
+}
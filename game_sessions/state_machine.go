@@ -0,0 +1,44 @@
+package gamesessions
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"platform-backend/models"
+	"strconv"
+)
+
+// StateMachine validates session state changes against models transition table
+type StateMachine struct {
+	illegalTransitionCounter *prometheus.CounterVec
+}
+
+// NewStateMachine creates state machine, metrics aren't registered if reg is nil
+func NewStateMachine(reg prometheus.Registerer) *StateMachine {
+	illegalTransitionCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "illegal_session_transition",
+			Help: "Rejected session state changes by from and to state",
+		}, []string{"from", "to"},
+	)
+
+	if reg != nil {
+		reg.MustRegister(illegalTransitionCounter)
+	}
+
+	return &StateMachine{
+		illegalTransitionCounter: illegalTransitionCounter,
+	}
+}
+
+// Transit returns ErrIllegalStateTransition if session can't move from one state to another
+func (m *StateMachine) Transit(sessionID uint64, from, to models.GameSessionState) error {
+	if from.CanTransitTo(to) {
+		return nil
+	}
+
+	m.illegalTransitionCounter.WithLabelValues(strconv.Itoa(int(from)), strconv.Itoa(int(to))).Inc()
+	log.Warn().Msgf("Rejected illegal session state transition, sessionID: %d, from: %d, to: %d",
+		sessionID, from, to)
+
+	return ErrIllegalStateTransition
+}
@@ -0,0 +1,20 @@
+package gamesessions
+
+import (
+	"platform-backend/models"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateMachineTransit(t *testing.T) {
+	m := NewStateMachine(prometheus.NewRegistry())
+
+	assert.NoError(t, m.Transit(1, models.RequestedGameAction, models.GameActionTrxSent))
+	assert.Equal(t, ErrIllegalStateTransition, m.Transit(1, models.GameActionTrxSent, models.GameActionTrxSent))
+	assert.Equal(t, ErrIllegalStateTransition, m.Transit(1, models.SignidicePartOneTrxSent, models.SignidicePartOneTrxSent))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.illegalTransitionCounter.WithLabelValues("3", "3")))
+}
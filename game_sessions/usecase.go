@@ -6,6 +6,27 @@ import (
 	"time"
 )
 
+type RecoveryAction string
+
+const (
+	// session is driven further by events or outbox
+	RecoveryResumed RecoveryAction = "resumed"
+	// lost transaction is sent again
+	RecoveryRedriven RecoveryAction = "redriven"
+	// session can't be continued
+	RecoveryFailed RecoveryAction = "failed"
+	// session couldn't be inspected
+	RecoverySkipped RecoveryAction = "skipped"
+)
+
+// RecoveryReport describes what recovery did with a stuck session
+type RecoveryReport struct {
+	SessionID uint64
+	State     models.GameSessionState
+	Action    RecoveryAction
+	Details   string
+}
+
 type UseCase interface {
 	CleanExpiredSessions(
 		ctx context.Context,
@@ -29,6 +50,15 @@ type UseCase interface {
 		actionParams []uint64,
 	) error
 
+	// PushGameAction sends game action trx of session which is already moved to GameActionTrxSent state,
+	// if trx isn't sent, action is requested from player again
+	PushGameAction(
+		ctx context.Context,
+		session *models.GameSession,
+		actionType uint16,
+		actionParams []uint64,
+	) error
+
 	GameActionWithDeposit(
 		ctx context.Context,
 		sessionId uint64,
@@ -36,4 +66,21 @@ type UseCase interface {
 		actionParams []uint64,
 		deposit string,
 	) error
+
+	// RecoverSessions inspects sessions left in intermediate states after restart
+	RecoverSessions(ctx context.Context) ([]*RecoveryReport, error)
+
+	// RunCasinoTrxOutbox sends casino signed transactions until ctx is done
+	RunCasinoTrxOutbox(ctx context.Context) error
+
+	// RunCasinoWebhooks delivers queued webhooks to casinos until ctx is done
+	RunCasinoWebhooks(ctx context.Context) error
+	GetCasinoWebhooks(ctx context.Context, query *CasinoWebhooksQuery) ([]*models.CasinoWebhook, error)
+	// RequeueCasinoWebhook schedules webhook delivery again with reset attempts
+	RequeueCasinoWebhook(ctx context.Context, id uint64) error
+
+	// RunTrxTracker updates finality status of pushed session transactions until ctx is done
+	RunTrxTracker(ctx context.Context) error
+
+	GetSessionTransactions(ctx context.Context, sessionId uint64) ([]*models.GameSessionTrx, error)
 }
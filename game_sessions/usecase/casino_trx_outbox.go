@@ -0,0 +1,463 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"platform-backend/blockchain"
+	casinoclient "platform-backend/casino_client"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"platform-backend/utils"
+	"sync"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/rs/zerolog/log"
+)
+
+// claimed outbox record isn't claimed by other workers until lease expires,
+// so lease should be greater than the time of single attempt
+const casinoTrxLease = time.Minute
+
+var (
+	// errBrokenCasinoTrx is returned for outbox record which can't be sent anyway
+	errBrokenCasinoTrx = errors.New("broken casino trx record")
+	// errCasinoTrxInFlight is returned while transaction signed by previous attempt can be still executed,
+	// such record is retried regardless of attempts count
+	errCasinoTrxInFlight = errors.New("casino trx can be still executed")
+)
+
+// isRetryableCasinoTrxErr reports whether casino trx attempt can be retried,
+// transaction rejected by casino or blockchain is never retried
+func isRetryableCasinoTrxErr(err error) bool {
+	var casinoErr *casinoclient.Error
+	if errors.As(err, &casinoErr) {
+		return casinoclient.IsRetryableError(err)
+	}
+	var trxErr *blockchain.TrxError
+	if errors.As(err, &trxErr) {
+		return trxErr.Retryable
+	}
+	return !errors.Is(err, errBrokenCasinoTrx)
+}
+
+// newCasinoTrx builds outbox record of casino signed transaction,
+// trx session id is taken from session, so it must be set before
+func newCasinoTrx(
+	kind models.CasinoTrxKind,
+	session *models.GameSession,
+	actions []*eos.Action,
+	actionType uint16,
+	actionParams []uint64,
+	deposit *eos.Asset,
+) (*models.CasinoTrx, error) {
+	// header is filled when transaction is signed, so store transaction without it
+	packed, err := eos.MarshalBinary(eos.NewTransaction(actions, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CasinoTrx{
+		SessionID:    session.ID,
+		CasinoID:     session.CasinoID,
+		Kind:         kind,
+		Trx:          packed,
+		ActionType:   actionType,
+		ActionParams: actionParams,
+		Deposit:      deposit,
+	}, nil
+}
+
+// enqueueCasinoTrx stores casino signed transaction to the outbox and wakes up outbox workers
+func (a *GameSessionsUseCase) enqueueCasinoTrx(
+	ctx context.Context,
+	kind models.CasinoTrxKind,
+	session *models.GameSession,
+	actions []*eos.Action,
+	actionType uint16,
+	actionParams []uint64,
+	deposit *eos.Asset,
+) error {
+	trx, err := newCasinoTrx(kind, session, actions, actionType, actionParams, deposit)
+	if err != nil {
+		return err
+	}
+	if err := a.repo.AddCasinoTrx(ctx, trx); err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Casino trx enqueued, sessionID: %d, outboxID: %d", session.ID, trx.ID)
+
+	a.wakeupCasinoTrxOutbox()
+	return nil
+}
+
+func (a *GameSessionsUseCase) wakeupCasinoTrxOutbox() {
+	select {
+	case a.outboxWakeup <- struct{}{}:
+	default:
+	}
+}
+
+// RunCasinoTrxOutbox sends outbox transactions to casinos until ctx is done
+func (a *GameSessionsUseCase) RunCasinoTrxOutbox(ctx context.Context) error {
+	workers := a.outboxConfig.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	trxs := make(chan *models.CasinoTrx)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for trx := range trxs {
+				a.processCasinoTrx(ctx, trx)
+			}
+		}()
+	}
+	defer func() {
+		close(trxs)
+		wg.Wait()
+	}()
+
+	log.Info().Msgf("Casino trx outbox is started with %d workers", workers)
+
+	ticker := time.NewTicker(time.Duration(a.outboxConfig.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := a.repo.ClaimCasinoTrxs(ctx, workers, casinoTrxLease)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Msgf("Casino trx outbox claim error: %s", err.Error())
+		}
+
+		for _, trx := range claimed {
+			select {
+			case trxs <- trx:
+			case <-ctx.Done():
+				log.Info().Msg("Casino trx outbox is stopped")
+				return nil
+			}
+		}
+
+		// more records can be ready, don't wait for the next tick
+		if len(claimed) == workers {
+			continue
+		}
+
+		select {
+		case <-ticker.C:
+		case <-a.outboxWakeup:
+		case <-ctx.Done():
+			log.Info().Msg("Casino trx outbox is stopped")
+			return nil
+		}
+	}
+}
+
+func (a *GameSessionsUseCase) processCasinoTrx(ctx context.Context, trx *models.CasinoTrx) {
+	session, err := a.repo.GetGameSession(ctx, trx.SessionID)
+	if err != nil {
+		a.retryCasinoTrx(ctx, trx, err)
+		return
+	}
+
+	if session.State == models.GameFailed || session.State == models.GameFinished {
+		log.Info().Msgf("Casino trx dropped, session is already over, sessionID: %d, outboxID: %d", session.ID, trx.ID)
+		if err := a.repo.UpdateCasinoTrxFailed(ctx, trx.ID, "session is over"); err != nil {
+			log.Error().Msgf("Failed to mark casino trx failed, outboxID: %d, reason: %s", trx.ID, err.Error())
+		}
+		return
+	}
+
+	trxID, err := a.sendCasinoTrx(ctx, trx)
+	if err != nil {
+		log.Info().Msgf("Casino trx attempt %d failed, sessionID: %d, outboxID: %d, error: %s",
+			trx.Attempts, session.ID, trx.ID, err.Error())
+		if errors.Is(err, errCasinoTrxInFlight) {
+			a.retryCasinoTrx(ctx, trx, err)
+			return
+		}
+		if !isRetryableCasinoTrxErr(err) || trx.Attempts >= a.outboxConfig.MaxAttempts {
+			a.failCasinoTrx(ctx, trx, session, err)
+			return
+		}
+		a.retryCasinoTrx(ctx, trx, err)
+		return
+	}
+
+	log.Info().Msgf("Successfully sent casino trx, sessionID: %d, trxID: %s", session.ID, trxID)
+
+	if err := a.completeCasinoTrx(ctx, trx, session, trxID); err != nil {
+		// record is claimed again after lease expiration, sent transaction isn't sent twice
+		log.Error().Msgf("Failed to complete casino trx, "+
+			"sessionID: %d, outboxID: %d, trxID: %s, reason: %s", session.ID, trx.ID, trxID, err.Error())
+	}
+}
+
+// completeCasinoTrx marks outbox record sent, adds deposit to the session and stores transaction atomically
+func (a *GameSessionsUseCase) completeCasinoTrx(
+	ctx context.Context,
+	trx *models.CasinoTrx,
+	session *models.GameSession,
+	trxID string,
+) error {
+	uow, err := a.repo.BeginUnitOfWork(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = uow.Rollback(ctx)
+	}()
+
+	if err := uow.UpdateCasinoTrxSent(ctx, trx.ID, trxID); err != nil {
+		return err
+	}
+	if trx.Deposit != nil {
+		if err := uow.AddSessionDeposit(ctx, session.ID, *trx.Deposit); err != nil {
+			return err
+		}
+	}
+	if err := uow.AddGameSessionTransaction(ctx, trxID, session.ID, trx.ActionType, trx.ActionParams); err != nil {
+		return err
+	}
+	return uow.Commit(ctx)
+}
+
+// sendCasinoTrx sends outbox transaction to casino and returns its id,
+// signed transaction is stored before it's sent, so it's sent again as is until it's expired
+// and deposit is never pushed twice by transactions with different ids
+func (a *GameSessionsUseCase) sendCasinoTrx(ctx context.Context, trx *models.CasinoTrx) (string, error) {
+	casino, err := a.contractsRepo.GetCasino(ctx, trx.CasinoID)
+	if err != nil {
+		return "", err
+	}
+	if err := casinoclient.CheckConfigured(casino); err != nil {
+		return "", err
+	}
+
+	if trx.SignedTrx != nil {
+		signedTrx, executed, err := a.checkSignedCasinoTrx(trx)
+		if err != nil {
+			return "", err
+		}
+		if executed {
+			log.Info().Msgf("Casino trx is already executed, outboxID: %d, trxID: %s", trx.ID, trx.TrxID)
+			return trx.TrxID, nil
+		}
+		if signedTrx != nil {
+			// casino can reject transaction it has already pushed, so its outcome is known only from blockchain
+			if err := a.casinoClient.SignTransaction(ctx, casino, signedTrx); err != nil {
+				return "", fmt.Errorf("%w: %s", errCasinoTrxInFlight, err)
+			}
+			return trx.TrxID, nil
+		}
+	}
+
+	signedTrx, err := a.signCasinoTrx(ctx, trx)
+	if err != nil {
+		return "", err
+	}
+	if err := a.casinoClient.SignTransaction(ctx, casino, signedTrx); err != nil {
+		return "", err
+	}
+	return trx.TrxID, nil
+}
+
+// checkSignedCasinoTrx looks up transaction signed by previous attempt:
+// executed one isn't sent again, one which can be still executed is returned to be sent again as is,
+// nil is returned if it can't be executed anymore, so the new one should be signed
+func (a *GameSessionsUseCase) checkSignedCasinoTrx(trx *models.CasinoTrx) (*eos.SignedTransaction, bool, error) {
+	signedTrx := new(eos.SignedTransaction)
+	if err := eos.UnmarshalBinary(trx.SignedTrx, signedTrx); err != nil {
+		return nil, false, fmt.Errorf("%w: %s", errBrokenCasinoTrx, err)
+	}
+
+	lib, err := a.bc.GetIrreversibleBlock()
+	if err != nil {
+		return nil, false, err
+	}
+	status, _, err := a.bc.GetTransactionStatus(trx.TrxID, lib)
+	switch {
+	case err == nil:
+		return nil, status != models.TrxDropped, nil
+	case err != blockchain.ErrTrxNotFound:
+		return nil, false, err
+	}
+
+	expiration := signedTrx.Expiration.Time
+	if lib.IsExpirationPassed(expiration) {
+		return nil, false, nil
+	}
+	if time.Now().Before(expiration) {
+		return signedTrx, false, nil
+	}
+	// expired by local clock, but it can be still included to a reversible block
+	return nil, false, errCasinoTrxInFlight
+}
+
+// signCasinoTrx fills header of outbox transaction, signs it and stores signed one before it's sent
+func (a *GameSessionsUseCase) signCasinoTrx(ctx context.Context, trx *models.CasinoTrx) (*eos.SignedTransaction, error) {
+	tx := new(eos.Transaction)
+	if err := eos.UnmarshalBinary(trx.Trx, tx); err != nil {
+		// broken record can't be sent anyway
+		return nil, fmt.Errorf("%w: %s", errBrokenCasinoTrx, err)
+	}
+	for _, action := range tx.Actions {
+		action.SetToServer(true)
+	}
+
+	txOpts := a.bc.GetTrxOpts()
+	if err := txOpts.FillFromChain(a.bc.Api); err != nil {
+		return nil, fmt.Errorf("filling tx opts: %s", err)
+	}
+	tx.Fill(txOpts.HeadBlockID, txOpts.DelaySecs, txOpts.MaxNetUsageWords, txOpts.MaxCPUUsageMS)
+	tx.SetExpiration(blockchain.TrxExpiration)
+
+	signedTrx, trxID, err := a.signTrxForCasino(tx)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := eos.MarshalBinary(signedTrx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.repo.UpdateCasinoTrxSigned(ctx, trx.ID, trxID, packed); err != nil {
+		return nil, err
+	}
+	trx.TrxID = trxID
+	trx.SignedTrx = packed
+	return signedTrx, nil
+}
+
+func (a *GameSessionsUseCase) retryCasinoTrx(ctx context.Context, trx *models.CasinoTrx, cause error) {
+	delay := utils.Backoff(
+		time.Duration(a.outboxConfig.MinBackoff)*time.Second,
+		time.Duration(a.outboxConfig.MaxBackoff)*time.Second,
+		trx.Attempts,
+	)
+	if err := a.repo.UpdateCasinoTrxRetry(ctx, trx.ID, delay, cause.Error()); err != nil {
+		// record will be claimed again after lease expiration
+		log.Error().Msgf("Failed to schedule casino trx retry, outboxID: %d, reason: %s", trx.ID, err.Error())
+	}
+}
+
+func (a *GameSessionsUseCase) failCasinoTrx(
+	ctx context.Context,
+	trx *models.CasinoTrx,
+	session *models.GameSession,
+	cause error,
+) {
+	if err := a.repo.UpdateCasinoTrxFailed(ctx, trx.ID, cause.Error()); err != nil {
+		log.Error().Msgf("Failed to mark casino trx failed, outboxID: %d, reason: %s", trx.ID, err.Error())
+	}
+	if err := a.failSession(ctx, session, cause); err != nil {
+		log.Error().Msgf("Failed to fail session of casino trx, sessionID: %d, reason: %s", session.ID, err.Error())
+	}
+}
+
+// failedUpdateData is data of failed update added by backend,
+// it's the only way casino errors reach players, ws requests don't wait for casino
+type failedUpdateData struct {
+	Details string `json:"details"`
+	// set only if session is failed because of casino error
+	CasinoError  string `json:"casinoError,omitempty"`
+	CasinoStatus int    `json:"casinoStatus,omitempty"`
+	// whether the failed request could succeed if done again
+	Retryable bool `json:"retryable"`
+}
+
+func newFailedUpdateData(cause error) *failedUpdateData {
+	data := &failedUpdateData{
+		Details:   cause.Error(),
+		Retryable: isRetryableCasinoTrxErr(cause),
+	}
+	var casinoErr *casinoclient.Error
+	if errors.As(cause, &casinoErr) {
+		data.CasinoError = casinoErr.Code()
+		data.CasinoStatus = casinoErr.Status
+	}
+	return data
+}
+
+// failSession moves session to failed state and notifies player,
+// session changed since it was read isn't failed, ErrSessionVersionConflict is returned then
+func (a *GameSessionsUseCase) failSession(ctx context.Context, session *models.GameSession, cause error) error {
+	unlock := a.sessionLocks.lock(session.ID)
+	defer unlock()
+
+	failedUpdate, err := a.storeFailedSession(ctx, session, cause)
+	if err != nil {
+		return err
+	}
+
+	a.notifyBackendUpdate(ctx, session, failedUpdate)
+	return nil
+}
+
+// notifyBackendUpdate notifies player about update added by backend without offset,
+// client can detect missed ones by previous offset
+func (a *GameSessionsUseCase) notifyBackendUpdate(
+	ctx context.Context,
+	session *models.GameSession,
+	update *models.GameSessionUpdate,
+) {
+	prevOffset, err := a.repo.GetLastGameSessionUpdateOffset(ctx, session.ID, nil)
+	if err != nil {
+		log.Error().Msgf("Error fetching session last update offset: %s", err.Error())
+		return
+	}
+	updateMsgs := models.ToGameSessionUpdateMsgs([]*models.GameSessionUpdate{update}, prevOffset)
+	// synchronous call keeps order with updates of event processor
+	a.subsUseCase.Notify(session.Player, "session_update", updateMsgs)
+}
+
+// storeFailedSession fails session if its version isn't changed,
+// state before fail, failed update and webhook are stored atomically
+func (a *GameSessionsUseCase) storeFailedSession(
+	ctx context.Context,
+	session *models.GameSession,
+	cause error,
+) (*models.GameSessionUpdate, error) {
+	failedUpdateData, err := json.Marshal(newFailedUpdateData(cause))
+	if err != nil {
+		return nil, err
+	}
+	failedUpdate := &models.GameSessionUpdate{
+		SessionID:  session.ID,
+		UpdateType: models.GameFailedUpdate,
+		Timestamp:  time.Now(),
+		Data:       failedUpdateData,
+		Offset:     nil,
+	}
+
+	uow, err := a.repo.BeginUnitOfWork(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = uow.Rollback(ctx)
+	}()
+
+	if err := uow.CompareAndSwapSessionState(ctx, session.ID, session.Version, models.GameFailed); err != nil {
+		return nil, err
+	}
+	if err := uow.UpdateSessionStateBeforeFail(ctx, session.ID, session.State); err != nil {
+		return nil, err
+	}
+	if err := uow.AddGameSessionUpdate(ctx, failedUpdate); err != nil {
+		return nil, err
+	}
+	if err := gamesessions.AddCasinoWebhook(ctx, uow, a.contractsRepo, models.WebhookSessionFailed, session, nil,
+		cause.Error()); err != nil {
+		return nil, err
+	}
+	if err := uow.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return failedUpdate, nil
+}
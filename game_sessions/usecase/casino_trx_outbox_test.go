@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"platform-backend/blockchain"
+	casinoclient "platform-backend/casino_client"
+	"platform-backend/models"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFailedUpdateData(t *testing.T) {
+	data := newFailedUpdateData(&casinoclient.Error{
+		Kind:      casinoclient.ErrCasinoRejected,
+		Retryable: false,
+		Status:    400,
+		Err:       errors.New("bad trx"),
+	})
+	assert.Equal(t, "casino_rejected", data.CasinoError)
+	assert.Equal(t, 400, data.CasinoStatus)
+	assert.False(t, data.Retryable)
+
+	data = newFailedUpdateData(&casinoclient.Error{
+		Kind:      casinoclient.ErrCasinoUnavailable,
+		Retryable: true,
+		Err:       errors.New("timeout"),
+	})
+	assert.Equal(t, "casino_unavailable", data.CasinoError)
+	assert.True(t, data.Retryable)
+
+	data = newFailedUpdateData(errBrokenCasinoTrx)
+	assert.Empty(t, data.CasinoError)
+	assert.False(t, data.Retryable)
+}
+
+func TestCheckSignedCasinoTrx(t *testing.T) {
+	var libTime time.Time
+	var trxState string
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/chain/get_info":
+			_, _ = w.Write([]byte(`{"last_irreversible_block_num": 100}`))
+		case "/v1/chain/get_block":
+			_, _ = fmt.Fprintf(w, `{"block_num": 100, "timestamp": "%s"}`,
+				libTime.UTC().Format(eos.BlockTimestampFormat))
+		case "/v1/chain/get_transaction_status":
+			_, _ = fmt.Fprintf(w, `{"state": "%s", "block_number": 90}`, trxState)
+		}
+	}))
+	defer node.Close()
+
+	uc := NewGameSessionsUseCase(&blockchain.Blockchain{Api: eos.New(node.URL)}, nil, nil, "platform",
+		nil, nil, nil, nil, nil, nil)
+
+	newSignedTrx := func(expiration time.Time) *models.CasinoTrx {
+		signedTrx := eos.NewSignedTransaction(eos.NewTransaction(nil, nil))
+		signedTrx.Expiration = eos.JSONTime{Time: expiration.UTC().Truncate(time.Second)}
+		packed, err := eos.MarshalBinary(signedTrx)
+		require.NoError(t, err)
+		return &models.CasinoTrx{ID: 1, TrxID: "trx1", SignedTrx: packed}
+	}
+
+	// executed transaction isn't sent again
+	libTime = time.Now()
+	trxState = "IN_BLOCK"
+	signedTrx, executed, err := uc.checkSignedCasinoTrx(newSignedTrx(time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+	assert.True(t, executed)
+	assert.Nil(t, signedTrx)
+
+	// not expired transaction is sent again as is
+	trxState = "UNKNOWN"
+	signedTrx, executed, err = uc.checkSignedCasinoTrx(newSignedTrx(time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+	assert.False(t, executed)
+	assert.NotNil(t, signedTrx)
+
+	// expired by local clock, but not by irreversible block
+	libTime = time.Now().Add(-time.Minute)
+	_, _, err = uc.checkSignedCasinoTrx(newSignedTrx(time.Now().Add(-time.Second)))
+	assert.Equal(t, errCasinoTrxInFlight, err)
+
+	// new transaction is signed only after irreversible block is past expiration
+	libTime = time.Now()
+	signedTrx, executed, err = uc.checkSignedCasinoTrx(newSignedTrx(time.Now().Add(-time.Minute)))
+	require.NoError(t, err)
+	assert.False(t, executed)
+	assert.Nil(t, signedTrx)
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	casinoclient "platform-backend/casino_client"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"platform-backend/utils"
+	"sync"
+	"time"
+)
+
+// claimed webhook isn't claimed by other workers until lease expires
+const casinoWebhookLease = time.Minute
+
+// RunCasinoWebhooks delivers queued webhooks to casinos until ctx is done
+func (a *GameSessionsUseCase) RunCasinoWebhooks(ctx context.Context) error {
+	workers := a.webhooksConfig.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	webhooks := make(chan *models.CasinoWebhook)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for webhook := range webhooks {
+				a.deliverCasinoWebhook(ctx, webhook)
+			}
+		}()
+	}
+	defer func() {
+		close(webhooks)
+		wg.Wait()
+	}()
+
+	log.Info().Msgf("Casino webhooks delivery is started with %d workers", workers)
+
+	ticker := time.NewTicker(time.Duration(a.webhooksConfig.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := a.repo.ClaimCasinoWebhooks(ctx, workers, casinoWebhookLease)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Msgf("Casino webhooks claim error: %s", err.Error())
+		}
+
+		for _, webhook := range claimed {
+			select {
+			case webhooks <- webhook:
+			case <-ctx.Done():
+				log.Info().Msg("Casino webhooks delivery is stopped")
+				return nil
+			}
+		}
+
+		// more webhooks can be ready, don't wait for the next tick
+		if len(claimed) == workers {
+			continue
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			log.Info().Msg("Casino webhooks delivery is stopped")
+			return nil
+		}
+	}
+}
+
+func (a *GameSessionsUseCase) deliverCasinoWebhook(ctx context.Context, webhook *models.CasinoWebhook) {
+	casino, err := a.contractsRepo.GetCasino(ctx, webhook.CasinoID)
+	if err == nil {
+		err = a.casinoClient.SendWebhook(ctx, casino, webhook)
+	}
+	if err == nil {
+		log.Debug().Msgf("Casino webhook delivered, casinoID: %d, webhookID: %d", webhook.CasinoID, webhook.ID)
+		if err := a.repo.UpdateCasinoWebhookDelivered(ctx, webhook.ID); err != nil {
+			log.Error().Msgf("Failed to mark casino webhook delivered, webhookID: %d, reason: %s", webhook.ID, err.Error())
+		}
+		return
+	}
+
+	log.Info().Msgf("Casino webhook attempt %d failed, casinoID: %d, webhookID: %d, error: %s",
+		webhook.Attempts, webhook.CasinoID, webhook.ID, err.Error())
+
+	// casinos repo errors are retried as well as casino unavailability
+	var casinoErr *casinoclient.Error
+	retryable := !errors.As(err, &casinoErr) || casinoErr.Retryable
+	if !retryable || webhook.Attempts >= a.webhooksConfig.MaxAttempts {
+		if err := a.repo.UpdateCasinoWebhookFailed(ctx, webhook.ID, err.Error()); err != nil {
+			log.Error().Msgf("Failed to mark casino webhook failed, webhookID: %d, reason: %s", webhook.ID, err.Error())
+		}
+		return
+	}
+
+	delay := utils.Backoff(
+		time.Duration(a.webhooksConfig.MinBackoff)*time.Second,
+		time.Duration(a.webhooksConfig.MaxBackoff)*time.Second,
+		webhook.Attempts,
+	)
+	if err := a.repo.UpdateCasinoWebhookRetry(ctx, webhook.ID, delay, err.Error()); err != nil {
+		// webhook will be claimed again after lease expiration
+		log.Error().Msgf("Failed to schedule casino webhook retry, webhookID: %d, reason: %s", webhook.ID, err.Error())
+	}
+}
+
+func (a *GameSessionsUseCase) GetCasinoWebhooks(
+	ctx context.Context,
+	query *gamesessions.CasinoWebhooksQuery,
+) ([]*models.CasinoWebhook, error) {
+	return a.repo.GetCasinoWebhooks(ctx, query)
+}
+
+func (a *GameSessionsUseCase) RequeueCasinoWebhook(ctx context.Context, id uint64) error {
+	return a.repo.RequeueCasinoWebhook(ctx, id)
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	casinoclient "platform-backend/casino_client"
+	"platform-backend/config"
+	"platform-backend/contracts/repository/mock"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/game_sessions/repository/localstorage"
+	"platform-backend/models"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebhooksTestUseCase(
+	t *testing.T,
+	handler http.HandlerFunc,
+	webhooksConfig *config.CasinoWebhooksConfig,
+) (*GameSessionsUseCase, *localstorage.GameSessionsLocalRepo) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	contractsRepo := mock.NewMockedListingRepo()
+	contractsRepo.AddCasino(&models.Casino{
+		Id:       1,
+		Contract: "casino",
+		Meta:     &models.CasinoMeta{ApiURL: server.URL, WebhookURL: server.URL + "/webhook"},
+	})
+	casinoClient := casinoclient.NewClient(&config.CasinoClientConfig{Timeout: 5, BreakerThreshold: 100},
+		prometheus.NewRegistry())
+
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, contractsRepo, "platform", nil, nil, nil, nil, webhooksConfig, casinoClient)
+	return uc, repo
+}
+
+func addTestWebhook(t *testing.T, repo *localstorage.GameSessionsLocalRepo) {
+	webhook, err := models.NewCasinoWebhook(models.WebhookSessionStarted,
+		&models.GameSession{ID: 1, CasinoID: 1, Player: "player"}, nil, "")
+	require.NoError(t, err)
+	require.NoError(t, repo.AddCasinoWebhook(context.Background(), webhook))
+}
+
+func getTestWebhook(t *testing.T, repo *localstorage.GameSessionsLocalRepo) *models.CasinoWebhook {
+	webhooks, err := repo.GetCasinoWebhooks(context.Background(), &gamesessions.CasinoWebhooksQuery{})
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	return webhooks[0]
+}
+
+func TestDeliverCasinoWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		result   models.CasinoWebhookStatus
+		retry    bool
+	}{
+		{"delivered", http.StatusOK, 1, models.CasinoWebhookDelivered, false},
+		{"casino unavailable", http.StatusInternalServerError, 1, models.CasinoWebhookPending, true},
+		{"casino rejected", http.StatusBadRequest, 1, models.CasinoWebhookFailed, false},
+		{"max attempts", http.StatusInternalServerError, 3, models.CasinoWebhookFailed, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			status := test.status
+			uc, repo := newWebhooksTestUseCase(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}, &config.CasinoWebhooksConfig{MaxAttempts: 3, MinBackoff: 5, MaxBackoff: 600})
+			addTestWebhook(t, repo)
+
+			claimed, err := repo.ClaimCasinoWebhooks(ctx, 1, casinoWebhookLease)
+			require.NoError(t, err)
+			require.Len(t, claimed, 1)
+			claimed[0].Attempts = test.attempts
+			uc.deliverCasinoWebhook(ctx, claimed[0])
+
+			webhook := getTestWebhook(t, repo)
+			assert.Equal(t, test.result, webhook.Status)
+			if test.result == models.CasinoWebhookDelivered {
+				assert.Empty(t, webhook.LastError)
+			} else {
+				assert.NotEmpty(t, webhook.LastError)
+			}
+			if test.retry {
+				// retry is scheduled with backoff instead of lease expiration
+				assert.True(t, webhook.NextAttempt.Before(time.Now().Add(casinoWebhookLease)))
+				assert.True(t, webhook.NextAttempt.After(time.Now()))
+			}
+		})
+	}
+}
+
+func TestRunCasinoWebhooks(t *testing.T) {
+	var requests int32
+	uc, repo := newWebhooksTestUseCase(t, func(w http.ResponseWriter, r *http.Request) {
+		// first attempt fails, retry succeeds
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}, &config.CasinoWebhooksConfig{Workers: 2, PollInterval: 1, MaxAttempts: 3})
+	addTestWebhook(t, repo)
+
+	// retry without backoff is claimed on the next poll
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	require.NoError(t, uc.RunCasinoWebhooks(ctx))
+
+	webhook := getTestWebhook(t, repo)
+	assert.Equal(t, models.CasinoWebhookDelivered, webhook.Status)
+	assert.Equal(t, 2, webhook.Attempts)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
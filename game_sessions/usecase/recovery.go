@@ -0,0 +1,325 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"platform-backend/blockchain"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"strconv"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// transactions sent less than grace period ago can be still not included to a block
+const recoveryGracePeriod = time.Minute
+
+// game contract session states
+const (
+	contractReqDeposit = iota
+	contractReqStart
+	contractReqAction
+	contractReqSignidicePartOne
+	contractReqSignidicePartTwo
+	contractFinished
+)
+
+var recoverableStates = []models.GameSessionState{
+	models.NewGameTrxSent,
+	models.GameActionTrxSent,
+	models.SignidicePartOneTrxSent,
+}
+
+// session in game contract(parse only fields used by recovery)
+type contractSession struct {
+	ReqId  eos.Uint64      `json:"req_id"`
+	State  uint8           `json:"state"`
+	Digest eos.Checksum256 `json:"digest"`
+}
+
+func (a *GameSessionsUseCase) RecoverSessions(ctx context.Context) ([]*gamesessions.RecoveryReport, error) {
+	sessions, err := a.repo.GetSessionsByStates(ctx, recoverableStates)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*gamesessions.RecoveryReport, 0, len(sessions))
+	for _, session := range sessions {
+		report, err := a.recoverSession(ctx, session)
+		if err != nil {
+			report = &gamesessions.RecoveryReport{Action: gamesessions.RecoverySkipped, Details: err.Error()}
+		}
+		report.SessionID = session.ID
+		report.State = session.State
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (a *GameSessionsUseCase) recoverSession(
+	ctx context.Context,
+	session *models.GameSession,
+) (*gamesessions.RecoveryReport, error) {
+	game, err := a.contractsRepo.GetGame(ctx, session.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	bcSession, err := a.getContractSession(game.Contract, session.BlockchainSesID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch session.State {
+	case models.NewGameTrxSent:
+		return a.recoverNewGame(ctx, session, bcSession)
+	case models.GameActionTrxSent:
+		return a.recoverGameAction(ctx, session, bcSession)
+	case models.SignidicePartOneTrxSent:
+		return a.recoverSignidice(ctx, session, game, bcSession)
+	}
+
+	return recoveryReport(gamesessions.RecoverySkipped, "state is not recoverable"), nil
+}
+
+func (a *GameSessionsUseCase) recoverNewGame(
+	ctx context.Context,
+	session *models.GameSession,
+	bcSession *contractSession,
+) (*gamesessions.RecoveryReport, error) {
+	if bcSession != nil {
+		return recoveryReport(gamesessions.RecoveryResumed, "session is created in contract, waiting for events"), nil
+	}
+
+	if report, err := a.checkCasinoTrxInProgress(ctx, session); report != nil || err != nil {
+		return report, err
+	}
+	// executed newgame closes contract session on finish, its events can be not processed yet
+	if report, err := a.checkSessionTrxExecuted(ctx, session); report != nil || err != nil {
+		return report, err
+	}
+
+	txns, err := a.repo.CountGameSessionTransactions(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	cause := errors.New("newgame transaction was not sent")
+	if txns > 0 {
+		cause = errors.New("newgame transaction was not executed")
+	}
+
+	err = a.failSession(ctx, session, cause)
+	if err == gamesessions.ErrSessionVersionConflict {
+		return recoveryReport(gamesessions.RecoveryResumed, "session is changed after inspection"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return recoveryReport(gamesessions.RecoveryFailed, cause.Error()), nil
+}
+
+func (a *GameSessionsUseCase) recoverGameAction(
+	ctx context.Context,
+	session *models.GameSession,
+	bcSession *contractSession,
+) (*gamesessions.RecoveryReport, error) {
+	if bcSession == nil || bcSession.State != contractReqAction {
+		return resumedByEvents(bcSession), nil
+	}
+
+	if report, err := a.checkCasinoTrxInProgress(ctx, session); report != nil || err != nil {
+		return report, err
+	}
+	// contract waits for action of the next round if action is executed and events are behind
+	if report, err := a.checkSessionTrxExecuted(ctx, session); report != nil || err != nil {
+		return report, err
+	}
+
+	unlock := a.sessionLocks.lock(session.ID)
+	defer unlock()
+
+	// action wasn't executed by contract, so player can send it again
+	err := a.requestGameActionAgain(ctx, session, errors.New("action transaction was lost"))
+	if err == gamesessions.ErrSessionVersionConflict {
+		return recoveryReport(gamesessions.RecoveryResumed, "session is changed after inspection"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return recoveryReport(gamesessions.RecoveryRedriven, "action trx is lost, action is requested from player again"), nil
+}
+
+func (a *GameSessionsUseCase) recoverSignidice(
+	ctx context.Context,
+	session *models.GameSession,
+	game *models.Game,
+	bcSession *contractSession,
+) (*gamesessions.RecoveryReport, error) {
+	if bcSession == nil || bcSession.State != contractReqSignidicePartOne {
+		return resumedByEvents(bcSession), nil
+	}
+
+	inFlight, err := a.isTrxInFlight(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	if inFlight || a.isRedrivenInFlight(session.ID) {
+		return recoveryReport(gamesessions.RecoveryResumed, "signidice trx can be still in flight"), nil
+	}
+
+	err = a.signidiceUseCase.PerformSignidice(ctx, session, game.Contract, bcSession.Digest)
+	if err != nil {
+		return nil, err
+	}
+	a.markRedriven(session.ID)
+	return recoveryReport(gamesessions.RecoveryRedriven, "signidice part one trx is sent again"), nil
+}
+
+// checkCasinoTrxInProgress returns report if casino signed trx can be still executed
+func (a *GameSessionsUseCase) checkCasinoTrxInProgress(
+	ctx context.Context,
+	session *models.GameSession,
+) (*gamesessions.RecoveryReport, error) {
+	pending, err := a.repo.HasPendingCasinoTrx(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return recoveryReport(gamesessions.RecoveryResumed, "casino trx is pending in outbox"), nil
+	}
+	inFlight, err := a.isTrxInFlight(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	if inFlight {
+		return recoveryReport(gamesessions.RecoveryResumed, "casino trx can be still in flight"), nil
+	}
+	return nil, nil
+}
+
+// checkSessionTrxExecuted returns report if trx sent in the current session state is executed or can be,
+// then session is moved by events, e.g. on startup processor can be still behind the blockchain
+func (a *GameSessionsUseCase) checkSessionTrxExecuted(
+	ctx context.Context,
+	session *models.GameSession,
+) (*gamesessions.RecoveryReport, error) {
+	trxs, err := a.repo.GetGameSessionTransactions(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var lib *blockchain.IrreversibleBlock
+	for _, trx := range trxs {
+		// trxs of previous states are pushed before state change
+		if trx.Created < session.LastUpdate || trx.Status == models.TrxDropped {
+			continue
+		}
+
+		status := trx.Status
+		if status != models.TrxExecuted && status != models.TrxIrreversible {
+			if lib == nil {
+				if lib, err = a.bc.GetIrreversibleBlock(); err != nil {
+					return nil, err
+				}
+			}
+			status, _, err = a.bc.GetTransactionStatus(trx.TrxID, lib)
+			if err == blockchain.ErrTrxNotFound {
+				// created time is rounded down to seconds
+				if !lib.IsTrxExpired(time.Unix(trx.Created+1, 0)) {
+					return recoveryReport(gamesessions.RecoveryResumed, "session trx can be still executed"), nil
+				}
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if status == models.TrxExecuted || status == models.TrxIrreversible {
+			return recoveryReport(gamesessions.RecoveryResumed, "session trx is executed, waiting for events"), nil
+		}
+	}
+	return nil, nil
+}
+
+func (a *GameSessionsUseCase) getContractSession(contract string, sessionID uint64) (*contractSession, error) {
+	id := strconv.FormatUint(sessionID, 10)
+	resp, err := a.bc.Api.GetTableRows(eos.GetTableRowsRequest{
+		Code:       contract,
+		Scope:      contract,
+		Table:      "session",
+		LowerBound: id,
+		UpperBound: id,
+		Limit:      1,
+		JSON:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*contractSession, 0)
+	if err := resp.JSONToStructs(&sessions); err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 || uint64(sessions[0].ReqId) != sessionID {
+		return nil, nil
+	}
+	return sessions[0], nil
+}
+
+// isTrxInFlight reports whether session state is changed or its trx is pushed during grace period,
+// recovery is run periodically, so such session is inspected again later
+func (a *GameSessionsUseCase) isTrxInFlight(ctx context.Context, session *models.GameSession) (bool, error) {
+	if time.Since(time.Unix(session.LastUpdate, 0)) < recoveryGracePeriod {
+		return true, nil
+	}
+
+	// casino trx can be sent by outbox long after session state change
+	trxs, err := a.repo.GetGameSessionTransactions(ctx, session.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, trx := range trxs {
+		if time.Since(time.Unix(trx.Created, 0)) < recoveryGracePeriod {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// markRedriven remembers that lost trx of session is sent again
+func (a *GameSessionsUseCase) markRedriven(sessionID uint64) {
+	a.redrivenMutex.Lock()
+	defer a.redrivenMutex.Unlock()
+	a.redriven[sessionID] = time.Now()
+}
+
+// isRedrivenInFlight reports whether trx sent again by recovery can be still in flight
+func (a *GameSessionsUseCase) isRedrivenInFlight(sessionID uint64) bool {
+	a.redrivenMutex.Lock()
+	defer a.redrivenMutex.Unlock()
+
+	// forget sessions whose trxs can't be in flight anymore
+	for id, redriven := range a.redriven {
+		if time.Since(redriven) >= recoveryGracePeriod {
+			delete(a.redriven, id)
+		}
+	}
+	_, ok := a.redriven[sessionID]
+	return ok
+}
+
+func resumedByEvents(bcSession *contractSession) *gamesessions.RecoveryReport {
+	if bcSession == nil {
+		return recoveryReport(gamesessions.RecoveryResumed, "session is closed in contract, waiting for events")
+	}
+	return recoveryReport(gamesessions.RecoveryResumed,
+		"contract session state is "+strconv.Itoa(int(bcSession.State))+", waiting for events")
+}
+
+func recoveryReport(action gamesessions.RecoveryAction, details string) *gamesessions.RecoveryReport {
+	return &gamesessions.RecoveryReport{Action: action, Details: details}
+}
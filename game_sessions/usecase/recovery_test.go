@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/game_sessions/repository/localstorage"
+	"platform-backend/models"
+	subsusecase "platform-backend/subscription/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverGameAction(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, nil, "platform", subsusecase.NewSubscriptionUseCase(repo),
+		nil, nil, nil, nil, nil)
+	bcSession := &contractSession{State: contractReqAction}
+
+	// trx pushed during grace period is still in flight, session is inspected again later
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.GameActionTrxSent}))
+	require.NoError(t, repo.AddGameSessionTransaction(ctx, "trx1", 1, 0, nil))
+	session, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	report, err := uc.recoverGameAction(ctx, session, bcSession)
+	require.NoError(t, err)
+	assert.Equal(t, gamesessions.RecoveryResumed, report.Action)
+
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 2, State: models.GameActionTrxSent}))
+	session, err = repo.GetGameSession(ctx, 2)
+	require.NoError(t, err)
+	report, err = uc.recoverGameAction(ctx, session, bcSession)
+	require.NoError(t, err)
+	assert.Equal(t, gamesessions.RecoveryRedriven, report.Action)
+	recovered, err := repo.GetGameSession(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, models.RequestedGameAction, recovered.State)
+	// player is notified to send action again
+	updates, err := repo.GetGameSessionUpdates(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	assert.Equal(t, models.GameActionRequestedUpdate, updates[0].UpdateType)
+
+	// session changed after inspection isn't touched
+	report, err = uc.recoverGameAction(ctx, session, bcSession)
+	require.NoError(t, err)
+	assert.Equal(t, gamesessions.RecoveryResumed, report.Action)
+}
+
+func TestRedrivenInFlight(t *testing.T) {
+	uc := NewGameSessionsUseCase(nil, nil, nil, "platform", nil, nil, nil, nil, nil, nil)
+
+	assert.False(t, uc.isRedrivenInFlight(1))
+	uc.markRedriven(1)
+	assert.True(t, uc.isRedrivenInFlight(1))
+
+	uc.redriven[1] = time.Now().Add(-recoveryGracePeriod)
+	assert.False(t, uc.isRedrivenInFlight(1))
+	assert.Empty(t, uc.redriven)
+}
+
+func TestCheckSessionTrxExecuted(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, nil, "platform", nil, nil, nil, nil, nil, nil)
+
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.GameActionTrxSent}))
+	require.NoError(t, repo.AddGameSessionTransaction(ctx, "trx1", 1, 0, nil))
+	require.NoError(t, repo.UpdateGameSessionTransactionStatus(ctx, "trx1", models.TrxExecuted, nil))
+
+	// executed action isn't requested again, session is moved by events
+	session := &models.GameSession{ID: 1, State: models.GameActionTrxSent, LastUpdate: time.Now().Unix() - 1}
+	report, err := uc.checkSessionTrxExecuted(ctx, session)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, gamesessions.RecoveryResumed, report.Action)
+
+	// trx executed before the current state isn't the lost one
+	session.LastUpdate = time.Now().Unix() + 10
+	report, err = uc.checkSessionTrxExecuted(ctx, session)
+	require.NoError(t, err)
+	assert.Nil(t, report)
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const maxSessionIDAttempts = 5
+
+// randomSessionID returns crypto-random non-zero 64-bit id
+func randomSessionID() (uint64, error) {
+	var buf [8]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, err
+		}
+		if id := binary.LittleEndian.Uint64(buf[:]); id != 0 {
+			return id, nil
+		}
+	}
+}
+
+// isSessionIDFree checks that id isn't used neither by platform nor by game contract
+func (a *GameSessionsUseCase) isSessionIDFree(ctx context.Context, gameContract string, id uint64) (bool, error) {
+	exists, err := a.repo.HasGameSession(ctx, id)
+	if err != nil || exists {
+		return false, err
+	}
+
+	bcSession, err := a.getContractSession(gameContract, id)
+	if err != nil {
+		return false, err
+	}
+	return bcSession == nil, nil
+}
+
+// addSessionWithNewID allocates unique id for session and stores it together with
+// created update and outbox transaction built by newTrx for allocated id,
+// ID and BlockchainSesID of the session are set to allocated id
+func (a *GameSessionsUseCase) addSessionWithNewID(
+	ctx context.Context,
+	gameContract string,
+	session *models.GameSession,
+	newTrx func(id uint64) (*models.CasinoTrx, error),
+) error {
+	for attempt := 1; attempt <= maxSessionIDAttempts; attempt++ {
+		id, err := randomSessionID()
+		if err != nil {
+			return err
+		}
+
+		free, err := a.isSessionIDFree(ctx, gameContract, id)
+		if err != nil {
+			return err
+		}
+		if !free {
+			log.Warn().Msgf("Session id collision, id: %d, attempt: %d", id, attempt)
+			continue
+		}
+
+		trx, err := newTrx(id)
+		if err != nil {
+			return err
+		}
+
+		session.ID = id
+		session.BlockchainSesID = id
+
+		// id can be taken concurrently after the check
+		err = a.addNewSession(ctx, session, trx)
+		if err == gamesessions.ErrGameSessionAlreadyExists {
+			log.Warn().Msgf("Session id collision, id: %d, attempt: %d", id, attempt)
+			continue
+		}
+		return err
+	}
+
+	return gamesessions.ErrSessionIDAllocation
+}
+
+// addNewSession stores session, its created update and outbox transaction atomically
+func (a *GameSessionsUseCase) addNewSession(ctx context.Context, session *models.GameSession, trx *models.CasinoTrx) error {
+	uow, err := a.repo.BeginUnitOfWork(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = uow.Rollback(ctx)
+	}()
+
+	if err := uow.AddGameSession(ctx, session); err != nil {
+		return err
+	}
+	err = uow.AddGameSessionUpdate(ctx, &models.GameSessionUpdate{
+		SessionID:  session.ID,
+		UpdateType: models.SessionCreatedUpdate,
+		Timestamp:  time.Now(),
+		Data:       nil,
+		Offset:     nil,
+	})
+	if err != nil {
+		return err
+	}
+	if err := uow.AddCasinoTrx(ctx, trx); err != nil {
+		return err
+	}
+	if err := uow.Commit(ctx); err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Casino trx enqueued, sessionID: %d, outboxID: %d", session.ID, trx.ID)
+	return nil
+}
@@ -0,0 +1,47 @@
+package usecase
+
+import "sync"
+
+type sessionLock struct {
+	sync.Mutex
+	// number of goroutines holding or waiting for the lock
+	refs int
+}
+
+// sessionLocks serializes operations on the same session,
+// lock is removed when nobody holds or waits for it
+type sessionLocks struct {
+	sync.Mutex
+	locks map[uint64]*sessionLock
+}
+
+func newSessionLocks() *sessionLocks {
+	return &sessionLocks{
+		locks: make(map[uint64]*sessionLock),
+	}
+}
+
+// lock locks session and returns unlock function
+func (l *sessionLocks) lock(sessionID uint64) func() {
+	l.Lock()
+	sesLock, ok := l.locks[sessionID]
+	if !ok {
+		sesLock = new(sessionLock)
+		l.locks[sessionID] = sesLock
+	}
+	sesLock.refs++
+	l.Unlock()
+
+	sesLock.Lock()
+
+	return func() {
+		sesLock.Unlock()
+
+		l.Lock()
+		sesLock.refs--
+		if sesLock.refs == 0 {
+			delete(l.locks, sessionID)
+		}
+		l.Unlock()
+	}
+}
@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionLocks(t *testing.T) {
+	locks := newSessionLocks()
+
+	var (
+		wg      sync.WaitGroup
+		counter int
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lock(1)
+			defer unlock()
+			counter++
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, counter)
+	assert.Len(t, locks.locks, 0)
+}
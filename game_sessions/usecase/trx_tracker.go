@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"platform-backend/blockchain"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"platform-backend/utils"
+	"time"
+)
+
+// RunTrxTracker polls blockchain for pending session transactions until ctx is done
+func (a *GameSessionsUseCase) RunTrxTracker(ctx context.Context) error {
+	if a.trackerConfig.Interval <= 0 {
+		log.Info().Msg("Session transactions tracker is disabled")
+		<-ctx.Done()
+		return nil
+	}
+
+	log.Info().Msg("Session transactions tracker is started")
+
+	ticker := time.NewTicker(time.Duration(a.trackerConfig.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Session transactions tracker is stopped")
+			return nil
+		case <-ticker.C:
+		}
+
+		trxs, err := a.repo.GetPendingGameSessionTransactions(ctx, a.trackerConfig.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error().Msgf("Failed to get pending session transactions: %s", err.Error())
+			}
+			continue
+		}
+		if len(trxs) == 0 {
+			continue
+		}
+
+		// transaction not found in blockchain is dropped only if it's expired before irreversible block
+		lib, err := a.bc.GetIrreversibleBlock()
+		if err != nil {
+			log.Error().Msgf("Failed to get last irreversible block: %s", err.Error())
+			continue
+		}
+
+		for _, trx := range trxs {
+			if ctx.Err() != nil {
+				break
+			}
+			// session is read before lookup, so it isn't failed if changed after trx was dropped
+			session, err := a.repo.GetGameSession(ctx, trx.SessionID)
+			if err != nil {
+				log.Error().Msgf("Failed to get session of transaction, sessionID: %d, reason: %s",
+					trx.SessionID, err.Error())
+				continue
+			}
+			status, blockNum, err := a.bc.GetTransactionStatus(trx.TrxID, lib)
+			a.trackTransaction(ctx, trx, session, lib, status, blockNum, err)
+		}
+	}
+}
+
+// trackTransaction stores looked up transaction status,
+// session waiting for dropped transaction can't be continued, so it is failed
+func (a *GameSessionsUseCase) trackTransaction(
+	ctx context.Context,
+	trx *models.GameSessionTrx,
+	session *models.GameSession,
+	lib *blockchain.IrreversibleBlock,
+	status models.GameSessionTrxStatus,
+	blockNum uint32,
+	lookupErr error,
+) {
+	var block *uint32
+	switch {
+	case lookupErr == blockchain.ErrTrxNotFound:
+		// transaction is stored after push, created time is rounded down to seconds
+		if !lib.IsTrxExpired(time.Unix(trx.Created+1, 0)) {
+			a.postponeTrxCheck(ctx, trx)
+			return
+		}
+		status = models.TrxDropped
+	case lookupErr != nil:
+		log.Warn().Msgf("Failed to get transaction status, trxID: %s, reason: %s", trx.TrxID, lookupErr.Error())
+		a.postponeTrxCheck(ctx, trx)
+		return
+	case status != models.TrxDropped:
+		block = &blockNum
+	}
+
+	if status != trx.Status {
+		if err := a.repo.UpdateGameSessionTransactionStatus(ctx, trx.TrxID, status, block); err != nil {
+			log.Error().Msgf("Failed to update transaction status, trxID: %s, reason: %s", trx.TrxID, err.Error())
+			return
+		}
+		log.Debug().Msgf("Session transaction status changed, sessionID: %d, trxID: %s, status: %d",
+			trx.SessionID, trx.TrxID, status)
+	}
+
+	if !status.IsFinal() {
+		// executed transaction is checked again until it's irreversible
+		a.postponeTrxCheck(ctx, trx)
+		return
+	}
+	if status != models.TrxDropped {
+		return
+	}
+
+	log.Warn().Msgf("Session transaction dropped, sessionID: %d, trxID: %s", trx.SessionID, trx.TrxID)
+
+	if err := a.failDroppedTrxSession(ctx, trx, session); err != nil {
+		log.Error().Msgf("Failed to fail session of dropped transaction, sessionID: %d, reason: %s",
+			trx.SessionID, err.Error())
+	}
+}
+
+// failDroppedTrxSession fails session only if it still waits for dropped transaction,
+// session with later transaction (e.g. action sent again after recovery) is left as is
+func (a *GameSessionsUseCase) failDroppedTrxSession(
+	ctx context.Context,
+	trx *models.GameSessionTrx,
+	session *models.GameSession,
+) error {
+	// failSession overwrites state before fail, so finished and failed sessions are left as is
+	if session.State.IsTerminal() {
+		return nil
+	}
+
+	sessionTrxs, err := a.repo.GetGameSessionTransactions(ctx, trx.SessionID)
+	if err != nil {
+		return err
+	}
+	if !isLatestSessionTrx(sessionTrxs, trx) || !session.State.IsTrxSent() {
+		log.Info().Msgf("Dropped transaction isn't current one of session, sessionID: %d, trxID: %s, state: %d",
+			trx.SessionID, trx.TrxID, session.State)
+		return nil
+	}
+
+	// version is read before lookup, so session changed since then isn't failed
+	err = a.failSession(ctx, session, gamesessions.ErrSessionTrxDropped)
+	if err == gamesessions.ErrSessionVersionConflict {
+		log.Info().Msgf("Session of dropped transaction is changed, sessionID: %d", trx.SessionID)
+		return nil
+	}
+	return err
+}
+
+// isLatestSessionTrx reports whether no transaction of session is pushed after trx,
+// transactions created in the same second can't be ordered, so none of them is the latest
+func isLatestSessionTrx(sessionTrxs []*models.GameSessionTrx, trx *models.GameSessionTrx) bool {
+	found := false
+	for _, sessionTrx := range sessionTrxs {
+		if sessionTrx.TrxID == trx.TrxID {
+			found = true
+			continue
+		}
+		if sessionTrx.Created >= trx.Created {
+			return false
+		}
+	}
+	return found
+}
+
+// postponeTrxCheck delays the next check of transaction with backoff,
+// so transactions checked again and again don't starve the others
+func (a *GameSessionsUseCase) postponeTrxCheck(ctx context.Context, trx *models.GameSessionTrx) {
+	delay := utils.Backoff(
+		time.Duration(a.trackerConfig.Interval)*time.Second,
+		time.Duration(a.trackerConfig.MaxBackoff)*time.Second,
+		trx.CheckAttempts+1,
+	)
+	if err := a.repo.PostponeGameSessionTransactionCheck(ctx, trx.TrxID, delay); err != nil {
+		log.Error().Msgf("Failed to postpone transaction check, trxID: %s, reason: %s", trx.TrxID, err.Error())
+	}
+}
+
+func (a *GameSessionsUseCase) GetSessionTransactions(
+	ctx context.Context,
+	sessionId uint64,
+) ([]*models.GameSessionTrx, error) {
+	return a.repo.GetGameSessionTransactions(ctx, sessionId)
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"platform-backend/blockchain"
+	"platform-backend/config"
+	"platform-backend/contracts/repository/mock"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/game_sessions/repository/localstorage"
+	"platform-backend/models"
+	subsusecase "platform-backend/subscription/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	contractsRepo := mock.NewMockedListingRepo()
+	contractsRepo.AddCasino(&models.Casino{Id: 1, Meta: &models.CasinoMeta{WebhookURL: "http://casino"}})
+	uc := NewGameSessionsUseCase(nil, repo, contractsRepo, "platform", subsusecase.NewSubscriptionUseCase(repo),
+		nil, nil, &config.TrxTrackerConfig{Interval: 5, MaxBackoff: 60}, nil, nil)
+
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, CasinoID: 1, State: models.GameActionTrxSent}))
+	require.NoError(t, repo.AddGameSessionTransaction(ctx, "trx1", 1, 0, nil))
+	trxs, err := repo.GetPendingGameSessionTransactions(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, trxs, 1)
+
+	session, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+
+	// not found transaction isn't dropped until it can be included to a block
+	lib := &blockchain.IrreversibleBlock{Num: 100, Timestamp: time.Now()}
+	uc.trackTransaction(ctx, trxs[0], session, lib, models.TrxPending, 0, blockchain.ErrTrxNotFound)
+	pending, err := repo.GetPendingGameSessionTransactions(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "check is postponed")
+
+	lib.Timestamp = time.Now().Add(blockchain.TrxExpiration + 2*time.Second)
+	uc.trackTransaction(ctx, trxs[0], session, lib, models.TrxPending, 0, blockchain.ErrTrxNotFound)
+	stored, err := repo.GetGameSessionTransactions(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.TrxDropped, stored[0].Status)
+	assert.Equal(t, 1, stored[0].CheckAttempts)
+
+	session, err = repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameFailed, session.State)
+	require.NotNil(t, session.StateBeforeFail)
+	assert.Equal(t, models.GameActionTrxSent, *session.StateBeforeFail)
+	webhooks, err := repo.GetCasinoWebhooks(ctx, &gamesessions.CasinoWebhooksQuery{})
+	require.NoError(t, err)
+	assert.Len(t, webhooks, 1)
+}
+
+func TestTrackTransactionDroppedNotLatest(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	contractsRepo := mock.NewMockedListingRepo()
+	contractsRepo.AddCasino(&models.Casino{Id: 1, Meta: &models.CasinoMeta{}})
+	uc := NewGameSessionsUseCase(nil, repo, contractsRepo, "platform", subsusecase.NewSubscriptionUseCase(repo),
+		nil, nil, &config.TrxTrackerConfig{Interval: 5, MaxBackoff: 60}, nil, nil)
+
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, CasinoID: 1, State: models.GameActionTrxSent}))
+	require.NoError(t, repo.AddGameSessionTransaction(ctx, "newgame", 1, 0, nil))
+	require.NoError(t, repo.AddGameSessionTransaction(ctx, "action", 1, 0, nil))
+	// action is sent again after recovery, the later transaction is in flight
+	time.Sleep(time.Millisecond * 1010)
+	require.NoError(t, repo.AddGameSessionTransaction(ctx, "action again", 1, 0, nil))
+	trxs, err := repo.GetGameSessionTransactions(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, trxs, 3)
+
+	session, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	lib := &blockchain.IrreversibleBlock{Num: 100, Timestamp: time.Now().Add(blockchain.TrxExpiration + 2*time.Second)}
+	uc.trackTransaction(ctx, trxs[1], session, lib, models.TrxPending, 0, blockchain.ErrTrxNotFound)
+
+	stored, err := repo.GetGameSessionTransactions(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.TrxDropped, stored[1].Status)
+	session, err = repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameActionTrxSent, session.State)
+
+	// the latest transaction is dropped, session waits for it
+	uc.trackTransaction(ctx, trxs[2], session, lib, models.TrxPending, 0, blockchain.ErrTrxNotFound)
+	session, err = repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameFailed, session.State)
+}
+
+func TestTrackTransactionDroppedSessionChanged(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, mock.NewMockedListingRepo(), "platform", subsusecase.NewSubscriptionUseCase(repo),
+		nil, nil, &config.TrxTrackerConfig{Interval: 5, MaxBackoff: 60}, nil, nil)
+
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.NewGameTrxSent}))
+	require.NoError(t, repo.AddGameSessionTransaction(ctx, "newgame", 1, 0, nil))
+	trxs, err := repo.GetGameSessionTransactions(ctx, 1)
+	require.NoError(t, err)
+
+	// session is read before lookup and changed after it
+	session, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateSessionState(ctx, 1, models.GameStartedInBC))
+
+	lib := &blockchain.IrreversibleBlock{Num: 100, Timestamp: time.Now().Add(blockchain.TrxExpiration + 2*time.Second)}
+	uc.trackTransaction(ctx, trxs[0], session, lib, models.TrxPending, 0, blockchain.ErrTrxNotFound)
+
+	session, err = repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameStartedInBC, session.State)
+}
+
+func TestFailSessionVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, mock.NewMockedListingRepo(), "platform", nil, nil, nil, nil, nil, nil)
+
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.NewGameTrxSent}))
+	session, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateSessionState(ctx, 1, models.GameStartedInBC))
+
+	err = uc.failSession(ctx, session, gamesessions.ErrSessionTrxDropped)
+	assert.Equal(t, gamesessions.ErrSessionVersionConflict, err)
+
+	session, err = repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.GameStartedInBC, session.State)
+	updates, err := repo.GetGameSessionUpdates(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, updates)
+}
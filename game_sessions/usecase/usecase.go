@@ -1,22 +1,22 @@
 package usecase
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"math/rand"
-	"net/http"
 	"platform-backend/blockchain"
+	casinoclient "platform-backend/casino_client"
+	"platform-backend/config"
 	"platform-backend/contracts"
 	gamesessions "platform-backend/game_sessions"
 	"platform-backend/models"
+	"platform-backend/signidice"
 	"platform-backend/subscription"
 	"platform-backend/utils"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/eoscanada/eos-go"
@@ -31,6 +31,17 @@ type GameSessionsUseCase struct {
 	contractsRepo    contracts.Repository
 	platformContract string
 	subsUseCase      subscription.UseCase
+	signidiceUseCase signidice.UseCase
+	outboxConfig     *config.CasinoTrxOutboxConfig
+	trackerConfig    *config.TrxTrackerConfig
+	webhooksConfig   *config.CasinoWebhooksConfig
+	outboxWakeup     chan struct{}
+	casinoClient     *casinoclient.Client
+	sessionLocks     *sessionLocks
+
+	// when lost signidice trxs were sent again by recovery
+	redrivenMutex sync.Mutex
+	redriven      map[uint64]time.Time
 }
 
 func NewGameSessionsUseCase(
@@ -39,14 +50,26 @@ func NewGameSessionsUseCase(
 	contractsRepo contracts.Repository,
 	platformContract string,
 	subsUseCase subscription.UseCase,
+	signidiceUseCase signidice.UseCase,
+	outboxConfig *config.CasinoTrxOutboxConfig,
+	trackerConfig *config.TrxTrackerConfig,
+	webhooksConfig *config.CasinoWebhooksConfig,
+	casinoClient *casinoclient.Client,
 ) *GameSessionsUseCase {
-	rand.Seed(time.Now().Unix())
 	return &GameSessionsUseCase{
 		bc:               bc,
 		repo:             repo,
 		contractsRepo:    contractsRepo,
 		platformContract: platformContract,
 		subsUseCase:      subsUseCase,
+		signidiceUseCase: signidiceUseCase,
+		outboxConfig:     outboxConfig,
+		trackerConfig:    trackerConfig,
+		webhooksConfig:   webhooksConfig,
+		outboxWakeup:     make(chan struct{}, 1),
+		casinoClient:     casinoClient,
+		sessionLocks:     newSessionLocks(),
+		redriven:         make(map[uint64]time.Time),
 	}
 }
 
@@ -56,11 +79,6 @@ type gameSession struct {
 	LastUpdate string     `json:"last_update"`
 }
 
-// casino error message
-type casinoError struct {
-	Error string `json:"error"`
-}
-
 func (a *GameSessionsUseCase) CleanExpiredSessions(
 	ctx context.Context,
 	maxLastUpdate time.Duration,
@@ -113,7 +131,7 @@ func (a *GameSessionsUseCase) CleanExpiredSessions(
 				notSigned := eos.NewSignedTransaction(tx)
 
 				requiredKeys := []ecc.PublicKey{a.bc.PubKeys.GameAction}
-				signedTrx, err := a.bc.Api.Signer.Sign(notSigned, a.bc.ChainId, requiredKeys...)
+				signedTrx, err := a.bc.SignTransaction(notSigned, requiredKeys...)
 				if err != nil {
 					return err
 				}
@@ -152,14 +170,6 @@ func (a *GameSessionsUseCase) NewSession(
 		return nil, err
 	}
 
-	// TODO fix after front lib fix
-	sessionId := uint64(rand.Uint32())
-
-	txOpts := a.bc.GetTrxOpts()
-	if err := txOpts.FillFromChain(a.bc.Api); err != nil {
-		return nil, fmt.Errorf("filling tx opts: %s", err)
-	}
-
 	asset, err := utils.ToBetAsset(deposit)
 	if err != nil {
 		return nil, err
@@ -170,42 +180,10 @@ func (a *GameSessionsUseCase) NewSession(
 		return nil, err
 	}
 
-	var transferAction *eos.Action
-
-	if realAsset.Amount > 0 {
-		// Add transfer deposit action
-		transferAction, err = a.getTransferAction(user.AccountName, game.Contract, casino.Contract, sessionId, realAsset)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	newGameAction := a.getNewGameAction(bonusAsset, game.Contract, user, sessionId, casino.Id)
-
-	firstGameAction := &eos.Action{
-		Account: eos.AN(game.Contract),
-		Name:    eos.ActN("gameaction"),
-		Authorization: []eos.PermissionLevel{{
-			Actor:      eos.AN(a.platformContract),
-			Permission: eos.PN("gameaction"),
-		}},
-		ActionData: eos.NewActionData(struct {
-			SessionId    uint64   `json:"ses_id"`
-			ActionType   uint16   `json:"type"`
-			ActionParams []uint64 `json:"params"`
-		}{
-			SessionId:    sessionId,
-			ActionType:   actionType,
-			ActionParams: actionParams,
-		}),
-	}
-
 	gameSession := &models.GameSession{
-		ID:              sessionId,
 		Player:          user.AccountName,
 		CasinoID:        casino.Id,
 		GameID:          game.Id,
-		BlockchainSesID: sessionId,
 		State:           models.NewGameTrxSent,
 		LastOffset:      0,
 		Deposit:         asset,
@@ -214,83 +192,53 @@ func (a *GameSessionsUseCase) NewSession(
 		StateBeforeFail: nil,
 	}
 
-	if err := a.repo.AddGameSession(ctx, gameSession); err != nil {
-		return nil, err
-	}
+	// actions are built before session is stored, so failure doesn't leave orphan session
+	err = a.addSessionWithNewID(ctx, game.Contract, gameSession, func(sessionId uint64) (*models.CasinoTrx, error) {
+		var transferAction *eos.Action
+		if realAsset.Amount > 0 {
+			var err error
+			// Add transfer deposit action
+			transferAction, err = a.getTransferAction(user.AccountName, game.Contract, casino.Contract, sessionId, realAsset)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	err = a.repo.AddGameSessionUpdate(ctx, &models.GameSessionUpdate{
-		SessionID:  sessionId,
-		UpdateType: models.SessionCreatedUpdate,
-		Timestamp:  time.Now(),
-		Data:       nil,
-		Offset:     nil,
+		newGameAction := a.getNewGameAction(bonusAsset, game.Contract, user, sessionId, casino.Id)
+
+		firstGameAction := &eos.Action{
+			Account: eos.AN(game.Contract),
+			Name:    eos.ActN("gameaction"),
+			Authorization: []eos.PermissionLevel{{
+				Actor:      eos.AN(a.platformContract),
+				Permission: eos.PN("gameaction"),
+			}},
+			ActionData: eos.NewActionData(struct {
+				SessionId    uint64   `json:"ses_id"`
+				ActionType   uint16   `json:"type"`
+				ActionParams []uint64 `json:"params"`
+			}{
+				SessionId:    sessionId,
+				ActionType:   actionType,
+				ActionParams: actionParams,
+			}),
+		}
+
+		actions := []*eos.Action{newGameAction, firstGameAction}
+		if transferAction != nil {
+			actions = []*eos.Action{transferAction, newGameAction, firstGameAction}
+		}
+		return newCasinoTrx(models.NewGameCasinoTrx, gameSession, actions, actionType, actionParams, nil)
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	log.Debug().Msgf("Created new session, sessionID: %d", sessionId)
-
-	// no need to wait response, because that can cause race between action monitor event and casino response
-	// sometimes action monitor event about created session handles earlier than casino response
-	go func() {
-		var trx *eos.Transaction
-		if transferAction == nil {
-			trx = eos.NewTransaction([]*eos.Action{newGameAction, firstGameAction}, txOpts)
-		} else {
-			trx = eos.NewTransaction([]*eos.Action{transferAction, newGameAction, firstGameAction}, txOpts)
-		}
-		trxID, err := a.trxByCasino(casino, trx)
-		if err != nil {
-			log.Info().Msgf("Error while newgame trx, sessionID: %d, error: %s", sessionId, err.Error())
-
-			e := a.repo.UpdateSessionState(ctx, sessionId, models.GameFailed)
-			if e != nil {
-				log.Error().Msgf("Error while updating state to failed: %s", e.Error())
-				return
-			}
-
-			failedUpdateData, e := json.Marshal(struct {
-				Details string `json:"details"`
-			}{
-				Details: err.Error(),
-			})
-			if e != nil {
-				log.Error().Msgf("Error while marshal failed update date: %s", e.Error())
-				return
-			}
+	log.Debug().Msgf("Created new session, sessionID: %d", gameSession.ID)
 
-			e = a.repo.AddGameSessionUpdate(ctx, &models.GameSessionUpdate{
-				SessionID:  sessionId,
-				UpdateType: models.GameFailedUpdate,
-				Timestamp:  time.Now(),
-				Data:       failedUpdateData,
-				Offset:     nil,
-			})
-			if e != nil {
-				log.Error().Msgf("Error pushing session failed update: %s", err.Error())
-				return
-			}
-
-			//TODO move notify logic to AddGameSessionUpdate
-			updates, e := a.repo.GetGameSessionUpdates(ctx, sessionId)
-			if e != nil {
-				log.Error().Msgf("Error fetching session updates: %s", e.Error())
-				return
-			}
-			updateMsgs := make([]*models.GameSessionUpdateMsg, len(updates))
-			for i, update := range updates {
-				updateMsgs[i] = models.ToGameSessionUpdateMsg(update)
-			}
-			go a.subsUseCase.Notify(user.AccountName, "session_update", updateMsgs)
-			return
-		}
-		if err = a.repo.AddGameSessionTransaction(ctx, trxID.String(), sessionId, actionType, actionParams); err != nil {
-			log.Warn().Msgf("Failed to add transaction to game_transactions_table, "+
-				"sessionID: %d, trxID: %s, reason: %s", sessionId, trxID.String(), err.Error())
-		}
-		log.Info().Msgf("Successfully sent newgame trx, sessionID: %d, trxID: %s", sessionId, trxID.String())
-	}()
+	// transaction is sent by outbox workers, no need to wait casino response here,
+	// because that can cause race between action monitor event and casino response
+	a.wakeupCasinoTrxOutbox()
 
 	return gameSession, nil
 }
@@ -301,11 +249,62 @@ func (a *GameSessionsUseCase) GameAction(
 	actionType uint16,
 	actionParams []uint64,
 ) error {
+	unlock := a.sessionLocks.lock(sessionId)
+	defer unlock()
+
 	gs, err := a.repo.GetGameSession(ctx, sessionId)
 	if err != nil {
 		return err
 	}
 
+	if err = a.startGameAction(ctx, gs); err != nil {
+		log.Debug().Msgf("Failed to update session state, "+
+			"reason: %s", err.Error())
+		return err
+	}
+
+	if err = a.pushGameAction(ctx, gs, actionType, actionParams); err != nil {
+		a.cancelGameAction(ctx, gs)
+		return err
+	}
+	return nil
+}
+
+func (a *GameSessionsUseCase) PushGameAction(
+	ctx context.Context,
+	session *models.GameSession,
+	actionType uint16,
+	actionParams []uint64,
+) error {
+	unlock := a.sessionLocks.lock(session.ID)
+	defer unlock()
+
+	// session version is changed since it was moved to GameActionTrxSent
+	gs, err := a.repo.GetGameSession(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+	if gs.State != models.GameActionTrxSent {
+		log.Warn().Msgf("Game action isn't pushed, sessionID: %d, state: %d", gs.ID, gs.State)
+		return nil
+	}
+
+	if err = a.pushGameAction(ctx, gs, actionType, actionParams); err != nil {
+		// otherwise session waits for lost trx until recovery
+		if e := a.requestGameActionAgain(ctx, gs, err); e != nil {
+			log.Error().Msgf("Failed to request game action again, sessionID: %d, reason: %s", gs.ID, e.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+func (a *GameSessionsUseCase) pushGameAction(
+	ctx context.Context,
+	gs *models.GameSession,
+	actionType uint16,
+	actionParams []uint64,
+) error {
 	game, err := a.contractsRepo.GetGame(ctx, gs.GameID)
 	if err != nil {
 		return err
@@ -338,17 +337,11 @@ func (a *GameSessionsUseCase) GameAction(
 		return err
 	}
 
-	log.Info().Msgf("Successfully sent game action trx, sessionID: %d, trxID: %s", sessionId, trxID.String())
+	log.Info().Msgf("Successfully sent game action trx, sessionID: %d, trxID: %s", gs.ID, trxID.String())
 
-	err = a.repo.UpdateSessionState(ctx, sessionId, models.GameActionTrxSent)
-	if err != nil {
-		log.Debug().Msgf("Failed to update session state, "+
-			"reason: %s", err.Error())
-		return err
-	}
-	if err = a.repo.AddGameSessionTransaction(ctx, trxID.String(), sessionId, actionType, actionParams); err != nil {
+	if err = a.repo.AddGameSessionTransaction(ctx, trxID.String(), gs.ID, actionType, actionParams); err != nil {
 		log.Warn().Msgf("Failed to add transaction to game_transactions_table, "+
-			"sessionID: %d, trxID: %s, reason: %s", sessionId, trxID.String(), err.Error())
+			"sessionID: %d, trxID: %s, reason: %s", gs.ID, trxID.String(), err.Error())
 		return err
 	}
 	return nil
@@ -361,6 +354,9 @@ func (a *GameSessionsUseCase) GameActionWithDeposit(
 	actionParams []uint64,
 	deposit string,
 ) error {
+	unlock := a.sessionLocks.lock(sessionId)
+	defer unlock()
+
 	gs, err := a.repo.GetGameSession(ctx, sessionId)
 	if err != nil {
 		return err
@@ -439,11 +435,6 @@ func (a *GameSessionsUseCase) GameActionWithDeposit(
 		}),
 	}
 
-	txOpts := a.bc.GetTrxOpts()
-	if err := txOpts.FillFromChain(a.bc.Api); err != nil {
-		return fmt.Errorf("filling tx opts: %s", err)
-	}
-
 	trxActions := make([]*eos.Action, 0, 3)
 	if transferAction != nil {
 		trxActions = append(trxActions, transferAction)
@@ -453,32 +444,89 @@ func (a *GameSessionsUseCase) GameActionWithDeposit(
 	}
 	trxActions = append(trxActions, gameAction)
 
-	trx := eos.NewTransaction(trxActions, txOpts)
-	trxID, err := a.trxByCasino(casino, trx)
+	if err = a.startGameAction(ctx, gs); err != nil {
+		log.Debug().Msgf("%s", err.Error())
+		return err
+	}
+
+	// deposit is added to the session after casino accepts transaction
+	err = a.enqueueCasinoTrx(ctx, models.GameActionCasinoTrx, gs, trxActions, actionType, actionParams, asset)
 	if err != nil {
+		a.cancelGameAction(ctx, gs)
 		return err
 	}
+	return nil
+}
 
-	log.Info().Msgf("Successfully sent game action with deposit trx, sessionID: %d, trxID: %s", sessionId, trxID.String())
+// startGameAction moves session to GameActionTrxSent state,
+// only one of concurrent actions succeeds, others get ErrConcurrentGameAction.
+// Actions of one process are serialized by session lock, so the later one sees action already sent,
+// version conflict means action of other process. Other transitions are checked by repository state machine.
+func (a *GameSessionsUseCase) startGameAction(ctx context.Context, gs *models.GameSession) error {
+	if gs.State == models.GameActionTrxSent {
+		return gamesessions.ErrConcurrentGameAction
+	}
 
-	err = a.repo.UpdateSessionState(ctx, sessionId, models.GameActionTrxSent)
+	err := a.repo.CompareAndSwapSessionState(ctx, gs.ID, gs.Version, models.GameActionTrxSent)
+	if err == gamesessions.ErrSessionVersionConflict {
+		return gamesessions.ErrConcurrentGameAction
+	}
+	return err
+}
+
+// cancelGameAction returns session to the state before startGameAction if action wasn't sent,
+// so first action is sent again on GameStartedInBC and the player can repeat requested one
+func (a *GameSessionsUseCase) cancelGameAction(ctx context.Context, gs *models.GameSession) {
+	if gs.State != models.RequestedGameAction && gs.State != models.GameStartedInBC {
+		return
+	}
+
+	err := a.repo.CompareAndSwapSessionState(ctx, gs.ID, gs.Version+1, gs.State)
+	if err != nil {
+		log.Warn().Msgf("Failed to cancel game action, sessionID: %d, reason: %s", gs.ID, err.Error())
+	}
+}
+
+// requestedAgainUpdateData is data of action requested update added by backend
+type requestedAgainUpdateData struct {
+	Details string `json:"details"`
+}
+
+// requestGameActionAgain moves session whose action trx is lost to RequestedGameAction and notifies player,
+// so the player sends action again, session changed since it was read is left as is.
+// Caller must hold session lock.
+func (a *GameSessionsUseCase) requestGameActionAgain(ctx context.Context, session *models.GameSession, cause error) error {
+	data, err := json.Marshal(&requestedAgainUpdateData{Details: cause.Error()})
 	if err != nil {
-		log.Debug().Msgf("%s", err.Error())
 		return err
 	}
+	update := &models.GameSessionUpdate{
+		SessionID:  session.ID,
+		UpdateType: models.GameActionRequestedUpdate,
+		Timestamp:  time.Now(),
+		Data:       data,
+		Offset:     nil,
+	}
 
-	totalDeposit := gs.Deposit.Add(*asset)
-	err = a.repo.UpdateSessionDeposit(ctx, sessionId, totalDeposit.String())
+	uow, err := a.repo.BeginUnitOfWork(ctx)
 	if err != nil {
-		log.Error().Msgf("Failed to update session deposit, "+
-			"sesID: %d, trxID: %s, reason: %s", sessionId, trxID.String(), err.Error())
 		return err
 	}
-	if err = a.repo.AddGameSessionTransaction(ctx, trxID.String(), sessionId, actionType, actionParams); err != nil {
-		log.Warn().Msgf("Failed to add transaction to game_transactions_table, "+
-			"sesID: %d, trxID: %s, reason: %s", sessionId, trxID.String(), err.Error())
+	defer func() {
+		_ = uow.Rollback(ctx)
+	}()
+
+	if err := uow.CompareAndSwapSessionState(ctx, session.ID, session.Version, models.RequestedGameAction); err != nil {
+		return err
+	}
+	if err := uow.AddGameSessionUpdate(ctx, update); err != nil {
 		return err
 	}
+	if err := uow.Commit(ctx); err != nil {
+		return err
+	}
+
+	a.notifyBackendUpdate(ctx, session, update)
 	return nil
 }
 
@@ -503,54 +551,32 @@ func (a *GameSessionsUseCase) getTransferAction(
 	return transferAction, nil
 }
 
-func (a *GameSessionsUseCase) trxByCasino(casino *models.Casino, trx *eos.Transaction) (eos.Checksum256, error) {
+// signTrxForCasino sponsors and signs transaction by platform keys, casino signs and pushes it then
+func (a *GameSessionsUseCase) signTrxForCasino(trx *eos.Transaction) (*eos.SignedTransaction, string, error) {
 	// Add sponsorship to the transaction
 	sponsoredTrx, err := a.bc.GetSponsoredTrx(trx)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Sign transaction with GameAction and deposit platform keys
 	requiredKeys := []ecc.PublicKey{a.bc.PubKeys.GameAction, a.bc.PubKeys.Deposit}
-	signedTrx, err := a.bc.Api.Signer.Sign(sponsoredTrx, a.bc.ChainId, requiredKeys...)
+	signedTrx, err := a.bc.SignTransaction(sponsoredTrx, requiredKeys...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	packedTrx, _, err := signedTrx.PackedTransactionAndCFD()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	h := sha256.New()
 	_, _ = h.Write(packedTrx)
-	trxID := h.Sum(nil)
+	trxID := hex.EncodeToString(h.Sum(nil))
 
-	toSend, _ := json.Marshal(signedTrx)
-	log.Debug().Msgf("Prepared trx for casino, trx_id: %s, trx: %s", hex.EncodeToString(trxID), string(toSend))
-
-	// Send sponsored and signed transaction to casino Backend
-	reader := bytes.NewReader(toSend)
-	resp, err := http.Post(casino.Meta.ApiURL+"/sign_transaction", "application/json", reader)
-	if err != nil {
-		log.Debug().Msgf("Casino request error: %s", err.Error())
-		return nil, err
-	}
-	// don't forget to close response body
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		errBody, _ := ioutil.ReadAll(resp.Body)
-		log.Debug().Msgf("deposit error from casino back, code %s, body: %s", resp.Status, string(errBody))
-		//TODO handle error types
-		casError := &casinoError{}
-		e := json.Unmarshal(errBody, casError)
-		if e != nil {
-			return nil, fmt.Errorf("unknown casino error")
-		}
-		return nil, fmt.Errorf("%s", casError.Error)
-	}
+	log.Debug().Msgf("Prepared trx for casino, trx_id: %s", trxID)
 
-	return trxID, nil
+	return signedTrx, trxID, nil
 }
 
 func (a *GameSessionsUseCase) getAssets(asset *eos.Asset, playerInfo *models.PlayerInfo, casinoId uint64) (*eos.Asset, *eos.Asset, error) {
@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"platform-backend/blockchain"
+	"platform-backend/contracts"
+	"platform-backend/contracts/repository/mock"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/game_sessions/repository/localstorage"
+	"platform-backend/models"
+	subsusecase "platform-backend/subscription/usecase"
+	"platform-backend/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartGameAction(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, nil, "platform", nil, nil, nil, nil, nil, nil)
+
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.RequestedGameAction}))
+	gs, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, uc.startGameAction(ctx, gs))
+	// stale session version means concurrent action
+	assert.Equal(t, gamesessions.ErrConcurrentGameAction, uc.startGameAction(ctx, gs))
+
+	// action of the same process sees the first one already sent
+	sent, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, gamesessions.ErrConcurrentGameAction, uc.startGameAction(ctx, sent))
+
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 2, State: models.GameFailed}))
+	failed, err := repo.GetGameSession(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, gamesessions.ErrIllegalStateTransition, uc.startGameAction(ctx, failed))
+}
+
+func TestConcurrentGameActions(t *testing.T) {
+	ctx := context.Background()
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/chain/get_info" {
+			id := strings.Repeat("00", 32)
+			_, _ = fmt.Fprintf(w, `{"chain_id": "%s", "head_block_id": "%s", "last_irreversible_block_id": "%s"}`,
+				id, id, id)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer node.Close()
+
+	privateKey, err := ecc.NewRandomPrivateKey()
+	require.NoError(t, err)
+	signer, err := blockchain.NewLocalSigner(privateKey.String())
+	require.NoError(t, err)
+	bc := &blockchain.Blockchain{
+		Api:     eos.New(node.URL),
+		Signer:  signer,
+		PubKeys: &blockchain.PubKeys{GameAction: privateKey.PublicKey()},
+		ChainId: make([]byte, 32),
+	}
+
+	reg := prometheus.NewRegistry()
+	repo := localstorage.NewGameSessionsLocalRepoWithStateMachine(gamesessions.NewStateMachine(reg))
+	contractsRepo := mock.NewMockedListingRepo()
+	contractsRepo.AddGame(&models.Game{Id: 1, Contract: "game"})
+	uc := NewGameSessionsUseCase(bc, repo, contractsRepo, "platform", nil, nil, nil, nil, nil, nil)
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, GameID: 1, State: models.RequestedGameAction}))
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- uc.GameAction(ctx, 1, 0, []uint64{1})
+		}()
+	}
+	results := []error{<-errs, <-errs}
+	assert.Contains(t, results, nil)
+	assert.Contains(t, results, gamesessions.ErrConcurrentGameAction)
+
+	// double click isn't illegal transition
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		assert.Empty(t, family.GetMetric(), family.GetName())
+	}
+}
+
+func TestCancelGameAction(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, nil, "platform", nil, nil, nil, nil, nil, nil)
+
+	for id, state := range map[uint64]models.GameSessionState{
+		1: models.GameStartedInBC,
+		2: models.RequestedGameAction,
+	} {
+		require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: id, State: state}))
+		gs, err := repo.GetGameSession(ctx, id)
+		require.NoError(t, err)
+
+		require.NoError(t, uc.startGameAction(ctx, gs))
+		uc.cancelGameAction(ctx, gs)
+
+		canceled, err := repo.GetGameSession(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, state, canceled.State)
+	}
+}
+
+func TestPushGameActionRequestsActionAgain(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, mock.NewMockedListingRepo(), "platform",
+		subsusecase.NewSubscriptionUseCase(repo), nil, nil, nil, nil, nil)
+
+	require.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, State: models.GameStartedInBC}))
+	gs, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateSessionState(ctx, 1, models.GameActionTrxSent))
+
+	// game isn't listed, so trx can't be pushed
+	assert.Equal(t, contracts.GameNotFound, uc.PushGameAction(ctx, gs, 1, nil))
+
+	gs, err = repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.RequestedGameAction, gs.State)
+	updates, err := repo.GetGameSessionUpdates(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	assert.Equal(t, models.GameActionRequestedUpdate, updates[0].UpdateType)
+	assert.Nil(t, updates[0].Offset)
+}
+
+func TestAddNewSession(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, nil, "platform", nil, nil, nil, nil, nil, nil)
+
+	deposit, err := utils.ToBetAsset("1.0000")
+	require.NoError(t, err)
+	session := &models.GameSession{ID: 1, BlockchainSesID: 1, State: models.NewGameTrxSent, Deposit: deposit}
+	trx, err := newCasinoTrx(models.NewGameCasinoTrx, session, nil, 0, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, uc.addNewSession(ctx, session, trx))
+
+	updates, err := repo.GetGameSessionUpdates(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	assert.Equal(t, models.SessionCreatedUpdate, updates[0].UpdateType)
+	pending, err := repo.HasPendingCasinoTrx(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, pending)
+
+	// nothing is stored on id collision
+	trx, err = newCasinoTrx(models.NewGameCasinoTrx, session, nil, 0, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, gamesessions.ErrGameSessionAlreadyExists, uc.addNewSession(ctx, session, trx))
+	claimed, err := repo.ClaimCasinoTrxs(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 1)
+}
+
+func TestCompleteCasinoTrx(t *testing.T) {
+	ctx := context.Background()
+	repo := localstorage.NewGameSessionsLocalRepo()
+	uc := NewGameSessionsUseCase(nil, repo, nil, "platform", nil, nil, nil, nil, nil, nil)
+
+	deposit, err := utils.ToBetAsset("1.0000")
+	require.NoError(t, err)
+	session := &models.GameSession{ID: 1, State: models.GameActionTrxSent, Deposit: deposit}
+	require.NoError(t, repo.AddGameSession(ctx, session))
+	trx, err := newCasinoTrx(models.GameActionCasinoTrx, session, nil, 1, []uint64{2}, deposit)
+	require.NoError(t, err)
+	require.NoError(t, repo.AddCasinoTrx(ctx, trx))
+
+	// deposit added after session is read isn't lost
+	require.NoError(t, repo.UpdateSessionDeposit(ctx, 1, "3.0000"))
+	require.NoError(t, uc.completeCasinoTrx(ctx, trx, session, "trx1"))
+
+	stored, err := repo.GetGameSession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "4.0000 BET", stored.Deposit.String())
+	pending, err := repo.HasPendingCasinoTrx(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, pending)
+	trxs, err := repo.GetGameSessionTransactions(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, trxs, 1)
+	assert.Equal(t, "trx1", trxs[0].TrxID)
+}
+
+func TestAddSessionWithNewIDStoresNothingOnTrxError(t *testing.T) {
+	ctx := context.Background()
+	// game contract has no sessions
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"rows": [], "more": false}`))
+	}))
+	defer node.Close()
+
+	repo := localstorage.NewGameSessionsLocalRepo()
+	bc := &blockchain.Blockchain{Api: eos.New(node.URL)}
+	uc := NewGameSessionsUseCase(bc, repo, nil, "platform", nil, nil, nil, nil, nil, nil)
+
+	buildErr := errors.New("can't build trx")
+	session := &models.GameSession{State: models.NewGameTrxSent}
+	err := uc.addSessionWithNewID(ctx, "game", session, func(id uint64) (*models.CasinoTrx, error) {
+		return nil, buildErr
+	})
+	assert.Equal(t, buildErr, err)
+
+	sessions, err := repo.GetSessionsByStates(ctx, []models.GameSessionState{models.NewGameTrxSent})
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
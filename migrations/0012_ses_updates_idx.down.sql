@@ -0,0 +1 @@
+DROP INDEX ses_updates_ses_idx;
@@ -0,0 +1 @@
+CREATE INDEX ses_updates_ses_idx ON game_session_updates (ses_id, timestamp);
@@ -0,0 +1,2 @@
+DROP INDEX sessions_player_keyset_idx;
+DROP INDEX sessions_state_keyset_idx;
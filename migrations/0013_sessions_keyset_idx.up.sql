@@ -0,0 +1,2 @@
+CREATE INDEX sessions_player_keyset_idx ON game_sessions (player, last_update DESC, id DESC);
+CREATE INDEX sessions_state_keyset_idx ON game_sessions (state, last_update DESC, id DESC);
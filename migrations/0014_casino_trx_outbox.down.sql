@@ -0,0 +1 @@
+DROP TABLE casino_trx_outbox;
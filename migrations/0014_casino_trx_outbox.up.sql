@@ -0,0 +1,20 @@
+CREATE TABLE casino_trx_outbox
+(
+    id            BIGSERIAL PRIMARY KEY,
+    ses_id        NUMERIC REFERENCES game_sessions (id),
+    casino_id     NUMERIC   NOT NULL,
+    kind          SMALLINT  NOT NULL,
+    trx           BYTEA     NOT NULL,
+    signed_trx    BYTEA,
+    action_type   SMALLINT  NOT NULL,
+    action_params NUMERIC[] NOT NULL,
+    deposit       VARCHAR(64),
+    status        SMALLINT  NOT NULL DEFAULT 0,
+    attempts      INTEGER   NOT NULL DEFAULT 0,
+    next_attempt  TIMESTAMP NOT NULL DEFAULT now(),
+    last_error    TEXT,
+    trx_id        VARCHAR(64),
+    created       TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX casino_trx_outbox_pending_idx ON casino_trx_outbox (next_attempt) WHERE status = 0;
@@ -0,0 +1,2 @@
+ALTER TABLE game_sessions
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+CREATE TABLE events_offset
+(
+    source   TEXT PRIMARY KEY,
+    "offset" NUMERIC NOT NULL
+);
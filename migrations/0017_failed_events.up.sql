@@ -0,0 +1,14 @@
+CREATE TABLE failed_events
+(
+    id           BIGSERIAL PRIMARY KEY,
+    ses_id       NUMERIC   NOT NULL,
+    "offset"     NUMERIC   NOT NULL UNIQUE,
+    event        JSONB     NOT NULL,
+    error        TEXT      NOT NULL,
+    status       SMALLINT  NOT NULL DEFAULT 0,
+    attempts     INTEGER   NOT NULL DEFAULT 1,
+    next_attempt TIMESTAMP NOT NULL DEFAULT now(),
+    created      TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX failed_events_ses_idx ON failed_events (ses_id, "offset") WHERE status IN (0, 1);
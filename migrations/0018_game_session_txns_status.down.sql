@@ -0,0 +1,13 @@
+DROP INDEX game_session_txns_pending_idx;
+DROP INDEX game_session_txns_ses_idx;
+
+ALTER TABLE game_session_txns
+    DROP COLUMN next_check;
+ALTER TABLE game_session_txns
+    DROP COLUMN check_attempts;
+ALTER TABLE game_session_txns
+    DROP COLUMN created;
+ALTER TABLE game_session_txns
+    DROP COLUMN block_num;
+ALTER TABLE game_session_txns
+    DROP COLUMN status;
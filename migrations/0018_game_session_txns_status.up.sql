@@ -0,0 +1,16 @@
+-- transactions pushed before tracking have unknown status
+ALTER TABLE game_session_txns
+    ADD COLUMN status SMALLINT NOT NULL DEFAULT 4;
+ALTER TABLE game_session_txns
+    ALTER COLUMN status SET DEFAULT 0;
+ALTER TABLE game_session_txns
+    ADD COLUMN block_num BIGINT;
+ALTER TABLE game_session_txns
+    ADD COLUMN created BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE game_session_txns
+    ADD COLUMN check_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE game_session_txns
+    ADD COLUMN next_check BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX game_session_txns_ses_idx ON game_session_txns (ses_id);
+CREATE INDEX game_session_txns_pending_idx ON game_session_txns (next_check) WHERE status IN (0, 1);
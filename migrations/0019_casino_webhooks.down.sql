@@ -0,0 +1 @@
+DROP TABLE casino_webhooks;
@@ -0,0 +1,17 @@
+CREATE TABLE casino_webhooks
+(
+    id           BIGSERIAL PRIMARY KEY,
+    casino_id    NUMERIC     NOT NULL,
+    ses_id       NUMERIC     NOT NULL,
+    event        VARCHAR(32) NOT NULL,
+    payload      JSONB       NOT NULL,
+    status       SMALLINT    NOT NULL DEFAULT 0,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    next_attempt TIMESTAMP   NOT NULL DEFAULT now(),
+    last_error   TEXT,
+    created      TIMESTAMP   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX casino_webhooks_pending_idx ON casino_webhooks (next_attempt) WHERE status = 0;
+CREATE INDEX casino_webhooks_casino_idx ON casino_webhooks (casino_id, id);
+CREATE INDEX casino_webhooks_ses_idx ON casino_webhooks (ses_id);
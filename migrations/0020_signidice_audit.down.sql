@@ -0,0 +1 @@
+DROP TABLE signidice_audit;
@@ -0,0 +1,16 @@
+CREATE TABLE signidice_audit
+(
+    id            BIGSERIAL PRIMARY KEY,
+    ses_id        NUMERIC     NOT NULL,
+    bc_ses_id     NUMERIC     NOT NULL,
+    game_contract VARCHAR(13) NOT NULL,
+    digest        BYTEA       NOT NULL,
+    signature     TEXT        NOT NULL,
+    key_id        TEXT        NOT NULL,
+    status        SMALLINT    NOT NULL DEFAULT 0,
+    trx_id        VARCHAR(64),
+    last_error    TEXT,
+    created       TIMESTAMP   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX signidice_audit_ses_idx ON signidice_audit (ses_id);
@@ -2,6 +2,8 @@ package models
 
 type CasinoMeta struct {
 	ApiURL string `json:"apiUrl"`
+	// optional, session lifecycle events are posted to it
+	WebhookURL string `json:"webhookUrl"`
 }
 
 type Casino struct {
@@ -9,6 +11,8 @@ type Casino struct {
 	Contract string      `json:"contract"`
 	Paused   bool        `json:"paused"`
 	Meta     *CasinoMeta `json:"meta"`
+	// signidice public key
+	RsaPubkey string `json:"rsaPubkey"`
 }
 
 type GameParam struct {
@@ -0,0 +1,37 @@
+package models
+
+import "github.com/eoscanada/eos-go"
+
+type CasinoTrxKind uint16
+
+const (
+	NewGameCasinoTrx CasinoTrxKind = iota
+	GameActionCasinoTrx
+)
+
+type CasinoTrxStatus uint16
+
+const (
+	CasinoTrxPending CasinoTrxStatus = iota
+	CasinoTrxSent
+	CasinoTrxFailed
+)
+
+// CasinoTrx is outbox record of transaction that should be signed by casino
+type CasinoTrx struct {
+	ID        uint64
+	SessionID uint64
+	CasinoID  uint64
+	Kind      CasinoTrxKind
+	// packed transaction without header, header is filled when the new transaction is signed
+	Trx          []byte
+	ActionType   uint16
+	ActionParams []uint64
+	// deposit added to session by transaction
+	Deposit  *eos.Asset
+	Status   CasinoTrxStatus
+	Attempts int
+	// id and packed signed transaction of the last attempt, it's sent again until it's expired
+	TrxID     string
+	SignedTrx []byte
+}
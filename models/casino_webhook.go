@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/eoscanada/eos-go"
+	"time"
+)
+
+type CasinoWebhookEvent string
+
+const (
+	WebhookSessionStarted  CasinoWebhookEvent = "session_started"
+	WebhookSessionFinished CasinoWebhookEvent = "session_finished"
+	WebhookSessionFailed   CasinoWebhookEvent = "session_failed"
+)
+
+type CasinoWebhookStatus uint16
+
+const (
+	CasinoWebhookPending CasinoWebhookStatus = iota
+	CasinoWebhookDelivered
+	CasinoWebhookFailed
+)
+
+// CasinoWebhook is queued notification of casino about session lifecycle event
+type CasinoWebhook struct {
+	ID          uint64              `json:"id,string"`
+	CasinoID    uint64              `json:"casinoId,string"`
+	SessionID   uint64              `json:"sessionId,string"`
+	Event       CasinoWebhookEvent  `json:"event"`
+	Payload     json.RawMessage     `json:"payload"`
+	Status      CasinoWebhookStatus `json:"status"`
+	Attempts    int                 `json:"attempts"`
+	NextAttempt time.Time           `json:"nextAttempt"`
+	LastError   string              `json:"lastError"`
+	Created     time.Time           `json:"created"`
+}
+
+// CasinoWebhookPayload is body of webhook request
+type CasinoWebhookPayload struct {
+	Event           CasinoWebhookEvent `json:"event"`
+	SessionID       uint64             `json:"sessionId,string"`
+	CasinoID        uint64             `json:"casinoId,string"`
+	GameID          uint64             `json:"gameId,string"`
+	Player          string             `json:"player"`
+	Deposit         *eos.Asset         `json:"deposit"`
+	PlayerWinAmount *eos.Asset         `json:"playerWinAmount,omitempty"`
+	Details         string             `json:"details,omitempty"`
+	// unix time
+	Timestamp int64 `json:"timestamp"`
+}
+
+// NewCasinoWebhook makes webhook of session event
+func NewCasinoWebhook(event CasinoWebhookEvent, session *GameSession, playerWin *eos.Asset, details string) (*CasinoWebhook, error) {
+	payload, err := json.Marshal(&CasinoWebhookPayload{
+		Event:           event,
+		SessionID:       session.ID,
+		CasinoID:        session.CasinoID,
+		GameID:          session.GameID,
+		Player:          session.Player,
+		Deposit:         session.Deposit,
+		PlayerWinAmount: playerWin,
+		Details:         details,
+		Timestamp:       time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CasinoWebhook{
+		CasinoID:  session.CasinoID,
+		SessionID: session.ID,
+		Event:     event,
+		Payload:   payload,
+	}, nil
+}
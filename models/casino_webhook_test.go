@@ -0,0 +1,21 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCasinoWebhookPayloadIDs(t *testing.T) {
+	session := &GameSession{ID: 18446744073709551615, CasinoID: 2, GameID: 3, Player: "player"}
+	webhook, err := NewCasinoWebhook(WebhookSessionStarted, session, nil, "")
+	require.NoError(t, err)
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(webhook.Payload, &payload))
+	assert.Equal(t, "18446744073709551615", payload["sessionId"])
+	assert.Equal(t, "2", payload["casinoId"])
+	assert.Equal(t, "3", payload["gameId"])
+}
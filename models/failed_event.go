@@ -0,0 +1,31 @@
+package models
+
+import (
+	eventlistener "github.com/DaoCasino/platform-action-monitor-client"
+	"time"
+)
+
+type FailedEventStatus uint16
+
+const (
+	// waiting for retry
+	FailedEventPending FailedEventStatus = iota
+	// retry attempts are exhausted, can be requeued manually
+	FailedEventExhausted
+	FailedEventResolved
+	FailedEventDiscarded
+)
+
+// FailedEvent is action monitor event stored to dead-letter queue,
+// following events of the session are parked behind it to keep order
+type FailedEvent struct {
+	ID          uint64               `json:"id"`
+	SessionID   uint64               `json:"sessionId,string"`
+	Offset      uint64               `json:"offset"`
+	Event       *eventlistener.Event `json:"event"`
+	Error       string               `json:"error"`
+	Status      FailedEventStatus    `json:"status"`
+	Attempts    int                  `json:"attempts"`
+	NextAttempt time.Time            `json:"nextAttempt"`
+	Created     time.Time            `json:"created"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailedEventJSON(t *testing.T) {
+	data, err := json.Marshal(&FailedEvent{ID: 1, SessionID: 18446744073709551615})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"sessionId":"18446744073709551615"`)
+}
@@ -26,4 +26,6 @@ type GameSession struct {
 	LastUpdate      int64             `json:"lastUpdate"`
 	PlayerWinAmount *eos.Asset        `json:"playerWinAmount"`
 	StateBeforeFail *GameSessionState `json:"stateBeforeFail"`
+	// incremented on every state change
+	Version uint64 `json:"-"`
 }
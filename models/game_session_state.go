@@ -0,0 +1,65 @@
+package models
+
+// gameSessionTransitions lists states reachable from each state,
+// terminal states have no transitions
+var gameSessionTransitions = map[GameSessionState][]GameSessionState{
+	NewGameTrxSent: {
+		GameStartedInBC,
+		GameFinished,
+		GameFailed,
+	},
+	GameStartedInBC: {
+		RequestedGameAction,
+		GameActionTrxSent,
+		SignidicePartOneTrxSent,
+		GameFinished,
+		GameFailed,
+	},
+	RequestedGameAction: {
+		GameActionTrxSent,
+		GameFinished,
+		GameFailed,
+	},
+	GameActionTrxSent: {
+		// first action transaction isn't pushed, it's sent again
+		GameStartedInBC,
+		// action transaction lost, action is requested again
+		RequestedGameAction,
+		SignidicePartOneTrxSent,
+		GameFinished,
+		GameFailed,
+	},
+	// recovery redrive sends lost signidice trx again without state change,
+	// so SignidicePartOneTrxSent isn't reachable from itself
+	SignidicePartOneTrxSent: {
+		RequestedGameAction,
+		GameFinished,
+		GameFailed,
+	},
+}
+
+// CanTransitTo reports whether session can move from state s to state to
+func (s GameSessionState) CanTransitTo(to GameSessionState) bool {
+	for _, state := range gameSessionTransitions[s] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRepeatedTerminal reports whether terminal state s is set again,
+// it's no-op, e.g. contract fails session already failed by backend
+func (s GameSessionState) IsRepeatedTerminal(to GameSessionState) bool {
+	return s == to && s.IsTerminal()
+}
+
+// IsTerminal reports whether session in state s can't be changed anymore
+func (s GameSessionState) IsTerminal() bool {
+	return s == GameFinished || s == GameFailed
+}
+
+// IsTrxSent reports whether session in state s waits for its new game or game action transaction
+func (s GameSessionState) IsTrxSent() bool {
+	return s == NewGameTrxSent || s == GameActionTrxSent
+}
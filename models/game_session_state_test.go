@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameSessionStateTransitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    GameSessionState
+		to      GameSessionState
+		allowed bool
+	}{
+		{"newgame started", NewGameTrxSent, GameStartedInBC, true},
+		{"newgame failed", NewGameTrxSent, GameFailed, true},
+		{"newgame action requested", NewGameTrxSent, RequestedGameAction, false},
+		{"first action sent", GameStartedInBC, GameActionTrxSent, true},
+		{"started action requested", GameStartedInBC, RequestedGameAction, true},
+		{"started back to newgame", GameStartedInBC, NewGameTrxSent, false},
+		{"action sent", RequestedGameAction, GameActionTrxSent, true},
+		{"action requested signidice", RequestedGameAction, SignidicePartOneTrxSent, false},
+		{"action signidice", GameActionTrxSent, SignidicePartOneTrxSent, true},
+		{"action lost", GameActionTrxSent, RequestedGameAction, true},
+		{"first action not sent", GameActionTrxSent, GameStartedInBC, true},
+		{"action finished", GameActionTrxSent, GameFinished, true},
+		{"signidice next action", SignidicePartOneTrxSent, RequestedGameAction, true},
+		{"signidice finished", SignidicePartOneTrxSent, GameFinished, true},
+		{"signidice twice", SignidicePartOneTrxSent, SignidicePartOneTrxSent, false},
+		{"finished action requested", GameFinished, RequestedGameAction, false},
+		{"finished failed", GameFinished, GameFailed, false},
+		{"failed finished", GameFailed, GameFinished, false},
+		{"failed twice", GameFailed, GameFailed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, tt.from.CanTransitTo(tt.to))
+		})
+	}
+}
+
+func TestGameSessionStateTerminal(t *testing.T) {
+	for from := NewGameTrxSent; from <= GameFailed; from++ {
+		for to := NewGameTrxSent; to <= GameFailed; to++ {
+			if from.IsTerminal() {
+				assert.False(t, from.CanTransitTo(to), "transition from terminal state %d to %d", from, to)
+			}
+		}
+	}
+	assert.True(t, GameFinished.IsTerminal())
+	assert.True(t, GameFailed.IsTerminal())
+	assert.False(t, RequestedGameAction.IsTerminal())
+	assert.True(t, GameFailed.IsRepeatedTerminal(GameFailed))
+	assert.True(t, GameFinished.IsRepeatedTerminal(GameFinished))
+	assert.False(t, GameFinished.IsRepeatedTerminal(GameFailed))
+	assert.False(t, SignidicePartOneTrxSent.IsRepeatedTerminal(SignidicePartOneTrxSent))
+}
@@ -0,0 +1,34 @@
+package models
+
+type GameSessionTrxStatus int16
+
+const (
+	// pushed, but not found in blockchain yet
+	TrxPending GameSessionTrxStatus = iota
+	// included to reversible block
+	TrxExecuted
+	TrxIrreversible
+	// expired or failed, will never be executed
+	TrxDropped
+	// pushed before status tracking
+	TrxStatusUnknown
+)
+
+func (s GameSessionTrxStatus) IsFinal() bool {
+	return s != TrxPending && s != TrxExecuted
+}
+
+type GameSessionTrx struct {
+	TrxID        string               `json:"trxId"`
+	SessionID    uint64               `json:"sessionId,string"`
+	ActionType   uint16               `json:"actionType"`
+	ActionParams []uint64             `json:"actionParams"`
+	Status       GameSessionTrxStatus `json:"status"`
+	BlockNum     *uint32              `json:"blockNum"`
+	// unix time
+	Created int64 `json:"created"`
+	// status checks count, the next check is postponed with backoff
+	CheckAttempts int `json:"checkAttempts"`
+	// unix time
+	NextCheck int64 `json:"nextCheck"`
+}
@@ -14,6 +14,8 @@ type GameSessionUpdateMsg struct {
 	Timestamp  time.Time             `json:"timestamp"`
 	Data       json.RawMessage       `json:"data"`
 	Offset     *uint64               `json:"offset"`
+	// offset of the previous session update, lets client detect missed updates
+	PrevOffset *uint64 `json:"prevOffset"`
 }
 
 func ToGameSessionUpdateMsg(u *GameSessionUpdate) *GameSessionUpdateMsg {
@@ -26,6 +28,20 @@ func ToGameSessionUpdateMsg(u *GameSessionUpdate) *GameSessionUpdateMsg {
 	}
 }
 
+// ToGameSessionUpdateMsgs converts updates linking every message with the previous update offset,
+// prevOffset is offset of the update preceding the first one
+func ToGameSessionUpdateMsgs(updates []*GameSessionUpdate, prevOffset *uint64) []*GameSessionUpdateMsg {
+	msgs := make([]*GameSessionUpdateMsg, len(updates))
+	for i, update := range updates {
+		msgs[i] = ToGameSessionUpdateMsg(update)
+		msgs[i].PrevOffset = prevOffset
+		if update.Offset != nil {
+			prevOffset = update.Offset
+		}
+	}
+	return msgs
+}
+
 type GameSessionUpdate struct {
 	SessionID  uint64                `json:"sessionId"`
 	UpdateType GameSessionUpdateType `json:"updateType"`
@@ -41,4 +57,6 @@ const (
 	GameMessageUpdate
 	GameFinishedUpdate
 	GameFailedUpdate
+	// raw event without specific handler
+	UnknownEventUpdate
 )
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/eoscanada/eos-go"
+	"time"
+)
+
+type SignidiceKey struct {
+	ID        string     `json:"id"`
+	NotBefore *time.Time `json:"notBefore"`
+	NotAfter  *time.Time `json:"notAfter"`
+	// key is inside validity window
+	Active bool `json:"active"`
+}
+
+type SignidiceAuditStatus uint16
+
+const (
+	// stored before transaction push
+	SignidiceAuditPending SignidiceAuditStatus = iota
+	SignidiceAuditSent
+	// transaction push failed or was rejected
+	SignidiceAuditFailed
+)
+
+// SignidiceAuditRecord is digest signed by platform for session
+type SignidiceAuditRecord struct {
+	ID              uint64          `json:"id,string"`
+	SessionID       uint64          `json:"sessionId,string"`
+	BlockchainSesID uint64          `json:"blockchainSesId,string"`
+	GameContract    string          `json:"gameContract"`
+	Digest          eos.Checksum256 `json:"digest"`
+	// base64 encoded as sent to contract
+	Signature string               `json:"signature"`
+	KeyID     string               `json:"keyId"`
+	Status    SignidiceAuditStatus `json:"status"`
+	// empty until transaction is sent
+	TrxID     string    `json:"trxId"`
+	LastError string    `json:"lastError"`
+	Created   time.Time `json:"created"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignidiceAuditRecordJSON(t *testing.T) {
+	data, err := json.Marshal(&SignidiceAuditRecord{
+		ID:              1,
+		SessionID:       18446744073709551615,
+		BlockchainSesID: 18446744073709551614,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id":"1"`)
+	assert.Contains(t, string(data), `"sessionId":"18446744073709551615"`)
+	assert.Contains(t, string(data), `"blockchainSesId":"18446744073709551614"`)
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"platform-backend/eventprocessor"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultFailedEventsLimit = 100
+	maxFailedEventsLimit     = 1000
+)
+
+type FailedEventsRequest struct {
+	Status *models.FailedEventStatus `json:"status"`
+	Limit  int                       `json:"limit"`
+}
+
+type FailedEventRequest struct {
+	ID uint64 `json:"id"`
+}
+
+type CasinoWebhooksRequest struct {
+	CasinoID  *eos.Uint64                 `json:"casinoId"`
+	SessionID *eos.Uint64                 `json:"sessionId"`
+	Status    *models.CasinoWebhookStatus `json:"status"`
+	Limit     int                         `json:"limit"`
+}
+
+type CasinoWebhookRequest struct {
+	ID eos.Uint64 `json:"id"`
+}
+
+type SessionTransactionsRequest struct {
+	SessionID eos.Uint64 `json:"sessionId"`
+}
+
+type SignidiceAuditRequest struct {
+	SessionID eos.Uint64 `json:"sessionId"`
+}
+
+// adminHandler checks bearer token from admin config before calling handler
+func adminHandler(app *App, handler func(*App, http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(app.config.Admin.Token)) != 1 {
+			respondWithError(w, http.StatusUnauthorized, "invalid admin token")
+			log.Warn().Msgf("Admin request with invalid token from %s", r.RemoteAddr)
+			return
+		}
+		handler(app, w, r)
+	}
+}
+
+func failedEventsHandler(app *App, w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msgf("New admin failed events request")
+
+	var req FailedEventsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		log.Debug().Msgf("Http body parse error, %s", err.Error())
+		return
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = defaultFailedEventsLimit
+	}
+	if req.Limit > maxFailedEventsLimit {
+		req.Limit = maxFailedEventsLimit
+	}
+
+	events, err := app.eventsRepo.GetFailedEvents(r.Context(), req.Status, req.Limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		log.Error().Msgf("Get failed events error: %s", err.Error())
+		return
+	}
+
+	respondOK(w, events)
+}
+
+func requeueEventHandler(app *App, w http.ResponseWriter, r *http.Request) {
+	updateFailedEvent(w, r, "requeue", app.eventsRepo.RequeueFailedEvent)
+}
+
+func discardEventHandler(app *App, w http.ResponseWriter, r *http.Request) {
+	updateFailedEvent(w, r, "discard", app.eventsRepo.DiscardFailedEvent)
+}
+
+func updateFailedEvent(
+	w http.ResponseWriter,
+	r *http.Request,
+	action string,
+	update func(ctx context.Context, id uint64) error,
+) {
+	log.Debug().Msgf("New admin %s event request", action)
+
+	var req FailedEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		log.Debug().Msgf("Http body parse error, %s", err.Error())
+		return
+	}
+
+	if err := update(r.Context(), req.ID); err != nil {
+		if errors.Is(err, eventprocessor.ErrFailedEventNotFound) {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		log.Error().Msgf("Failed event %s error: %s", action, err.Error())
+		return
+	}
+
+	log.Info().Msgf("Failed event %d %s by admin", req.ID, action)
+	respondOK(w, true)
+}
+
+func sessionTransactionsHandler(app *App, w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msgf("New admin session transactions request")
+
+	var req SessionTransactionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		log.Debug().Msgf("Http body parse error, %s", err.Error())
+		return
+	}
+
+	trxs, err := app.useCases.GameSession.GetSessionTransactions(r.Context(), uint64(req.SessionID))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		log.Error().Msgf("Get session transactions error: %s", err.Error())
+		return
+	}
+
+	respondOK(w, trxs)
+}
+
+func casinoWebhooksHandler(app *App, w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msgf("New admin casino webhooks request")
+
+	var req CasinoWebhooksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		log.Debug().Msgf("Http body parse error, %s", err.Error())
+		return
+	}
+
+	query := &gamesessions.CasinoWebhooksQuery{
+		Status: req.Status,
+		Limit:  req.Limit,
+	}
+	if req.CasinoID != nil {
+		casinoID := uint64(*req.CasinoID)
+		query.CasinoID = &casinoID
+	}
+	if req.SessionID != nil {
+		sessionID := uint64(*req.SessionID)
+		query.SessionID = &sessionID
+	}
+
+	webhooks, err := app.useCases.GameSession.GetCasinoWebhooks(r.Context(), query)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		log.Error().Msgf("Get casino webhooks error: %s", err.Error())
+		return
+	}
+
+	respondOK(w, webhooks)
+}
+
+func requeueWebhookHandler(app *App, w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msgf("New admin requeue webhook request")
+
+	var req CasinoWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		log.Debug().Msgf("Http body parse error, %s", err.Error())
+		return
+	}
+
+	if err := app.useCases.GameSession.RequeueCasinoWebhook(r.Context(), uint64(req.ID)); err != nil {
+		if errors.Is(err, gamesessions.ErrCasinoWebhookNotFound) {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		log.Error().Msgf("Casino webhook requeue error: %s", err.Error())
+		return
+	}
+
+	log.Info().Msgf("Casino webhook %d requeued by admin", req.ID)
+	respondOK(w, true)
+}
+
+func reloadSignidiceKeysHandler(app *App, w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msgf("New admin reload signidice keys request")
+
+	keys, err := app.useCases.Signidice.ReloadKeys(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		log.Error().Msgf("Signidice keys reload error: %s", err.Error())
+		return
+	}
+
+	log.Info().Msgf("Signidice keys reloaded by admin")
+	respondOK(w, keys)
+}
+
+func signidiceAuditHandler(app *App, w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msgf("New admin signidice audit request")
+
+	var req SignidiceAuditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		log.Debug().Msgf("Http body parse error, %s", err.Error())
+		return
+	}
+
+	records, err := app.useCases.Signidice.GetSessionAudit(r.Context(), uint64(req.SessionID))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		log.Error().Msgf("Get signidice audit error: %s", err.Error())
+		return
+	}
+
+	respondOK(w, records)
+}
@@ -21,6 +21,8 @@ type WsApi struct {
 	UseCases        *usecases.UseCases
 	Repos           *repositories.Repos
 	eventHistograms map[string]*prometheus.HistogramVec
+	// game requests are refused, e.g. in replay dry run
+	gameRequestsDisabled bool
 }
 
 func NewWsApi(useCases *usecases.UseCases, repos *repositories.Repos, registerer prometheus.Registerer) *WsApi {
@@ -43,10 +45,17 @@ func NewWsApi(useCases *usecases.UseCases, repos *repositories.Repos, registerer
 	return wsApi
 }
 
+// DisableGameRequests makes api refuse requests creating or changing sessions
+func (api *WsApi) DisableGameRequests() {
+	api.gameRequestsDisabled = true
+}
+
 type RequestHandlerInfo struct {
 	handler     func(context context.Context, req *ws_interface.ApiRequest) (interface{}, *ws_interface.HandlerError)
 	messageType int
 	needAuth    bool
+	// request creates or changes session
+	changesGame bool
 }
 
 var handlersMap = map[string]RequestHandlerInfo{
@@ -69,11 +78,13 @@ var handlersMap = map[string]RequestHandlerInfo{
 		handler:     handlers.ProcessNewGameRequest,
 		messageType: websocket.TextMessage,
 		needAuth:    true,
+		changesGame: true,
 	},
 	"game_action": {
 		handler:     handlers.ProcessGameActionRequest,
 		messageType: websocket.TextMessage,
 		needAuth:    true,
+		changesGame: true,
 	},
 	"fetch_session": {
 		handler:     handlers.ProcessFetchSessionRequest,
@@ -100,6 +111,16 @@ var handlersMap = map[string]RequestHandlerInfo{
 		messageType: websocket.TextMessage,
 		needAuth:    true,
 	},
+	"fetch_session_transactions": {
+		handler:     handlers.ProcessFetchSessionTransactionsRequest,
+		messageType: websocket.TextMessage,
+		needAuth:    true,
+	},
+	"fetch_signidice_audit": {
+		handler:     handlers.ProcessFetchSignidiceAuditRequest,
+		messageType: websocket.TextMessage,
+		needAuth:    true,
+	},
 	"fetch_casinos": {
 		handler:     handlers.ProcessFetchCasinosRequest,
 		messageType: websocket.TextMessage,
@@ -165,6 +186,11 @@ func (api *WsApi) ProcessRawRequest(context context.Context, messageType int, me
 			return respondWithError(messageObj.Id, ws_interface.UnauthorizedError), messageObj.Request, nil
 		}
 
+		if handler.changesGame && api.gameRequestsDisabled {
+			log.Info().Msgf("WS request from: %s refused, game requests are disabled", suid)
+			return respondWithError(messageObj.Id, ws_interface.GameRequestsDisabled), messageObj.Request, nil
+		}
+
 		start := time.Now()
 		// process request
 		wsResp, handlerError := handler.handler(context, &ws_interface.ApiRequest{
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"platform-backend/blockchain"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/server/api/ws_interface"
+)
+
+// newSessionHandlerError maps session creation errors to ws error codes,
+// casino requests are done later by outbox, so only casino config is checked here
+func newSessionHandlerError(err error) *ws_interface.HandlerError {
+	switch {
+	case errors.Is(err, gamesessions.ErrCasinoMetaEmpty),
+		errors.Is(err, gamesessions.ErrCasinoUrlNotDefined):
+		return ws_interface.NewHandlerError(ws_interface.CasinoMisconfigured, err)
+	default:
+		return blockchainHandlerError(err)
+	}
+}
+
+// blockchainHandlerError maps transaction push errors to ws error codes, other errors are internal
+func blockchainHandlerError(err error) *ws_interface.HandlerError {
+	switch {
+	case errors.Is(err, blockchain.ErrTrxAssertion):
+		return ws_interface.NewHandlerError(ws_interface.TrxRejectedByContract, err)
+	case errors.Is(err, blockchain.ErrTrxMissingAuth):
+		return ws_interface.NewHandlerError(ws_interface.TrxAuthorizationError, err)
+	case errors.Is(err, blockchain.ErrTrxRejected),
+		errors.Is(err, blockchain.ErrSponsorRejected):
+		return ws_interface.NewHandlerError(ws_interface.TrxRejected, err)
+	case errors.Is(err, blockchain.ErrTrxResourceExhausted):
+		return ws_interface.NewHandlerError(ws_interface.BlockchainResourcesExhausted, err)
+	case errors.Is(err, blockchain.ErrTrxExpired),
+		errors.Is(err, blockchain.ErrNodeUnavailable),
+		errors.Is(err, blockchain.ErrSponsorUnavailable):
+		return ws_interface.NewHandlerError(ws_interface.BlockchainUnavailable, err)
+	default:
+		return ws_interface.NewHandlerError(ws_interface.InternalError, err)
+	}
+}
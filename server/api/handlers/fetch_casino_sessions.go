@@ -3,14 +3,15 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"github.com/eoscanada/eos-go"
+	"errors"
 	gamesessions "platform-backend/game_sessions"
 	"platform-backend/server/api/ws_interface"
 )
 
 type FetchCasinoSessionsPayload struct {
-	Filter   gamesessions.FilterType `json:"filter"`
-	CasinoId eos.Uint64              `json:"casinoId"`
+	// casinoId of embedded query is required
+	SessionsQueryPayload
+	Filter gamesessions.FilterType `json:"filter"`
 }
 
 func ProcessCasinoSessionsRequest(context context.Context, req *ws_interface.ApiRequest) (interface{}, *ws_interface.HandlerError) {
@@ -19,7 +20,16 @@ func ProcessCasinoSessionsRequest(context context.Context, req *ws_interface.Api
 		return nil, ws_interface.NewHandlerError(ws_interface.RequestParseError, err)
 	}
 
-	gameSessions, err := req.Repos.GameSession.GetCasinoSessions(context, payload.Filter, payload.CasinoId)
+	if payload.CasinoId == nil {
+		return nil, ws_interface.NewHandlerError(ws_interface.BadRequest, errors.New("casinoId is required"))
+	}
+
+	query, err := payload.toFinishedSessionsQuery()
+	if err != nil {
+		return nil, ws_interface.NewHandlerError(ws_interface.BadRequest, err)
+	}
+
+	gameSessions, err := req.Repos.GameSession.GetCasinoSessions(context, payload.Filter, *payload.CasinoId, query)
 
 	if err != nil {
 		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
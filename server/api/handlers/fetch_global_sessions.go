@@ -8,6 +8,7 @@ import (
 )
 
 type FetchGlobalSessionsPayload struct {
+	SessionsQueryPayload
 	Filter gamesessions.FilterType `json:"filter"`
 }
 
@@ -17,7 +18,12 @@ func ProcessFetchGlobalSessionsRequest(context context.Context, req *ws_interfac
 		return nil, ws_interface.NewHandlerError(ws_interface.RequestParseError, err)
 	}
 
-	gameSessions, err := req.Repos.GameSession.GetGlobalSessions(context, payload.Filter)
+	query, err := payload.toFinishedSessionsQuery()
+	if err != nil {
+		return nil, ws_interface.NewHandlerError(ws_interface.BadRequest, err)
+	}
+
+	gameSessions, err := req.Repos.GameSession.GetGlobalSessions(context, payload.Filter, query)
 
 	if err != nil {
 		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/eoscanada/eos-go"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/server/api/ws_interface"
+)
+
+type FetchSessionTransactionsPayload struct {
+	SessionId eos.Uint64 `json:"sessionId"`
+}
+
+func ProcessFetchSessionTransactionsRequest(context context.Context, req *ws_interface.ApiRequest) (interface{}, *ws_interface.HandlerError) {
+	var payload FetchSessionTransactionsPayload
+	if err := json.Unmarshal(req.Data.Payload, &payload); err != nil {
+		return nil, ws_interface.NewHandlerError(ws_interface.RequestParseError, err)
+	}
+
+	gameSession, err := req.Repos.GameSession.GetGameSession(context, uint64(payload.SessionId))
+	if err == gamesessions.ErrGameSessionNotFound {
+		return nil, ws_interface.NewHandlerError(ws_interface.SessionNotFoundError, err)
+	}
+	if err != nil {
+		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
+	}
+
+	if gameSession.Player != req.User.AccountName {
+		return nil, ws_interface.NewHandlerError(ws_interface.UnauthorizedError, errors.New("attempt to fetch transactions for not own session"))
+	}
+
+	trxs, err := req.UseCases.GameSession.GetSessionTransactions(context, gameSession.ID)
+	if err != nil {
+		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
+	}
+
+	return trxs, nil
+}
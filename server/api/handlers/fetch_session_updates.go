@@ -12,6 +12,8 @@ import (
 
 type FetchSessionUpdatesPayload struct {
 	SessionId eos.Uint64 `json:"sessionId"`
+	// fetch only updates after the one with given offset
+	SinceOffset *eos.Uint64 `json:"sinceOffset"`
 }
 
 func ProcessFetchSessionUpdatesRequest(context context.Context, req *ws_interface.ApiRequest) (interface{}, *ws_interface.HandlerError) {
@@ -32,15 +34,25 @@ func ProcessFetchSessionUpdatesRequest(context context.Context, req *ws_interfac
 		return nil, ws_interface.NewHandlerError(ws_interface.UnauthorizedError, errors.New("attempt to fetch updates for not own session"))
 	}
 
-	gameSessionUpdates, err := req.Repos.GameSession.GetGameSessionUpdates(context, gameSession.ID)
+	if payload.SinceOffset == nil {
+		gameSessionUpdates, err := req.Repos.GameSession.GetGameSessionUpdates(context, gameSession.ID)
+		if err != nil {
+			return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
+		}
+
+		return models.ToGameSessionUpdateMsgs(gameSessionUpdates, nil), nil
+	}
+
+	sinceOffset := uint64(*payload.SinceOffset)
+	gameSessionUpdates, err := req.Repos.GameSession.GetGameSessionUpdatesSince(context, gameSession.ID, sinceOffset)
 	if err != nil {
 		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
 	}
 
-	response := make([]*models.GameSessionUpdateMsg, len(gameSessionUpdates))
-	for i, su := range gameSessionUpdates {
-		response[i] = models.ToGameSessionUpdateMsg(su)
+	prevOffset, err := req.Repos.GameSession.GetLastGameSessionUpdateOffset(context, gameSession.ID, &sinceOffset)
+	if err != nil {
+		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
 	}
 
-	return response, nil
+	return models.ToGameSessionUpdateMsgs(gameSessionUpdates, prevOffset), nil
 }
@@ -2,11 +2,29 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"platform-backend/server/api/ws_interface"
 )
 
+type FetchSessionsPayload struct {
+	SessionsQueryPayload
+}
+
 func ProcessFetchSessionsRequest(context context.Context, req *ws_interface.ApiRequest) (interface{}, *ws_interface.HandlerError) {
-	gameSessions, err := req.Repos.GameSession.GetUserGameSessions(context, req.User.AccountName)
+	var payload FetchSessionsPayload
+	// payload is optional, returns first page without filters
+	if len(req.Data.Payload) > 0 {
+		if err := json.Unmarshal(req.Data.Payload, &payload); err != nil {
+			return nil, ws_interface.NewHandlerError(ws_interface.RequestParseError, err)
+		}
+	}
+
+	query, err := payload.toSessionsQuery()
+	if err != nil {
+		return nil, ws_interface.NewHandlerError(ws_interface.BadRequest, err)
+	}
+
+	gameSessions, err := req.Repos.GameSession.GetUserGameSessions(context, req.User.AccountName, query)
 	if err != nil {
 		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
 	}
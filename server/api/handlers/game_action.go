@@ -53,8 +53,14 @@ func ProcessGameActionRequest(context context.Context, req *ws_interface.ApiRequ
 	} else {
 		err = req.UseCases.GameSession.GameAction(context, uint64(payload.SessionId), payload.ActionType, params)
 	}
+	if err == gamesessions.ErrConcurrentGameAction {
+		return nil, ws_interface.NewHandlerError(ws_interface.SessionActionConflict, err)
+	}
+	if err == gamesessions.ErrIllegalStateTransition {
+		return nil, ws_interface.NewHandlerError(ws_interface.SessionInvalidStateError, err)
+	}
 	if err != nil {
-		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
+		return nil, blockchainHandlerError(err)
 	}
 
 	return struct{}{}, nil
@@ -68,7 +68,7 @@ func ProcessNewGameRequest(context context.Context, req *ws_interface.ApiRequest
 		payload.ActionType, actionParams,
 	)
 	if err != nil {
-		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
+		return nil, newSessionHandlerError(err)
 	}
 
 	return toGameSessionResponse(session), nil
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/eoscanada/eos-go"
+	gamesessions "platform-backend/game_sessions"
+	"platform-backend/models"
+)
+
+// SessionsCursorPayload is lastUpdate and id of the last session from the previous page
+type SessionsCursorPayload struct {
+	LastUpdate int64      `json:"lastUpdate"`
+	Id         eos.Uint64 `json:"id"`
+}
+
+type SessionsQueryPayload struct {
+	Cursor   *SessionsCursorPayload   `json:"cursor"`
+	Limit    int                      `json:"limit"`
+	GameId   *eos.Uint64              `json:"gameId"`
+	CasinoId *eos.Uint64              `json:"casinoId"`
+	State    *models.GameSessionState `json:"state"`
+	From     *int64                   `json:"from"`
+	To       *int64                   `json:"to"`
+}
+
+func (p *SessionsQueryPayload) toSessionsQuery() (*gamesessions.SessionsQuery, error) {
+	if p.Limit < 0 || p.Limit > gamesessions.MaxSessionsLimit {
+		return nil, errors.New("invalid sessions limit")
+	}
+	if p.State != nil && *p.State > models.GameFailed {
+		return nil, errors.New("invalid session state")
+	}
+	if p.From != nil && p.To != nil && *p.From > *p.To {
+		return nil, errors.New("invalid date range")
+	}
+
+	query := &gamesessions.SessionsQuery{
+		Limit: p.Limit,
+		State: p.State,
+		From:  p.From,
+		To:    p.To,
+	}
+	if p.Cursor != nil {
+		query.Cursor = &gamesessions.SessionsCursor{
+			LastUpdate: p.Cursor.LastUpdate,
+			ID:         uint64(p.Cursor.Id),
+		}
+	}
+	if p.GameId != nil {
+		gameID := uint64(*p.GameId)
+		query.GameID = &gameID
+	}
+	if p.CasinoId != nil {
+		casinoID := uint64(*p.CasinoId)
+		query.CasinoID = &casinoID
+	}
+
+	return query, nil
+}
+
+// toFinishedSessionsQuery converts payload of requests listing only finished sessions,
+// state filter is rejected as other states aren't listed
+func (p *SessionsQueryPayload) toFinishedSessionsQuery() (*gamesessions.SessionsQuery, error) {
+	if p.State != nil {
+		return nil, errors.New("state filter is not supported, only finished sessions are listed")
+	}
+	return p.toSessionsQuery()
+}
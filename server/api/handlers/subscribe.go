@@ -2,9 +2,65 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"github.com/eoscanada/eos-go"
+	"github.com/google/uuid"
+	gamesessions "platform-backend/game_sessions"
 	"platform-backend/server/api/ws_interface"
+	"platform-backend/subscription"
 )
 
+type SessionCursorPayload struct {
+	SessionId eos.Uint64  `json:"sessionId"`
+	Offset    *eos.Uint64 `json:"offset"`
+}
+
+type SubscribePayload struct {
+	Sessions []*SessionCursorPayload `json:"sessions"`
+}
+
 func ProcessSubscribeRequest(context context.Context, req *ws_interface.ApiRequest) (interface{}, *ws_interface.HandlerError) {
+	var payload SubscribePayload
+	// payload is optional, old clients subscribe without cursors
+	if len(req.Data.Payload) > 0 {
+		if err := json.Unmarshal(req.Data.Payload, &payload); err != nil {
+			return nil, ws_interface.NewHandlerError(ws_interface.RequestParseError, err)
+		}
+	}
+
+	cursors := make([]*subscription.SessionCursor, len(payload.Sessions))
+	for i, sc := range payload.Sessions {
+		gameSession, err := req.Repos.GameSession.GetGameSession(context, uint64(sc.SessionId))
+		if err == gamesessions.ErrGameSessionNotFound {
+			return nil, ws_interface.NewHandlerError(ws_interface.SessionNotFoundError, err)
+		}
+		if err != nil {
+			return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
+		}
+
+		if gameSession.Player != req.User.AccountName {
+			return nil, ws_interface.NewHandlerError(ws_interface.UnauthorizedError, errors.New("attempt to subscribe on not own session"))
+		}
+
+		cursors[i] = &subscription.SessionCursor{SessionID: gameSession.ID}
+		if sc.Offset != nil {
+			offset := uint64(*sc.Offset)
+			cursors[i].Offset = &offset
+		}
+	}
+
+	suid := context.Value("suid").(uuid.UUID)
+	send := context.Value("send").(chan []byte)
+	disconnect := context.Value("disconnect").(func())
+
+	err := req.UseCases.Subscriptions.Subscribe(context, suid, req.User, send, disconnect, cursors)
+	if err == subscription.ErrSendChannelFull {
+		return nil, ws_interface.NewHandlerError(ws_interface.SubscriptionReplayFailed, err)
+	}
+	if err != nil {
+		return nil, ws_interface.NewHandlerError(ws_interface.InternalError, err)
+	}
+
 	return struct{}{}, nil
 }
@@ -40,9 +40,19 @@ const (
 	CasinoPaused          WsErrorCode = 4010
 
 	SessionInvalidStateError WsErrorCode = 4100
+	SessionActionConflict    WsErrorCode = 4101
 	SessionFailedOrFinished  WsErrorCode = 4200
 
-	InternalError WsErrorCode = 5000
+	TrxRejectedByContract WsErrorCode = 4300
+	TrxAuthorizationError WsErrorCode = 4301
+	TrxRejected           WsErrorCode = 4302
+
+	InternalError                WsErrorCode = 5000
+	BlockchainResourcesExhausted WsErrorCode = 5001
+	BlockchainUnavailable        WsErrorCode = 5002
+	CasinoMisconfigured          WsErrorCode = 5003
+	GameRequestsDisabled         WsErrorCode = 5004
+	SubscriptionReplayFailed     WsErrorCode = 5005
 )
 
 func GetErrorMsg(code WsErrorCode) string {
@@ -73,10 +83,28 @@ func GetErrorMsg(code WsErrorCode) string {
 
 	case SessionInvalidStateError:
 		return "action while session invalid state"
+	case SessionActionConflict:
+		return "another action is already in progress"
 	case SessionFailedOrFinished:
 		return "session failed or finished"
+	case TrxRejectedByContract:
+		return "transaction rejected by contract"
+	case TrxAuthorizationError:
+		return "transaction authorization error"
+	case TrxRejected:
+		return "transaction rejected by blockchain"
 	case InternalError:
 		return "internal server error"
+	case BlockchainResourcesExhausted:
+		return "not enough blockchain resources, try later"
+	case BlockchainUnavailable:
+		return "blockchain is unavailable, try later"
+	case CasinoMisconfigured:
+		return "casino backend is misconfigured"
+	case GameRequestsDisabled:
+		return "game requests are disabled, try later"
+	case SubscriptionReplayFailed:
+		return "session updates replay failed, subscribe again"
 	default:
 		return "unknown error"
 	}
@@ -18,6 +18,7 @@ import (
 	authPgRepo "platform-backend/auth/repository/postgres"
 	authUC "platform-backend/auth/usecase"
 	"platform-backend/blockchain"
+	casinoclient "platform-backend/casino_client"
 	"platform-backend/config"
 	"platform-backend/contracts"
 	contractsBcRepo "platform-backend/contracts/repository/blockchain"
@@ -25,6 +26,9 @@ import (
 	contractsUC "platform-backend/contracts/usecase"
 	"platform-backend/db"
 	"platform-backend/eventprocessor"
+	eventsPgRepo "platform-backend/eventprocessor/repository/postgres"
+	eventsSource "platform-backend/eventprocessor/source"
+	gamesessions "platform-backend/game_sessions"
 	gameSessionPgRepo "platform-backend/game_sessions/repository/postgres"
 	gameSessionUC "platform-backend/game_sessions/usecase"
 	"platform-backend/logger"
@@ -34,6 +38,7 @@ import (
 	"platform-backend/server/api"
 	"platform-backend/server/session_manager"
 	smLocalRepo "platform-backend/server/session_manager/repository/localstorage"
+	signidicePgRepo "platform-backend/signidice/repository/postgres"
 	signidiceUC "platform-backend/signidice/usecase"
 	subscriptionUc "platform-backend/subscription/usecase"
 	"platform-backend/usecases"
@@ -68,11 +73,14 @@ type App struct {
 	wsUpgrader  websocket.Upgrader
 	wsApi       *api.WsApi
 
+	bc             *blockchain.Blockchain
 	smRepo         session_manager.Repository
 	uRepo          auth.UserRepository
 	eventProcessor *eventprocessor.EventProcessor
+	eventsRepo     eventprocessor.Repository
+	dispatcher     *eventprocessor.Dispatcher
 	useCases       *usecases.UseCases
-	events         chan *eventlistener.EventMessage
+	eventSource    eventprocessor.EventSource
 
 	developmentMode bool
 }
@@ -98,7 +106,7 @@ func NewApp(config *config.Config) (*App, error) {
 		return nil, err
 	}
 
-	bc, err := blockchain.Init(&config.Blockchain)
+	bc, err := blockchain.Init(&config.Blockchain, registerer)
 	if err != nil {
 		log.Fatal().Msgf("Blockchain init error, %s", err.Error())
 		return nil, err
@@ -116,7 +124,7 @@ func NewApp(config *config.Config) (*App, error) {
 		}
 	}
 
-	gsRepo := gameSessionPgRepo.NewGameSessionsPostgresRepo(db.DbPool)
+	gsRepo := gameSessionPgRepo.NewGameSessionsPostgresRepo(db.DbPool, gamesessions.NewStateMachine(registerer))
 	smRepo := smLocalRepo.NewLocalRepository(registerer)
 	uRepo := authPgRepo.NewUserPostgresRepo(db.DbPool, config.Auth.MaxUserSessions, config.Auth.RefreshTokenTTL)
 	refsRepo := referralsRepo.NewReferralPostgresRepo(db.DbPool)
@@ -128,9 +136,22 @@ func NewApp(config *config.Config) (*App, error) {
 		affStatsRepo,
 	)
 
-	subsUC := subscriptionUc.NewSubscriptionUseCase()
+	subsUC := subscriptionUc.NewSubscriptionUseCase(repos.GameSession)
 	contractUC := contractsUC.NewContractsUseCase(bc, config.ActiveFeatures.Bonus)
 	refsUC := referralsUC.NewReferralsUseCase(refsRepo, config.ActiveFeatures.Referrals)
+	signidiceUseCase := signidiceUC.NewSignidiceUseCase(
+		bc,
+		signidicePgRepo.NewSignidicePostgresRepo(db.DbPool),
+		repos.Contracts,
+		config.Blockchain.Contracts.Platform,
+		&config.Signidice,
+		registerer,
+	)
+	err = signidiceUseCase.CheckKeys(context.Background())
+	if err != nil {
+		log.Fatal().Msgf("Signidice keys check error, %s", err.Error())
+		return nil, err
+	}
 
 	useCases := usecases.NewUseCases(
 		authUC.NewAuthUseCase(
@@ -150,33 +171,61 @@ func NewApp(config *config.Config) (*App, error) {
 			repos.Contracts,
 			config.Blockchain.Contracts.Platform,
 			subsUC,
+			signidiceUseCase,
+			&config.CasinoTrxOutbox,
+			&config.TrxTracker,
+			&config.CasinoWebhooks,
+			casinoclient.NewClient(&config.CasinoClient, registerer),
 		),
-		signidiceUC.NewSignidiceUseCase(
-			bc,
-			config.Blockchain.Contracts.Platform,
-			config.Signidice.AccountName,
-			config.Signidice.Key,
-		),
+		signidiceUseCase,
 		subsUC,
 		refsUC,
 	)
 
-	events := make(chan *eventlistener.EventMessage)
-
 	// Hack for development mode, just set DEV_MODE env to enable
 	_, devMode := os.LookupEnv("DEV_MODE")
 
+	eventsRepo := eventsPgRepo.NewEventsPostgresRepo(db.DbPool)
+	eventProcessor := eventprocessor.New(
+		repos,
+		eventsRepo,
+		bc,
+		useCases,
+		&eventprocessor.RetryPolicy{
+			MinBackoff:  time.Duration(config.EventProcessor.RetryMinBackoff) * time.Second,
+			MaxBackoff:  time.Duration(config.EventProcessor.RetryMaxBackoff) * time.Second,
+			MaxAttempts: config.EventProcessor.RetryMaxAttempts,
+		},
+		registerer,
+	)
+	wsApi := api.NewWsApi(useCases, repos, registerer)
+	if isReplayDryRun(config) {
+		log.Info().Msg("Events are replayed in dry run, only session changes are stored")
+		eventProcessor.EnableDryRun()
+		wsApi.DisableGameRequests()
+	}
+
 	app := &App{
 		config: config,
 		wsUpgrader: websocket.Upgrader{CheckOrigin: func(r *http.Request) bool {
 			return true
 		}},
-		smRepo:          smRepo,
-		uRepo:           uRepo,
-		eventProcessor:  eventprocessor.New(repos, bc, useCases, registerer),
+		bc:             bc,
+		smRepo:         smRepo,
+		uRepo:          uRepo,
+		eventProcessor: eventProcessor,
+		eventsRepo:     eventsRepo,
+		dispatcher: eventprocessor.NewDispatcher(
+			eventProcessor,
+			eventsRepo,
+			eventsOffsetSource(config),
+			config.EventProcessor.Workers,
+			config.EventProcessor.QueueSize,
+			registerer,
+		),
 		useCases:        useCases,
-		wsApi:           api.NewWsApi(useCases, repos, registerer),
-		events:          events,
+		wsApi:           wsApi,
+		eventSource:     newEventSource(config, eventProcessor),
 		developmentMode: devMode,
 	}
 
@@ -241,6 +290,16 @@ func NewApp(config *config.Config) (*App, error) {
 	handleFunc("optout", optOutHandler)
 	handleFunc("ping", pingHandler)
 	handleFunc("who", whoHandler)
+	if config.Admin.Token != "" {
+		handleFunc("admin_failed_events", adminHandler(app, failedEventsHandler))
+		handleFunc("admin_requeue_event", adminHandler(app, requeueEventHandler))
+		handleFunc("admin_discard_event", adminHandler(app, discardEventHandler))
+		handleFunc("admin_session_txns", adminHandler(app, sessionTransactionsHandler))
+		handleFunc("admin_casino_webhooks", adminHandler(app, casinoWebhooksHandler))
+		handleFunc("admin_requeue_webhook", adminHandler(app, requeueWebhookHandler))
+		handleFunc("admin_reload_signidice_keys", adminHandler(app, reloadSignidiceKeysHandler))
+		handleFunc("admin_signidice_audit", adminHandler(app, signidiceAuditHandler))
+	}
 	handle("metrics", promhttp.InstrumentMetricHandler(
 		registerer, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
 	))
@@ -256,7 +315,60 @@ func NewApp(config *config.Config) (*App, error) {
 	return app, nil
 }
 
+// isReplayDryRun reports whether events are replayed from file without side effects:
+// workers pushing transactions or sending webhooks aren't run and game requests are refused
+func isReplayDryRun(config *config.Config) bool {
+	return config.EventProcessor.Source.ReplayFile != "" && config.EventProcessor.Source.ReplayDryRun
+}
+
+// disabledInDryRun waits until ctx is done if replay dry run is enabled,
+// so worker pushing transactions or sending webhooks isn't run
+func disabledInDryRun(a *App, ctx context.Context, worker string) bool {
+	if !isReplayDryRun(a.config) {
+		return false
+	}
+	log.Info().Msgf("%s is disabled in replay dry run", worker)
+	<-ctx.Done()
+	return true
+}
+
+// eventsOffsetSource returns key of stored processed offset,
+// replayed file offsets don't move the action monitor one
+func eventsOffsetSource(config *config.Config) string {
+	if config.EventProcessor.Source.ReplayFile != "" {
+		return "file:" + config.EventProcessor.Source.ReplayFile
+	}
+	return "amc"
+}
+
+func newEventSource(config *config.Config, eventProcessor *eventprocessor.EventProcessor) eventprocessor.EventSource {
+	var eventSource eventprocessor.EventSource
+	sourceConfig := &config.EventProcessor.Source
+	if sourceConfig.ReplayFile != "" {
+		eventSource = eventsSource.NewFileSource(sourceConfig.ReplayFile, sourceConfig.ReplaySpeed)
+	} else {
+		extraTypes := make([]eventlistener.EventType, len(config.EventProcessor.ExtraEventTypes))
+		for i, eventType := range config.EventProcessor.ExtraEventTypes {
+			extraTypes[i] = eventlistener.EventType(eventType)
+		}
+		eventSource = eventsSource.NewAmcSource(
+			&config.Amc,
+			eventProcessor.EventsToSubscribe(extraTypes),
+			config.LogLevel == "debug",
+		)
+	}
+
+	if sourceConfig.RecordFile != "" {
+		eventSource = eventsSource.NewRecordingSource(eventSource, sourceConfig.RecordFile)
+	}
+	return eventSource
+}
+
 func startSessionsCleaner(a *App, ctx context.Context) error {
+	if disabledInDryRun(a, ctx, "Sessions cleaner") {
+		return nil
+	}
+
 	interval := a.config.SessionsCleaner.Interval
 	if interval <= 0 {
 		log.Info().Msg("Sessions cleaner is disabled")
@@ -294,6 +406,71 @@ func startSessionsCleaner(a *App, ctx context.Context) error {
 	}
 }
 
+// recoverSessions drives forward sessions left in intermediate states by previous run
+func recoverSessions(a *App, ctx context.Context) {
+	log.Info().Msg("Sessions recovery is started")
+	reports, err := a.useCases.GameSession.RecoverSessions(ctx)
+	if err != nil {
+		log.Error().Msgf("Sessions recovery error: %s", err.Error())
+		return
+	}
+	for _, report := range reports {
+		log.Info().Msgf("RECOVERY: sessionID: %d, state: %d, action: %s, details: %s",
+			report.SessionID, report.State, report.Action, report.Details)
+	}
+	log.Info().Msgf("Sessions recovery is finished, %d sessions inspected", len(reports))
+}
+
+// startSessionsRecovery inspects intermediate sessions again, recovery skips ones with trx in flight
+func startSessionsRecovery(a *App, ctx context.Context) error {
+	if disabledInDryRun(a, ctx, "Periodic sessions recovery") {
+		return nil
+	}
+	interval := a.config.SessionsRecovery.Interval
+	if interval <= 0 {
+		log.Info().Msg("Periodic sessions recovery is disabled")
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			recoverSessions(a, ctx)
+		case <-ctx.Done():
+			log.Info().Msg("Periodic sessions recovery is stopped")
+			return nil
+		}
+	}
+}
+
+func startCasinoTrxOutbox(a *App, ctx context.Context) error {
+	if disabledInDryRun(a, ctx, "Casino trx outbox") {
+		return nil
+	}
+	return a.useCases.GameSession.RunCasinoTrxOutbox(ctx)
+}
+
+func startCasinoWebhooks(a *App, ctx context.Context) error {
+	if disabledInDryRun(a, ctx, "Casino webhooks delivery") {
+		return nil
+	}
+	return a.useCases.GameSession.RunCasinoWebhooks(ctx)
+}
+
+func startTrxTracker(a *App, ctx context.Context) error {
+	if disabledInDryRun(a, ctx, "Session transactions tracker") {
+		return nil
+	}
+	return a.useCases.GameSession.RunTrxTracker(ctx)
+}
+
+func startSignidiceKeysReloader(a *App, ctx context.Context) error {
+	return a.useCases.Signidice.RunKeysReloader(ctx)
+}
+
 func startAuthSessionsCleaner(a *App, ctx context.Context) error {
 	interval := a.config.Auth.CleanerInterval
 	if interval <= 0 {
@@ -358,41 +535,47 @@ func startHttpServer(a *App, ctx context.Context) error {
 	return nil
 }
 
-func startAmc(a *App, ctx context.Context) error {
-	listener := eventlistener.NewEventListener(a.config.Amc.Url, a.events)
-	// setup reconnection options
-	listener.ReconnectionAttempts = a.config.Amc.ReconnectionAttempts
-	listener.ReconnectionDelay = time.Duration(a.config.Amc.ReconnectionDelay)
-
-	log.Info().Msgf("Connecting to the action monitor on %s", a.config.Amc.Url)
-
-	if a.config.LogLevel == "debug" {
-		eventlistener.EnableDebugLogging()
+func startEventSource(a *App, ctx context.Context) error {
+	offset, err := a.dispatcher.StartOffset(ctx)
+	if err != nil {
+		return err
 	}
-
-	// set auth token
-	listener.SetToken(a.config.Amc.Token)
-
-	go listener.Run(ctx)
-
-	if ok, err := listener.BatchSubscribe(eventprocessor.GetEventsToSubscribe(), 0); err != nil || !ok {
-		log.Fatal().Msgf("Action monitor subscribe to events, error: %v", err)
+	// file is replayed from the beginning, events processed already are skipped by sessions offsets
+	if a.config.EventProcessor.Source.ReplayFile != "" {
+		offset = 0
+	}
+	if a.config.Amc.ReplayFromOffset != nil {
+		log.Info().Msgf("Forced replay from offset %d, last processed offset: %d", *a.config.Amc.ReplayFromOffset, offset)
+		offset = *a.config.Amc.ReplayFromOffset
 	}
 
-	log.Info().Msgf("Subscribed to all events!")
+	// dispatcher is stopped and joined on return, so the final offset is committed before exit
+	dispatcherCtx, stopDispatcher := context.WithCancel(ctx)
+	dispatcherDone := make(chan struct{})
+	go func() {
+		defer close(dispatcherDone)
+		a.dispatcher.Run(dispatcherCtx)
+	}()
+	defer func() {
+		stopDispatcher()
+		<-dispatcherDone
+	}()
+
+	events := make(chan *eventlistener.Event)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- a.eventSource.Run(ctx, offset, events)
+	}()
 
 	for {
 		select {
-		case <-ctx.Done():
-			log.Info().Msgf("Action monitor client is stopped")
-			return nil
-		case eventMessage, ok := <-a.events:
-			if !ok {
-				return nil
-			}
-			for _, event := range eventMessage.Events {
-				a.eventProcessor.Process(ctx, event)
+		case err := <-errs:
+			if err != nil {
+				log.Error().Msgf("Event source error: %s", err.Error())
 			}
+			return err
+		case event := <-events:
+			a.dispatcher.Dispatch(ctx, event)
 		}
 	}
 }
@@ -402,22 +585,52 @@ func (a *App) Run() error {
 	runCtx, cancelRun := context.WithCancel(context.Background())
 	errGroup, runCtx := errgroup.WithContext(runCtx)
 
+	// should be done before accepting requests, processor can be behind the blockchain yet,
+	// so sessions with executed trxs are left to events
+	if !isReplayDryRun(a.config) {
+		recoverSessions(a, runCtx)
+	}
+
 	errGroup.Go(func() error {
 		defer cancelRun()
 		return startHttpServer(a, runCtx)
 	})
 	errGroup.Go(func() error {
 		defer cancelRun()
-		return startAmc(a, runCtx)
+		return startEventSource(a, runCtx)
 	})
 	errGroup.Go(func() error {
 		defer cancelRun()
 		return startSessionsCleaner(a, runCtx)
 	})
+	errGroup.Go(func() error {
+		defer cancelRun()
+		return startSessionsRecovery(a, runCtx)
+	})
 	errGroup.Go(func() error {
 		defer cancelRun()
 		return startAuthSessionsCleaner(a, runCtx)
 	})
+	errGroup.Go(func() error {
+		defer cancelRun()
+		return startCasinoTrxOutbox(a, runCtx)
+	})
+	errGroup.Go(func() error {
+		defer cancelRun()
+		return startTrxTracker(a, runCtx)
+	})
+	errGroup.Go(func() error {
+		defer cancelRun()
+		return startCasinoWebhooks(a, runCtx)
+	})
+	errGroup.Go(func() error {
+		defer cancelRun()
+		return startSignidiceKeysReloader(a, runCtx)
+	})
+	errGroup.Go(func() error {
+		defer cancelRun()
+		return a.bc.RunHealthChecks(runCtx)
+	})
 
 	errGroup.Go(func() error {
 		quit := make(chan os.Signal, 1)
@@ -25,6 +25,9 @@ const (
 	// Maximum message size allowed from peer.
 	// TODO: need move this to config, max websocket packet size 64kB
 	maxMessageSize = 1024 * 2
+
+	// Send channel buffer, notifications are dropped when buffer is full
+	sendBufferSize = 256
 )
 
 type OnCloseCb func()
@@ -49,12 +52,12 @@ type Session struct {
 	Send chan []byte
 }
 
+// close can be called by subscriptions too, so it's done once
 func (s *Session) close() {
-	if !s.closing.Load() {
+	if s.closing.CAS(false, true) {
 		_ = s.wsConn.Close()
 		s.wsApi.UseCases.Subscriptions.RemoveSession(s.Uuid)
 		s.onClose()
-		s.closing.Store(true)
 	}
 }
 
@@ -88,16 +91,18 @@ func (s *Session) readLoop() {
 			// add user info into context
 			ctx = context.WithValue(ctx, "user", s.User)
 
-			resp, request, err := s.wsApi.ProcessRawRequest(ctx, messageType, message)
+			// add send chan into context, used by subscribe to replay missed updates
+			ctx = context.WithValue(ctx, "send", s.Send)
+
+			// add disconnect into context, subscription disconnects client instead of dropping updates
+			ctx = context.WithValue(ctx, "disconnect", s.close)
+
+			resp, _, err := s.wsApi.ProcessRawRequest(ctx, messageType, message)
 			if err != nil {
 				log.Debug().Msgf("Websocket request fatal error, disconnection, %s", err.Error())
 				return
 			}
 
-			if request == "subscribe" {
-				s.wsApi.UseCases.Subscriptions.AddSession(s.Uuid, s.User, s.Send)
-			}
-
 			if marshal, err := json.Marshal(resp); err != nil {
 				log.Debug().Msgf("Websocket answer marshal error, %s", err.Error())
 				return
@@ -152,7 +157,7 @@ func NewSession(ctx context.Context, conn *websocket.Conn, wsApi *api.WsApi, onC
 	session.onClose = onClose
 	session.wsApi = wsApi
 	session.User = nil
-	session.Send = make(chan []byte)
+	session.Send = make(chan []byte, sendBufferSize)
 	session.closing.Store(false)
 
 	return session
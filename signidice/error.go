@@ -0,0 +1,10 @@
+package signidice
+
+import "errors"
+
+var (
+	ErrNoSignidiceKey    = errors.New("no active signidice key matches casino public key")
+	ErrInvalidRsaPubkey  = errors.New("invalid casino rsa public key")
+	ErrInvalidKeyring    = errors.New("invalid signidice keyring")
+	ErrSignatureMismatch = errors.New("signidice signature doesn't match casino public key")
+)
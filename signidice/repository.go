@@ -0,0 +1,14 @@
+package signidice
+
+import (
+	"context"
+	"platform-backend/models"
+)
+
+type Repository interface {
+	// record is added as pending before transaction push
+	AddAuditRecord(ctx context.Context, record *models.SignidiceAuditRecord) error
+	UpdateAuditRecordSent(ctx context.Context, id uint64, trxID string) error
+	UpdateAuditRecordFailed(ctx context.Context, id uint64, lastErr string) error
+	GetSessionAuditRecords(ctx context.Context, sessionID uint64) ([]*models.SignidiceAuditRecord, error)
+}
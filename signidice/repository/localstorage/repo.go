@@ -0,0 +1,77 @@
+package localstorage
+
+import (
+	"context"
+	"platform-backend/models"
+	"sync"
+	"time"
+)
+
+type SignidiceLocalRepo struct {
+	sync.Mutex
+	records []*models.SignidiceAuditRecord
+}
+
+func NewSignidiceLocalRepo() *SignidiceLocalRepo {
+	return &SignidiceLocalRepo{}
+}
+
+func (r *SignidiceLocalRepo) AddAuditRecord(_ context.Context, record *models.SignidiceAuditRecord) error {
+	r.Lock()
+	defer r.Unlock()
+
+	record.ID = uint64(len(r.records) + 1)
+	record.Created = time.Now()
+	copied := *record
+	r.records = append(r.records, &copied)
+	return nil
+}
+
+func (r *SignidiceLocalRepo) UpdateAuditRecordSent(_ context.Context, id uint64, trxID string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if record := r.getAuditRecord(id); record != nil {
+		record.Status = models.SignidiceAuditSent
+		record.TrxID = trxID
+		record.LastError = ""
+	}
+	return nil
+}
+
+func (r *SignidiceLocalRepo) UpdateAuditRecordFailed(_ context.Context, id uint64, lastErr string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if record := r.getAuditRecord(id); record != nil {
+		record.Status = models.SignidiceAuditFailed
+		record.LastError = lastErr
+	}
+	return nil
+}
+
+func (r *SignidiceLocalRepo) GetSessionAuditRecords(
+	_ context.Context,
+	sessionID uint64,
+) ([]*models.SignidiceAuditRecord, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	records := make([]*models.SignidiceAuditRecord, 0)
+	for _, record := range r.records {
+		if record.SessionID == sessionID {
+			copied := *record
+			records = append(records, &copied)
+		}
+	}
+	return records, nil
+}
+
+func (r *SignidiceLocalRepo) getAuditRecord(id uint64) *models.SignidiceAuditRecord {
+	for _, record := range r.records {
+		if record.ID == id {
+			return record
+		}
+	}
+	return nil
+}
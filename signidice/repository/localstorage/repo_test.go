@@ -0,0 +1,38 @@
+package localstorage
+
+import (
+	"context"
+	"platform-backend/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRecordLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSignidiceLocalRepo()
+
+	sent := &models.SignidiceAuditRecord{SessionID: 1, KeyID: "key1"}
+	require.NoError(t, repo.AddAuditRecord(ctx, sent))
+	failed := &models.SignidiceAuditRecord{SessionID: 1, KeyID: "key1"}
+	require.NoError(t, repo.AddAuditRecord(ctx, failed))
+	require.NoError(t, repo.AddAuditRecord(ctx, &models.SignidiceAuditRecord{SessionID: 2}))
+
+	records, err := repo.GetSessionAuditRecords(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, models.SignidiceAuditPending, records[0].Status)
+	assert.Empty(t, records[0].TrxID)
+
+	require.NoError(t, repo.UpdateAuditRecordSent(ctx, sent.ID, "trx1"))
+	require.NoError(t, repo.UpdateAuditRecordFailed(ctx, failed.ID, "rejected"))
+
+	records, err = repo.GetSessionAuditRecords(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.SignidiceAuditSent, records[0].Status)
+	assert.Equal(t, "trx1", records[0].TrxID)
+	assert.Equal(t, models.SignidiceAuditFailed, records[1].Status)
+	assert.Equal(t, "rejected", records[1].LastError)
+	assert.Empty(t, records[1].TrxID)
+}
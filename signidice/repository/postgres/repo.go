@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"platform-backend/models"
+)
+
+const (
+	insertAuditRecordStmt = `
+        INSERT INTO signidice_audit
+            (ses_id, bc_ses_id, game_contract, digest, signature, key_id, status)
+        VALUES
+            ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, created`
+	updateAuditRecordSentStmt = `
+        UPDATE signidice_audit
+        SET status = $2, trx_id = $3, last_error = NULL
+        WHERE id = $1`
+	updateAuditRecordFailedStmt = `
+        UPDATE signidice_audit
+        SET status = $2, last_error = $3
+        WHERE id = $1`
+	selectSessionAuditRecordsStmt = `
+        SELECT id, ses_id, bc_ses_id, game_contract, digest, signature, key_id, status,
+               COALESCE(trx_id, ''), COALESCE(last_error, ''), created
+        FROM signidice_audit
+        WHERE ses_id = $1
+        ORDER BY id`
+)
+
+type SignidicePostgresRepo struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewSignidicePostgresRepo(dbPool *pgxpool.Pool) *SignidicePostgresRepo {
+	return &SignidicePostgresRepo{dbPool: dbPool}
+}
+
+func (r *SignidicePostgresRepo) AddAuditRecord(ctx context.Context, record *models.SignidiceAuditRecord) error {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	return conn.QueryRow(ctx, insertAuditRecordStmt,
+		record.SessionID,
+		record.BlockchainSesID,
+		record.GameContract,
+		[]byte(record.Digest),
+		record.Signature,
+		record.KeyID,
+		record.Status,
+	).Scan(&record.ID, &record.Created)
+}
+
+func (r *SignidicePostgresRepo) UpdateAuditRecordSent(ctx context.Context, id uint64, trxID string) error {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, updateAuditRecordSentStmt, id, models.SignidiceAuditSent, trxID)
+	return err
+}
+
+func (r *SignidicePostgresRepo) UpdateAuditRecordFailed(ctx context.Context, id uint64, lastErr string) error {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, updateAuditRecordFailedStmt, id, models.SignidiceAuditFailed, lastErr)
+	return err
+}
+
+func (r *SignidicePostgresRepo) GetSessionAuditRecords(
+	ctx context.Context,
+	sessionID uint64,
+) ([]*models.SignidiceAuditRecord, error) {
+	conn, err := r.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, selectSessionAuditRecordsStmt, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*models.SignidiceAuditRecord, 0)
+	for rows.Next() {
+		record := new(models.SignidiceAuditRecord)
+		var digest []byte
+		err := rows.Scan(
+			&record.ID,
+			&record.SessionID,
+			&record.BlockchainSesID,
+			&record.GameContract,
+			&digest,
+			&record.Signature,
+			&record.KeyID,
+			&record.Status,
+			&record.TrxID,
+			&record.LastError,
+			&record.Created,
+		)
+		if err != nil {
+			return nil, err
+		}
+		record.Digest = digest
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
@@ -1,7 +1,17 @@
 package signidice
 
-import "context"
+import (
+	"context"
+	"platform-backend/models"
+)
 
 type UseCase interface {
-	PerformSignidice(ctx context.Context, gameName string, digest []byte, bcSessionID uint64) error
+	PerformSignidice(ctx context.Context, session *models.GameSession, gameName string, digest []byte) error
+	GetSessionAudit(ctx context.Context, sessionID uint64) ([]*models.SignidiceAuditRecord, error)
+
+	// ReloadKeys rereads keys file and returns loaded keys, keys failed CheckKeys aren't loaded
+	ReloadKeys(ctx context.Context) ([]*models.SignidiceKey, error)
+	RunKeysReloader(ctx context.Context) error
+	// CheckKeys returns error if any active casino has no matching signidice key
+	CheckKeys(ctx context.Context) error
 }
@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"platform-backend/config"
+	"platform-backend/models"
+	"platform-backend/signidice"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// id of key from deprecated single key config
+const defaultKeyID = "default"
+
+type signidiceKey struct {
+	id        string
+	key       *rsa.PrivateKey
+	notBefore *time.Time
+	notAfter  *time.Time
+}
+
+func (k *signidiceKey) activeAt(now time.Time) bool {
+	if k.notBefore != nil && now.Before(*k.notBefore) {
+		return false
+	}
+	if k.notAfter != nil && !now.Before(*k.notAfter) {
+		return false
+	}
+	return true
+}
+
+func (k *signidiceKey) matches(pubKey *rsa.PublicKey) bool {
+	return k.key.E == pubKey.E && k.key.N.Cmp(pubKey.N) == 0
+}
+
+// keyring holds signidice keys, keys file can be reloaded at runtime
+type keyring struct {
+	configKeys []config.SignidiceKeyConfig
+	keysFile   string
+
+	mutex sync.RWMutex
+	keys  []*signidiceKey
+}
+
+func newKeyring(cfg *config.SignidiceConfig) (*keyring, error) {
+	configKeys := make([]config.SignidiceKeyConfig, 0, len(cfg.Keys)+1)
+	if cfg.Key != "" {
+		configKeys = append(configKeys, config.SignidiceKeyConfig{ID: defaultKeyID, Key: cfg.Key})
+	}
+	configKeys = append(configKeys, cfg.Keys...)
+
+	k := &keyring{
+		configKeys: configKeys,
+		keysFile:   cfg.KeysFile,
+	}
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// reload replaces keys only if all of them are valid
+func (k *keyring) reload() error {
+	keys, err := k.load()
+	if err != nil {
+		return err
+	}
+	k.set(keys)
+	return nil
+}
+
+// load reads and parses keys without replacing current ones,
+// so they can be checked before set
+func (k *keyring) load() ([]*signidiceKey, error) {
+	keyConfigs := k.configKeys
+	if k.keysFile != "" {
+		data, err := ioutil.ReadFile(k.keysFile)
+		if err != nil {
+			return nil, err
+		}
+		var fileKeys []config.SignidiceKeyConfig
+		if err := json.Unmarshal(data, &fileKeys); err != nil {
+			return nil, fmt.Errorf("%w: keys file: %s", signidice.ErrInvalidKeyring, err.Error())
+		}
+		keyConfigs = mergeKeyConfigs(keyConfigs, fileKeys)
+	}
+
+	keys := make([]*signidiceKey, 0, len(keyConfigs))
+	ids := make(map[string]bool, len(keyConfigs))
+	for _, cfg := range keyConfigs {
+		if cfg.ID == "" {
+			return nil, fmt.Errorf("%w: key without id", signidice.ErrInvalidKeyring)
+		}
+		if ids[cfg.ID] {
+			return nil, fmt.Errorf("%w: duplicated key id %s", signidice.ErrInvalidKeyring, cfg.ID)
+		}
+		ids[cfg.ID] = true
+
+		if cfg.NotBefore != nil && cfg.NotAfter != nil && !cfg.NotBefore.Before(*cfg.NotAfter) {
+			return nil, fmt.Errorf("%w: key %s has empty validity window", signidice.ErrInvalidKeyring, cfg.ID)
+		}
+
+		key, err := parseRsaPrivateKey(cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s: %s", signidice.ErrInvalidKeyring, cfg.ID, err.Error())
+		}
+
+		keys = append(keys, &signidiceKey{
+			id:        cfg.ID,
+			key:       key,
+			notBefore: cfg.NotBefore,
+			notAfter:  cfg.NotAfter,
+		})
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no keys", signidice.ErrInvalidKeyring)
+	}
+
+	return keys, nil
+}
+
+func (k *keyring) set(keys []*signidiceKey) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.keys = keys
+}
+
+// current returns keys loaded at the moment, the slice isn't changed by later reloads
+func (k *keyring) current() []*signidiceKey {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.keys
+}
+
+// selectKey returns active key for casino public key,
+// the most recent one if several keys match
+func (k *keyring) selectKey(pubKey *rsa.PublicKey, now time.Time) (*signidiceKey, error) {
+	return selectKey(k.current(), pubKey, now)
+}
+
+func selectKey(keys []*signidiceKey, pubKey *rsa.PublicKey, now time.Time) (*signidiceKey, error) {
+	var selected *signidiceKey
+	for _, key := range keys {
+		if !key.activeAt(now) || !key.matches(pubKey) {
+			continue
+		}
+		if selected == nil || isNewerKey(key, selected) {
+			selected = key
+		}
+	}
+
+	if selected == nil {
+		return nil, signidice.ErrNoSignidiceKey
+	}
+	return selected, nil
+}
+
+func (k *keyring) list(now time.Time) []*models.SignidiceKey {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	ret := make([]*models.SignidiceKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		ret = append(ret, &models.SignidiceKey{
+			ID:        key.id,
+			NotBefore: key.notBefore,
+			NotAfter:  key.notAfter,
+			Active:    key.activeAt(now),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret
+}
+
+func isNewerKey(a, b *signidiceKey) bool {
+	if b.notBefore == nil {
+		return a.notBefore != nil
+	}
+	return a.notBefore != nil && a.notBefore.After(*b.notBefore)
+}
+
+func mergeKeyConfigs(configKeys, fileKeys []config.SignidiceKeyConfig) []config.SignidiceKeyConfig {
+	overridden := make(map[string]bool, len(fileKeys))
+	for _, key := range fileKeys {
+		overridden[key.ID] = true
+	}
+
+	ret := make([]config.SignidiceKeyConfig, 0, len(configKeys)+len(fileKeys))
+	for _, key := range configKeys {
+		if !overridden[key.ID] {
+			ret = append(ret, key)
+		}
+	}
+	return append(ret, fileKeys...)
+}
+
+// parseRsaPrivateKey parses base64 encoded PKCS1 or PKCS8 pem key
+func parseRsaPrivateKey(rsaBase64 string) (*rsa.PrivateKey, error) {
+	rsaPem, err := base64.StdEncoding.DecodeString(rsaBase64)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode rsa key: %w", err)
+	}
+
+	block, _ := pem.Decode(rsaPem)
+	if block == nil {
+		return nil, fmt.Errorf("rsa key is not pem encoded")
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err == nil {
+		return key, nil
+	}
+	parsed, pkcs8Err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if pkcs8Err != nil {
+		return nil, fmt.Errorf("cannot parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not rsa key")
+	}
+	return key, nil
+}
+
+// parseRsaPubkey parses casino on-chain public key,
+// it can be pem or der (PKIX or PKCS1) and optionally base64 encoded
+func parseRsaPubkey(pubkey string) (*rsa.PublicKey, error) {
+	data := []byte(strings.TrimSpace(pubkey))
+	if len(data) == 0 {
+		return nil, signidice.ErrInvalidRsaPubkey
+	}
+	if !strings.HasPrefix(string(data), "-----BEGIN") {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", signidice.ErrInvalidRsaPubkey, err.Error())
+		}
+		data = decoded
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(data); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", signidice.ErrInvalidRsaPubkey, err.Error())
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: not rsa key", signidice.ErrInvalidRsaPubkey)
+	}
+	return key, nil
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"platform-backend/config"
+	"platform-backend/signidice"
+)
+
+func newTestKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return key, base64.StdEncoding.EncodeToString(keyPem)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestKeyringSelectKey(t *testing.T) {
+	now := time.Now()
+	oldKey, oldBase64 := newTestKey(t)
+	newKey, _ := newTestKey(t)
+
+	keys, err := newKeyring(&config.SignidiceConfig{
+		Key: oldBase64,
+		Keys: []config.SignidiceKeyConfig{
+			{ID: "expired", Key: oldBase64, NotAfter: timePtr(now.Add(-time.Hour))},
+			{ID: "rotated", Key: oldBase64, NotBefore: timePtr(now.Add(-time.Minute))},
+			{ID: "future", Key: oldBase64, NotBefore: timePtr(now.Add(time.Hour))},
+		},
+	})
+	require.NoError(t, err)
+
+	key, err := keys.selectKey(&oldKey.PublicKey, now)
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", key.id)
+
+	key, err = keys.selectKey(&oldKey.PublicKey, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "future", key.id)
+
+	_, err = keys.selectKey(&newKey.PublicKey, now)
+	assert.True(t, err == signidice.ErrNoSignidiceKey)
+}
+
+func TestKeyringReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signidice")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keysFile := filepath.Join(dir, "keys.json")
+
+	oldKey, oldBase64 := newTestKey(t)
+	newKey, newBase64 := newTestKey(t)
+	writeKeys := func(keys []config.SignidiceKeyConfig) {
+		data, err := json.Marshal(keys)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(keysFile, data, 0600))
+	}
+	writeKeys([]config.SignidiceKeyConfig{})
+
+	keys, err := newKeyring(&config.SignidiceConfig{Key: oldBase64, KeysFile: keysFile})
+	require.NoError(t, err)
+	_, err = keys.selectKey(&newKey.PublicKey, time.Now())
+	assert.Error(t, err)
+
+	// new key is added, default key is overridden by file
+	writeKeys([]config.SignidiceKeyConfig{
+		{ID: defaultKeyID, Key: oldBase64, NotAfter: timePtr(time.Now().Add(-time.Minute))},
+		{ID: "new", Key: newBase64},
+	})
+	require.NoError(t, keys.reload())
+	key, err := keys.selectKey(&newKey.PublicKey, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "new", key.id)
+	_, err = keys.selectKey(&oldKey.PublicKey, time.Now())
+	assert.Error(t, err)
+
+	// invalid file keeps previous keys
+	require.NoError(t, ioutil.WriteFile(keysFile, []byte(`[{"id": "new", "key": "broken"}]`), 0600))
+	assert.Error(t, keys.reload())
+	_, err = keys.selectKey(&newKey.PublicKey, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, keys.list(time.Now()), 2)
+}
+
+func TestParseRsaPubkey(t *testing.T) {
+	key, _ := newTestKey(t)
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pkcs1 := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+	pkixPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix})
+
+	for _, pubkey := range []string{
+		string(pkixPem),
+		base64.StdEncoding.EncodeToString(pkixPem),
+		base64.StdEncoding.EncodeToString(pkix),
+		base64.StdEncoding.EncodeToString(pkcs1),
+	} {
+		parsed, err := parseRsaPubkey(pubkey)
+		require.NoError(t, err)
+		assert.Equal(t, key.PublicKey, *parsed)
+	}
+
+	_, err = parseRsaPubkey("")
+	assert.True(t, err == signidice.ErrInvalidRsaPubkey)
+}
@@ -5,61 +5,185 @@ import (
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
+	"crypto/sha256"
 	"encoding/base64"
-	"encoding/pem"
+	"fmt"
 	"github.com/eoscanada/eos-go"
 	"github.com/eoscanada/eos-go/ecc"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 	"platform-backend/blockchain"
+	"platform-backend/config"
+	"platform-backend/contracts"
+	"platform-backend/models"
+	"platform-backend/signidice"
+	"strconv"
+	"sync"
+	"time"
 )
 
 type SignidiceUseCase struct {
 	bc                   *blockchain.Blockchain
-	rsaKey               *rsa.PrivateKey
+	repo                 signidice.Repository
+	contractsRepo        contracts.Repository
+	keyring              *keyring
 	platformAccountName  string
 	signidiceAccountName string
+	reloadInterval       time.Duration
+	// serializes admin and periodic keys reloads
+	reloadMutex     sync.Mutex
+	mismatchCounter *prometheus.CounterVec
 }
 
+// key mismatch reasons
+const (
+	mismatchInvalidPubkey = "invalid_pubkey"
+	mismatchNoKey         = "no_key"
+	mismatchSignature     = "signature"
+)
+
 func NewSignidiceUseCase(
 	bc *blockchain.Blockchain,
+	repo signidice.Repository,
+	contractsRepo contracts.Repository,
 	platformAccountName string,
-	signidiceAccountName string,
-	rsaBase64 string,
+	cfg *config.SignidiceConfig,
+	reg prometheus.Registerer,
 ) *SignidiceUseCase {
-	rsaPem, err := base64.StdEncoding.DecodeString(rsaBase64)
+	keys, err := newKeyring(cfg)
 	if err != nil {
-		log.Panic().Msgf("Cannot decode rsa key: %s", err.Error())
+		log.Panic().Msgf("Cannot load signidice keys: %s", err.Error())
 		return nil
 	}
 
-	block, _ := pem.Decode(rsaPem)
-	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		log.Panic().Msgf("Cannot parse PKCS1 private key: %s", err.Error())
-		return nil
+	reloadInterval := time.Duration(0)
+	if cfg.KeysFile != "" {
+		reloadInterval = time.Duration(cfg.ReloadInterval) * time.Second
 	}
 
+	mismatchCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "signidice_key_mismatch_total",
+			Help: "signidice keys not matching casino on-chain public key",
+		}, []string{"casino", "reason"},
+	)
+	reg.MustRegister(mismatchCounter)
+
 	return &SignidiceUseCase{
 		bc:                   bc,
-		rsaKey:               key,
+		repo:                 repo,
+		contractsRepo:        contractsRepo,
+		keyring:              keys,
 		platformAccountName:  platformAccountName,
-		signidiceAccountName: signidiceAccountName,
+		signidiceAccountName: cfg.AccountName,
+		reloadInterval:       reloadInterval,
+		mismatchCounter:      mismatchCounter,
 	}
 }
 
-func (a *SignidiceUseCase) rsaSign(digest eos.Checksum256) (string, error) {
-	sign, err := rsa.SignPKCS1v15(rand.Reader, a.rsaKey, crypto.SHA256, digest)
+func (a *SignidiceUseCase) countMismatch(casinoID uint64, reason string) {
+	a.mismatchCounter.WithLabelValues(strconv.FormatUint(casinoID, 10), reason).Inc()
+}
+
+// casinoKey selects signidice key of given keys by casino on-chain public key
+func (a *SignidiceUseCase) casinoKey(
+	keys []*signidiceKey,
+	casino *models.Casino,
+	now time.Time,
+) (*signidiceKey, *rsa.PublicKey, error) {
+	pubKey, err := parseRsaPubkey(casino.RsaPubkey)
+	if err != nil {
+		a.countMismatch(casino.Id, mismatchInvalidPubkey)
+		return nil, nil, fmt.Errorf("casino %d: %w", casino.Id, err)
+	}
+
+	key, err := selectKey(keys, pubKey, now)
+	if err != nil {
+		a.countMismatch(casino.Id, mismatchNoKey)
+		return nil, nil, fmt.Errorf("casino %d: %w", casino.Id, err)
+	}
+	return key, pubKey, nil
+}
+
+// sign signs digest and verifies signature against casino public key,
+// so broken key is found before contract rejects transaction
+func (a *SignidiceUseCase) sign(
+	keys []*signidiceKey,
+	casino *models.Casino,
+	digest []byte,
+	now time.Time,
+) (string, *signidiceKey, error) {
+	key, pubKey, err := a.casinoKey(keys, casino, now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sign, err := rsa.SignPKCS1v15(rand.Reader, key.key, crypto.SHA256, digest)
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+
+	if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, digest, sign); err != nil {
+		a.countMismatch(casino.Id, mismatchSignature)
+		log.Error().Msgf("Signidice signature verification failed, casinoID: %d, keyID: %s, err: %s",
+			casino.Id, key.id, err.Error())
+		return "", nil, fmt.Errorf("casino %d, key %s: %w", casino.Id, key.id, signidice.ErrSignatureMismatch)
 	}
 
 	// contract require base64 string
-	return base64.StdEncoding.EncodeToString(sign), nil
+	return base64.StdEncoding.EncodeToString(sign), key, nil
+}
+
+// CheckKeys checks that every active casino has signidice key matching its on-chain public key
+func (a *SignidiceUseCase) CheckKeys(ctx context.Context) error {
+	return a.checkKeys(ctx, a.keyring.current())
+}
+
+func (a *SignidiceUseCase) checkKeys(ctx context.Context, keys []*signidiceKey) error {
+	casinos, err := a.contractsRepo.AllCasinos(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, casino := range casinos {
+		if casino.Paused {
+			if _, _, err := a.casinoKey(keys, casino, now); err != nil {
+				log.Warn().Msgf("Signidice key check failed for paused casino: %s", err.Error())
+			}
+			continue
+		}
+		if err := a.checkCasinoKey(keys, casino, now); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msgf("Signidice keys are checked, casinos count: %d", len(casinos))
+	return nil
+}
+
+// checkCasinoKey signs random digest, so key inconsistent with casino public key is found too
+func (a *SignidiceUseCase) checkCasinoKey(keys []*signidiceKey, casino *models.Casino, now time.Time) error {
+	digest := make([]byte, sha256.Size)
+	if _, err := rand.Read(digest); err != nil {
+		return err
+	}
+	_, _, err := a.sign(keys, casino, digest, now)
+	return err
 }
 
-func (a *SignidiceUseCase) PerformSignidice(ctx context.Context, gameName string, digest []byte, bcSessionID uint64) error {
-	rsaSign, err := a.rsaSign(digest)
+func (a *SignidiceUseCase) PerformSignidice(
+	ctx context.Context,
+	session *models.GameSession,
+	gameName string,
+	digest []byte,
+) error {
+	casino, err := a.contractsRepo.GetCasino(ctx, session.CasinoID)
+	if err != nil {
+		return err
+	}
+
+	rsaSign, key, err := a.sign(a.keyring.current(), casino, digest, time.Now())
 	if err != nil {
 		return err
 	}
@@ -74,21 +198,110 @@ func (a *SignidiceUseCase) PerformSignidice(ctx context.Context, gameName string
 			SessionId uint64 `json:"ses_id"`
 			Signature string `json:"sign"`
 		}{
-			SessionId: bcSessionID,
+			SessionId: session.BlockchainSesID,
 			Signature: rsaSign,
 		}),
 	}
 
-	trxID, err := a.bc.PushTransaction(
-		[]*eos.Action{action},
-		[]ecc.PublicKey{a.bc.PubKeys.SigniDice},
-		false,
-	)
+	record := &models.SignidiceAuditRecord{
+		SessionID:       session.ID,
+		BlockchainSesID: session.BlockchainSesID,
+		GameContract:    gameName,
+		Digest:          digest,
+		Signature:       rsaSign,
+		KeyID:           key.id,
+	}
+	return a.pushAudited(ctx, record, func() (eos.Checksum256, error) {
+		return a.bc.PushTransaction(
+			[]*eos.Action{action},
+			[]ecc.PublicKey{a.bc.PubKeys.SigniDice},
+			false,
+		)
+	})
+}
+
+// pushAudited stores pending audit record before push, so failed and rejected attempts are audited too,
+// trx isn't pushed if record isn't stored
+func (a *SignidiceUseCase) pushAudited(
+	ctx context.Context,
+	record *models.SignidiceAuditRecord,
+	push func() (eos.Checksum256, error),
+) error {
+	record.Status = models.SignidiceAuditPending
+	if err := a.repo.AddAuditRecord(ctx, record); err != nil {
+		return err
+	}
+
+	trxID, err := push()
 	if err != nil {
+		if e := a.repo.UpdateAuditRecordFailed(ctx, record.ID, err.Error()); e != nil {
+			log.Error().Msgf("Signidice audit record updating error, sessionID: %d, auditID: %d, err: %s",
+				record.SessionID, record.ID, e.Error())
+		}
 		return err
 	}
 
-	log.Info().Msgf("Successfully sent signidice_1 trx, sessionID: %d, trxID: %s", bcSessionID, trxID.String())
+	log.Info().Msgf("Successfully sent signidice_1 trx, sessionID: %d, keyID: %s, trxID: %s",
+		record.BlockchainSesID, record.KeyID, trxID.String())
+
+	// trx is already pushed, so audit error doesn't fail signidice
+	if err := a.repo.UpdateAuditRecordSent(ctx, record.ID, trxID.String()); err != nil {
+		log.Error().Msgf("Signidice audit record updating error, sessionID: %d, trxID: %s, err: %s",
+			record.SessionID, trxID.String(), err.Error())
+	}
+
+	return nil
+}
+
+func (a *SignidiceUseCase) GetSessionAudit(ctx context.Context, sessionID uint64) ([]*models.SignidiceAuditRecord, error) {
+	return a.repo.GetSessionAuditRecords(ctx, sessionID)
+}
+
+func (a *SignidiceUseCase) ReloadKeys(ctx context.Context) ([]*models.SignidiceKey, error) {
+	if err := a.reloadKeys(ctx); err != nil {
+		return nil, err
+	}
+	keys := a.keyring.list(time.Now())
+	log.Info().Msgf("Signidice keys reloaded, keys count: %d", len(keys))
+	return keys, nil
+}
+
+// reloadKeys rereads keys file and checks new keys before they are used,
+// previous keys are kept if any active casino has no valid matching key
+func (a *SignidiceUseCase) reloadKeys(ctx context.Context) error {
+	a.reloadMutex.Lock()
+	defer a.reloadMutex.Unlock()
 
+	keys, err := a.keyring.load()
+	if err != nil {
+		return err
+	}
+	if err := a.checkKeys(ctx, keys); err != nil {
+		return err
+	}
+	a.keyring.set(keys)
 	return nil
 }
+
+// RunKeysReloader periodically rereads keys file, previous keys are kept on error
+func (a *SignidiceUseCase) RunKeysReloader(ctx context.Context) error {
+	if a.reloadInterval <= 0 {
+		log.Info().Msg("Signidice keys reloader is disabled")
+		<-ctx.Done()
+		return nil
+	}
+
+	log.Info().Msg("Signidice keys reloader is started")
+	ticker := time.NewTicker(a.reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := a.reloadKeys(ctx); err != nil {
+				log.Error().Msgf("Signidice keys reload error: %s", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"platform-backend/config"
+	"platform-backend/contracts/repository/mock"
+	"platform-backend/models"
+	"platform-backend/signidice"
+	"platform-backend/signidice/repository/localstorage"
+)
+
+func TestCheckKeys(t *testing.T) {
+	key, keyBase64 := newTestKey(t)
+	otherKey, _ := newTestKey(t)
+	pubkey := base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	otherPubkey := base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&otherKey.PublicKey))
+
+	repo := mock.NewMockedListingRepo()
+	repo.AddCasino(&models.Casino{Id: 1, Contract: "casino1", RsaPubkey: pubkey})
+	// paused casino mismatch doesn't prevent start
+	repo.AddCasino(&models.Casino{Id: 2, Contract: "casino2", RsaPubkey: otherPubkey, Paused: true})
+
+	uc := NewSignidiceUseCase(nil, nil, repo, "platform", &config.SignidiceConfig{Key: keyBase64}, prometheus.NewRegistry())
+	require.NoError(t, uc.CheckKeys(context.Background()))
+	assert.Equal(t, 1.0, testutil.ToFloat64(uc.mismatchCounter.WithLabelValues("2", mismatchNoKey)))
+
+	repo.AddCasino(&models.Casino{Id: 3, Contract: "casino3", RsaPubkey: otherPubkey})
+	err := uc.CheckKeys(context.Background())
+	assert.True(t, errors.Is(err, signidice.ErrNoSignidiceKey))
+	assert.Equal(t, 1.0, testutil.ToFloat64(uc.mismatchCounter.WithLabelValues("3", mismatchNoKey)))
+
+	repo.AddCasino(&models.Casino{Id: 3, Contract: "casino3", RsaPubkey: "not a key"})
+	err = uc.CheckKeys(context.Background())
+	assert.True(t, errors.Is(err, signidice.ErrInvalidRsaPubkey))
+	assert.Equal(t, 1.0, testutil.ToFloat64(uc.mismatchCounter.WithLabelValues("3", mismatchInvalidPubkey)))
+}
+
+func TestPushAudited(t *testing.T) {
+	ctx := context.Background()
+	_, keyBase64 := newTestKey(t)
+	repo := localstorage.NewSignidiceLocalRepo()
+	uc := NewSignidiceUseCase(nil, repo, mock.NewMockedListingRepo(), "platform",
+		&config.SignidiceConfig{Key: keyBase64}, prometheus.NewRegistry())
+
+	err := uc.pushAudited(ctx, &models.SignidiceAuditRecord{SessionID: 1, KeyID: "key1"},
+		func() (eos.Checksum256, error) {
+			records, err := repo.GetSessionAuditRecords(ctx, 1)
+			require.NoError(t, err)
+			// record is stored before push
+			require.Len(t, records, 1)
+			assert.Equal(t, models.SignidiceAuditPending, records[0].Status)
+			return eos.Checksum256{0xab}, nil
+		})
+	require.NoError(t, err)
+
+	pushErr := errors.New("assertion failure")
+	err = uc.pushAudited(ctx, &models.SignidiceAuditRecord{SessionID: 1, KeyID: "key1"},
+		func() (eos.Checksum256, error) {
+			return nil, pushErr
+		})
+	assert.Equal(t, pushErr, err)
+
+	records, err := repo.GetSessionAuditRecords(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, models.SignidiceAuditSent, records[0].Status)
+	assert.Equal(t, "ab", records[0].TrxID)
+	assert.Equal(t, models.SignidiceAuditFailed, records[1].Status)
+	assert.Equal(t, pushErr.Error(), records[1].LastError)
+}
+
+func TestReloadKeysChecksKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signidice")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keysFile := filepath.Join(dir, "keys.json")
+
+	key, keyBase64 := newTestKey(t)
+	_, otherBase64 := newTestKey(t)
+	pubkey := base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	require.NoError(t, ioutil.WriteFile(keysFile, []byte(`[]`), 0600))
+
+	repo := mock.NewMockedListingRepo()
+	repo.AddCasino(&models.Casino{Id: 1, Contract: "casino1", RsaPubkey: pubkey})
+	uc := NewSignidiceUseCase(nil, nil, repo, "platform",
+		&config.SignidiceConfig{Key: keyBase64, KeysFile: keysFile}, prometheus.NewRegistry())
+
+	// active casino loses its key, so previous keys are kept
+	data, err := json.Marshal([]config.SignidiceKeyConfig{{ID: defaultKeyID, Key: otherBase64}})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(keysFile, data, 0600))
+	_, err = uc.ReloadKeys(context.Background())
+	assert.True(t, errors.Is(err, signidice.ErrNoSignidiceKey))
+	_, _, err = uc.casinoKey(uc.keyring.current(), &models.Casino{Id: 1, RsaPubkey: pubkey}, time.Now())
+	assert.NoError(t, err)
+
+	data, err = json.Marshal([]config.SignidiceKeyConfig{{ID: "new", Key: otherBase64}})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(keysFile, data, 0600))
+	keys, err := uc.ReloadKeys(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+}
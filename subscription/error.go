@@ -0,0 +1,7 @@
+package subscription
+
+import "errors"
+
+var (
+	ErrSendChannelFull = errors.New("subscription send channel is full")
+)
@@ -1,12 +1,31 @@
 package subscription
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"platform-backend/models"
 )
 
+// SessionCursor is the last session update offset already received by client
+type SessionCursor struct {
+	SessionID uint64
+	// nil if client has no updates of the session
+	Offset *uint64
+}
+
 type UseCase interface {
-	AddSession(uuid uuid.UUID, user *models.User, send chan<- []byte)
+	// Subscribe adds session and replays updates after cursors before live notifications,
+	// session isn't subscribed if replay fails, e.g. with ErrSendChannelFull.
+	// Update isn't dropped when send channel is full, session is unsubscribed and disconnect is called instead
+	Subscribe(
+		ctx context.Context,
+		uuid uuid.UUID,
+		user *models.User,
+		send chan<- []byte,
+		disconnect func(),
+		cursors []*SessionCursor,
+	) error
 	RemoveSession(uuid uuid.UUID)
+	// Notify doesn't block, subscriber with full send channel is disconnected
 	Notify(user string, reason string, payload interface{})
 }
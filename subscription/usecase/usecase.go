@@ -1,11 +1,15 @@
 package usecase
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	gamesessions "platform-backend/game_sessions"
 	"platform-backend/models"
 	"platform-backend/server/api/ws_interface"
+	"platform-backend/subscription"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -13,27 +17,155 @@ import (
 type Subscription struct {
 	user *models.User
 	send chan<- []byte
+	// closes client connection when send channel is full, client subscribes again with cursors
+	disconnect func()
+	// replay in progress, live notifications are queued to pending
+	replaying bool
+	pending   []*notification
+	// the last replayed update of every session, pending updates up to it are already sent
+	replayed map[string]*replayedUpdate
+}
+
+type notification struct {
+	reason  string
+	payload interface{}
+}
+
+type replayedUpdate struct {
+	offset    *uint64
+	timestamp time.Time
 }
 
 type SubscriptionUseCase struct {
 	sync.Mutex
 	subscriptions map[uuid.UUID]*Subscription
+	gsRepo        gamesessions.Repository
 }
 
-func NewSubscriptionUseCase() *SubscriptionUseCase {
+func NewSubscriptionUseCase(gsRepo gamesessions.Repository) *SubscriptionUseCase {
 	return &SubscriptionUseCase{
 		subscriptions: make(map[uuid.UUID]*Subscription),
+		gsRepo:        gsRepo,
 	}
 }
 
-func (s *SubscriptionUseCase) AddSession(uuid uuid.UUID, user *models.User, send chan<- []byte) {
+func (s *SubscriptionUseCase) Subscribe(
+	ctx context.Context,
+	uuid uuid.UUID,
+	user *models.User,
+	send chan<- []byte,
+	disconnect func(),
+	cursors []*subscription.SessionCursor,
+) error {
+	// register subscription before fetching updates to not lose updates added meanwhile
+	s.Lock()
+	sub := &Subscription{
+		user:       user,
+		send:       send,
+		disconnect: disconnect,
+		replaying:  true,
+		replayed:   make(map[string]*replayedUpdate),
+	}
+	s.subscriptions[uuid] = sub
+	s.Unlock()
+
+	err := s.replay(ctx, sub, cursors)
+	if err != nil {
+		// client missed some updates, live notifications after them would leave a gap
+		s.unsubscribe(uuid, sub)
+		return err
+	}
+
+	if !s.finishReplay(uuid, sub) {
+		sub.disconnect()
+	}
+	return nil
+}
+
+// replay sends updates after cursors to send channel
+func (s *SubscriptionUseCase) replay(ctx context.Context, sub *Subscription, cursors []*subscription.SessionCursor) error {
+	for _, cursor := range cursors {
+		var (
+			updates    []*models.GameSessionUpdate
+			prevOffset *uint64
+			err        error
+		)
+		if cursor.Offset == nil {
+			updates, err = s.gsRepo.GetGameSessionUpdates(ctx, cursor.SessionID)
+		} else {
+			updates, err = s.gsRepo.GetGameSessionUpdatesSince(ctx, cursor.SessionID, *cursor.Offset)
+			if err == nil {
+				prevOffset, err = s.gsRepo.GetLastGameSessionUpdateOffset(ctx, cursor.SessionID, cursor.Offset)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if len(updates) == 0 {
+			continue
+		}
+
+		updateMsgs := models.ToGameSessionUpdateMsgs(updates, prevOffset)
+
+		marshal, err := marshalUpdate("session_update", updateMsgs)
+		if err != nil {
+			return err
+		}
+
+		log.Debug().Msgf("Subscribe: replay %d updates of session %d", len(updates), cursor.SessionID)
+		// Select to prevent lock when send channel is not listening
+		select {
+		case sub.send <- marshal:
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			log.Info().Msgf("Subscribe: replay stopped, send channel is full or dead")
+			return subscription.ErrSendChannelFull
+		}
+		sub.addReplayed(updates)
+	}
+
+	return nil
+}
+
+// unsubscribe removes subscription unless it's replaced by the new one
+func (s *SubscriptionUseCase) unsubscribe(uuid uuid.UUID, sub *Subscription) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.subscriptions[uuid] == sub {
+		delete(s.subscriptions, uuid)
+	}
+}
+
+// finishReplay sends notifications queued during replay except replayed updates
+// and switches subscription to live mode, it returns false if subscription is removed because send channel is full
+func (s *SubscriptionUseCase) finishReplay(uuid uuid.UUID, sub *Subscription) bool {
 	s.Lock()
 	defer s.Unlock()
 
-	s.subscriptions[uuid] = &Subscription{
-		user: user,
-		send: send,
+	for _, n := range sub.pending {
+		payload := sub.skipReplayed(n.payload)
+		if payload == nil {
+			continue
+		}
+
+		marshal, err := marshalUpdate(n.reason, payload)
+		if err != nil {
+			log.Debug().Msgf("Websocket answer marshal error, %s", err.Error())
+			continue
+		}
+		if !sub.trySend(marshal) {
+			if s.subscriptions[uuid] == sub {
+				delete(s.subscriptions, uuid)
+			}
+			return false
+		}
 	}
+	sub.pending = nil
+	sub.replayed = nil
+	sub.replaying = false
+	return true
 }
 
 func (s *SubscriptionUseCase) RemoveSession(uuid uuid.UUID) {
@@ -44,33 +176,99 @@ func (s *SubscriptionUseCase) RemoveSession(uuid uuid.UUID) {
 }
 
 func (s *SubscriptionUseCase) Notify(user string, reason string, payload interface{}) {
-	resp := &ws_interface.WsUpdate{
-		Type:    "update",
-		Reason:  reason,
-		Time:    time.Now().Unix(),
-		Payload: payload,
-	}
-
-	marshal, err := json.Marshal(resp)
+	marshal, err := marshalUpdate(reason, payload)
 
 	if err != nil {
 		log.Debug().Msgf("Websocket answer marshal error, %s", err.Error())
 		return
 	}
 
+	// disconnect removes subscription, so it's called without lock
+	for _, sub := range s.notify(user, reason, payload, marshal) {
+		sub.disconnect()
+	}
+}
+
+// notify sends update to user subscriptions, it returns subscriptions removed because send channel is full
+func (s *SubscriptionUseCase) notify(user string, reason string, payload interface{}, marshal []byte) []*Subscription {
 	s.Lock()
 	defer s.Unlock()
 
-	for _, subscription := range s.subscriptions {
-		if subscription.user.AccountName != user {
+	overflowed := make([]*Subscription, 0)
+	for uuid, sub := range s.subscriptions {
+		if sub.user.AccountName != user {
 			continue
 		}
 
-		// Select to prevent lock when send channel is not listening
-		select {
-		case subscription.send <- marshal:
-		default:
-			log.Info().Msgf("Subscribe: notify called when send channel is dead")
+		if sub.replaying {
+			sub.pending = append(sub.pending, &notification{reason: reason, payload: payload})
+			continue
+		}
+
+		if !sub.trySend(marshal) {
+			delete(s.subscriptions, uuid)
+			overflowed = append(overflowed, sub)
 		}
 	}
+	return overflowed
+}
+
+// trySend returns false if send channel is full, dropped update would leave a gap, so client must be disconnected
+func (s *Subscription) trySend(marshal []byte) bool {
+	// Select to prevent lock when send channel is not listening
+	select {
+	case s.send <- marshal:
+		return true
+	default:
+		log.Info().Msgf("Subscribe: send channel is full, client is disconnected")
+		return false
+	}
+}
+
+// addReplayed remembers the last of replayed updates of session, updates are ordered
+func (s *Subscription) addReplayed(updates []*models.GameSessionUpdate) {
+	last := &replayedUpdate{}
+	for _, update := range updates {
+		if update.Offset != nil {
+			last.offset = update.Offset
+		}
+		if update.Timestamp.After(last.timestamp) {
+			last.timestamp = update.Timestamp
+		}
+	}
+	s.replayed[strconv.FormatUint(updates[0].SessionID, 10)] = last
+}
+
+// skipReplayed returns session update messages which weren't replayed, nil if there is nothing to send,
+// updates without offset are placed by timestamp
+func (s *Subscription) skipReplayed(payload interface{}) interface{} {
+	msgs, ok := payload.([]*models.GameSessionUpdateMsg)
+	if !ok {
+		return payload
+	}
+
+	notReplayed := make([]*models.GameSessionUpdateMsg, 0, len(msgs))
+	for _, msg := range msgs {
+		last, ok := s.replayed[msg.SessionID]
+		if ok && (msg.Offset != nil && last.offset != nil && *msg.Offset <= *last.offset ||
+			msg.Offset == nil && !msg.Timestamp.After(last.timestamp)) {
+			continue
+		}
+		notReplayed = append(notReplayed, msg)
+	}
+	if len(notReplayed) == 0 && len(msgs) > 0 {
+		return nil
+	}
+	return notReplayed
+}
+
+func marshalUpdate(reason string, payload interface{}) ([]byte, error) {
+	resp := &ws_interface.WsUpdate{
+		Type:    "update",
+		Reason:  reason,
+		Time:    time.Now().Unix(),
+		Payload: payload,
+	}
+
+	return json.Marshal(resp)
 }
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"platform-backend/game_sessions/repository/localstorage"
+	"platform-backend/models"
+	"platform-backend/subscription"
+	"testing"
+	"time"
+)
+
+type updateMsg struct {
+	Reason  string                         `json:"reason"`
+	Payload []*models.GameSessionUpdateMsg `json:"payload"`
+}
+
+func readUpdate(t *testing.T, send chan []byte) *updateMsg {
+	select {
+	case raw := <-send:
+		msg := new(updateMsg)
+		assert.NoError(t, json.Unmarshal(raw, msg))
+		return msg
+	default:
+		t.Fatal("no update in send channel")
+		return nil
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		repo   = localstorage.NewGameSessionsLocalRepo()
+		subsUC = NewSubscriptionUseCase(repo)
+		user   = &models.User{AccountName: "testuser"}
+		suid   = uuid.New()
+		send   = make(chan []byte, 10)
+		now    = time.Now()
+	)
+
+	assert.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, Player: user.AccountName}))
+
+	addUpdate := func(updateType models.GameSessionUpdateType, offset *uint64, shift time.Duration) {
+		assert.NoError(t, repo.AddGameSessionUpdate(ctx, &models.GameSessionUpdate{
+			SessionID:  1,
+			UpdateType: updateType,
+			Timestamp:  now.Add(shift),
+			Offset:     offset,
+		}))
+	}
+	offsets := []uint64{10, 20}
+	addUpdate(models.SessionCreatedUpdate, nil, 0)
+	addUpdate(models.SessionStartedUpdate, &offsets[0], time.Second)
+	addUpdate(models.GameFinishedUpdate, &offsets[1], 2*time.Second)
+
+	err := subsUC.Subscribe(ctx, suid, user, send, func() {}, []*subscription.SessionCursor{
+		{SessionID: 1, Offset: &offsets[0]},
+	})
+	assert.NoError(t, err)
+
+	msg := readUpdate(t, send)
+	assert.Equal(t, "session_update", msg.Reason)
+	assert.Len(t, msg.Payload, 1)
+	assert.Equal(t, models.GameFinishedUpdate, msg.Payload[0].UpdateType)
+	assert.Equal(t, offsets[0], *msg.Payload[0].PrevOffset)
+
+	// no cursor replays whole session
+	err = subsUC.Subscribe(ctx, suid, user, send, func() {}, []*subscription.SessionCursor{{SessionID: 1}})
+	assert.NoError(t, err)
+	msg = readUpdate(t, send)
+	assert.Len(t, msg.Payload, 3)
+	assert.Nil(t, msg.Payload[0].PrevOffset)
+	assert.Nil(t, msg.Payload[1].PrevOffset)
+	assert.Equal(t, offsets[0], *msg.Payload[2].PrevOffset)
+
+	// live notifications after replay
+	subsUC.Notify(user.AccountName, "session_update", []*models.GameSessionUpdateMsg{})
+	assert.Equal(t, "session_update", readUpdate(t, send).Reason)
+
+	subsUC.Notify("otheruser", "session_update", []*models.GameSessionUpdateMsg{})
+	assert.Len(t, send, 0)
+}
+
+func TestSubscribeReplayFullChannel(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		repo   = localstorage.NewGameSessionsLocalRepo()
+		subsUC = NewSubscriptionUseCase(repo)
+		user   = &models.User{AccountName: "testuser"}
+		// client doesn't read from channel
+		send = make(chan []byte)
+	)
+
+	assert.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, Player: user.AccountName}))
+	assert.NoError(t, repo.AddGameSessionUpdate(ctx, &models.GameSessionUpdate{
+		SessionID:  1,
+		UpdateType: models.SessionCreatedUpdate,
+		Timestamp:  time.Now(),
+	}))
+
+	suid := uuid.New()
+	err := subsUC.Subscribe(ctx, suid, user, send, func() {}, []*subscription.SessionCursor{{SessionID: 1}})
+	assert.Equal(t, subscription.ErrSendChannelFull, err)
+	// session with incomplete replay isn't subscribed
+	assert.NotContains(t, subsUC.subscriptions, suid)
+}
+
+func TestSubscribeReplayedUpdateNotDuplicated(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		repo   = localstorage.NewGameSessionsLocalRepo()
+		subsUC = NewSubscriptionUseCase(repo)
+		user   = &models.User{AccountName: "testuser"}
+		suid   = uuid.New()
+		send   = make(chan []byte, 10)
+		offset = uint64(10)
+	)
+	assert.NoError(t, repo.AddGameSession(ctx, &models.GameSession{ID: 1, Player: user.AccountName}))
+
+	sub := &Subscription{user: user, send: send, replaying: true, replayed: make(map[string]*replayedUpdate)}
+	subsUC.subscriptions[suid] = sub
+
+	// update is added after subscription is registered, but before replay query
+	update := &models.GameSessionUpdate{
+		SessionID:  1,
+		UpdateType: models.SessionStartedUpdate,
+		Timestamp:  time.Now(),
+		Offset:     &offset,
+	}
+	assert.NoError(t, repo.AddGameSessionUpdate(ctx, update))
+	subsUC.Notify(user.AccountName, "session_update", models.ToGameSessionUpdateMsgs([]*models.GameSessionUpdate{update}, nil))
+
+	assert.NoError(t, subsUC.replay(ctx, sub, []*subscription.SessionCursor{{SessionID: 1}}))
+	assert.True(t, subsUC.finishReplay(suid, sub))
+
+	assert.Len(t, readUpdate(t, send).Payload, 1)
+	assert.Len(t, send, 0)
+}
+
+func TestNotifyFullChannelDisconnects(t *testing.T) {
+	var (
+		ctx          = context.Background()
+		repo         = localstorage.NewGameSessionsLocalRepo()
+		subsUC       = NewSubscriptionUseCase(repo)
+		user         = &models.User{AccountName: "testuser"}
+		suid         = uuid.New()
+		send         = make(chan []byte, 1)
+		disconnected = false
+	)
+
+	err := subsUC.Subscribe(ctx, suid, user, send, func() { disconnected = true }, nil)
+	assert.NoError(t, err)
+
+	subsUC.Notify(user.AccountName, "session_update", []*models.GameSessionUpdateMsg{})
+	assert.False(t, disconnected)
+
+	// update isn't dropped silently, client subscribes again after reconnection
+	subsUC.Notify(user.AccountName, "session_update", []*models.GameSessionUpdateMsg{})
+	assert.True(t, disconnected)
+	assert.NotContains(t, subsUC.subscriptions, suid)
+}
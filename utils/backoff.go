@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff returns exponential delay before the next attempt, it's doubled from minDelay up to maxDelay
+func Backoff(minDelay, maxDelay time.Duration, attempts int) time.Duration {
+	delay := minDelay
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// JitterBackoff returns Backoff delay with random half,
+// so concurrent requests don't retry simultaneously
+func JitterBackoff(minDelay, maxDelay time.Duration, attempts int) time.Duration {
+	delay := Backoff(minDelay, maxDelay, attempts)
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Second, Backoff(5*time.Second, time.Minute, 1))
+	assert.Equal(t, 10*time.Second, Backoff(5*time.Second, time.Minute, 2))
+	assert.Equal(t, 40*time.Second, Backoff(5*time.Second, time.Minute, 4))
+	assert.Equal(t, time.Minute, Backoff(5*time.Second, time.Minute, 100))
+	assert.Equal(t, time.Duration(0), JitterBackoff(0, time.Second, 3))
+
+	for i := 0; i < 100; i++ {
+		delay := JitterBackoff(time.Second, time.Minute, 2)
+		assert.True(t, delay >= time.Second && delay <= 2*time.Second, delay)
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	assert.NoError(t, breaker.Allow())
+	breaker.Failure()
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	// success resets consecutive failures
+	breaker.Success()
+	breaker.Failure()
+	assert.Equal(t, BreakerClosed, breaker.State())
+	breaker.Failure()
+	assert.Equal(t, BreakerOpen, breaker.State())
+	assert.Equal(t, ErrCircuitOpen, breaker.Allow())
+
+	// only single probe after open timeout
+	now = now.Add(time.Minute)
+	assert.NoError(t, breaker.Allow())
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	assert.Equal(t, ErrCircuitOpen, breaker.Allow())
+
+	// failed probe opens circuit again
+	breaker.Failure()
+	assert.Equal(t, BreakerOpen, breaker.State())
+	assert.Equal(t, ErrCircuitOpen, breaker.Allow())
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, breaker.Allow())
+	breaker.Success()
+	assert.Equal(t, BreakerClosed, breaker.State())
+	assert.NoError(t, breaker.Allow())
+}